@@ -12,7 +12,6 @@ import (
 	"go.uber.org/zap"
 
 	"liquidityScope/internal/aggregate"
-	"liquidityScope/internal/chain"
 	"liquidityScope/internal/config"
 	"liquidityScope/internal/storage/postgres"
 )
@@ -30,7 +29,7 @@ func runAggregate(cmd *cobra.Command, _ []string) error {
 	}
 	defer logger.Sync()
 
-	if cfg.RPCURL == "" {
+	if len(cfg.RPC) == 0 {
 		return fmt.Errorf("rpc url is required")
 	}
 	if cfg.Input == "" {
@@ -60,11 +59,12 @@ func runAggregate(cmd *cobra.Command, _ []string) error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	chainClient, err := chain.NewClient(ctx, cfg.RPCURL)
+	chainClient, err := dialChain(ctx, cfg.RPC)
 	if err != nil {
-		return fmt.Errorf("connect rpc: %w", err)
+		return err
 	}
 	defer chainClient.Close()
+	defer logRPCUsage(logger, chainClient)
 
 	store, err := postgres.NewStore(ctx, cfg.PGDSN)
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -14,10 +15,12 @@ import (
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 
-	"liquidityScope/internal/chain"
 	"liquidityScope/internal/config"
 	"liquidityScope/internal/dex"
+	"liquidityScope/internal/indexer"
 	"liquidityScope/internal/model"
+	"liquidityScope/internal/storage"
+	"liquidityScope/internal/storage/postgres"
 )
 
 func runDecode(cmd *cobra.Command, _ []string) error {
@@ -33,11 +36,20 @@ func runDecode(cmd *cobra.Command, _ []string) error {
 	}
 	defer logger.Sync()
 
-	if cfg.RPCURL == "" {
+	if len(cfg.RPC) == 0 {
 		return fmt.Errorf("rpc url is required")
 	}
-	if cfg.In == "" {
-		return fmt.Errorf("input path is required")
+	switch cfg.Source {
+	case "file", "":
+		if cfg.In == "" {
+			return fmt.Errorf("input path is required")
+		}
+	case "postgres":
+		if cfg.PGDSN == "" {
+			return fmt.Errorf("pg dsn is required when source is postgres")
+		}
+	default:
+		return fmt.Errorf("unknown source %q", cfg.Source)
 	}
 	if cfg.Out == "" {
 		return fmt.Errorf("output path is required")
@@ -45,37 +57,81 @@ func runDecode(cmd *cobra.Command, _ []string) error {
 	if cfg.Errors == "" {
 		return fmt.Errorf("errors path is required")
 	}
+	if cfg.Errors == storage.StdioPath {
+		return fmt.Errorf("errors cannot be written to stdout, use a file")
+	}
+	if cfg.ToBlock != 0 && cfg.FromBlock > cfg.ToBlock {
+		return fmt.Errorf("from block %d is after to block %d", cfg.FromBlock, cfg.ToBlock)
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	chainClient, err := chain.NewClient(ctx, cfg.RPCURL)
+	chainClient, err := dialChain(ctx, cfg.RPC)
 	if err != nil {
-		return fmt.Errorf("connect rpc: %w", err)
+		return err
 	}
 	defer chainClient.Close()
+	defer logRPCUsage(logger, chainClient)
 
-	decoder, err := dex.NewV3PoolDecoder(dex.DecoderConfig{Topic0Map: cfg.Topic0Map})
+	v3Decoder, err := dex.NewV3PoolDecoder(dex.DecoderConfig{Topic0Map: cfg.Topic0Map})
+	if err != nil {
+		return err
+	}
+	v2Decoder, err := dex.NewV2PairDecoder()
+	if err != nil {
+		return err
+	}
+	v4Registry, err := dex.LoadV4PoolRegistry(cfg.V4Registry)
 	if err != nil {
 		return err
 	}
+	// Pool keys learned before a failure are kept too: a later run starting
+	// past their Initialize could not decode those pools otherwise.
+	v4Saved := false
+	defer func() {
+		if v4Saved {
+			return
+		}
+		if err := v4Registry.Save(); err != nil {
+			logger.Warn("save v4 registry", zap.Error(err))
+		}
+	}()
+	v4Managers, err := indexer.ParseAddresses(cfg.V4PoolManagers)
+	if err != nil {
+		return fmt.Errorf("parse v4 pool managers: %w", err)
+	}
+	v4Decoder, err := dex.NewV4PoolManagerDecoder(v4Registry, v4Managers)
+	if err != nil {
+		return err
+	}
+	algebraDecoder, err := dex.NewAlgebraPoolDecoder()
+	if err != nil {
+		return err
+	}
+	decoder := dex.NewMultiDecoder(v3Decoder, v2Decoder, v4Decoder, algebraDecoder)
+
+	poolMetaCache := dex.NewPoolMetaCache()
+	registered := 0
+	if cfg.Registry != "" {
+		registry, err := dex.LoadPoolRegistry(cfg.Registry)
+		if err != nil {
+			return err
+		}
+		registry.SeedPoolMetaCache(poolMetaCache)
+		registered = registry.Len()
+	}
 
 	decodeCtx := dex.DecodeContext{
 		Context:         ctx,
 		Chain:           chainClient,
-		PoolMetaCache:   dex.NewPoolMetaCache(),
+		PoolMetaCache:   poolMetaCache,
 		TokenMetaCache:  dex.NewTokenMetaCache(),
 		Logger:          logger,
 		IncludeLiveMeta: cfg.IncludeLiveMeta,
 	}
 
-	inputFile, err := os.Open(cfg.In)
-	if err != nil {
-		return fmt.Errorf("open input: %w", err)
-	}
-	defer inputFile.Close()
-
-	outWriter, err := newJSONLWriter(cfg.Out, false)
+	outWriter, err := openEventWriter(cfg)
 	if err != nil {
 		return err
 	}
@@ -87,76 +143,192 @@ func runDecode(cmd *cobra.Command, _ []string) error {
 	}
 	defer errWriter.Close()
 
-	logger.Info("decode start",
-		zap.String("rpc", cfg.RPCURL),
-		zap.String("in", cfg.In),
-		zap.String("out", cfg.Out),
-		zap.String("errors", cfg.Errors),
-		zap.Bool("include_live_meta", cfg.IncludeLiveMeta),
-	)
-
-	scanner := bufio.NewScanner(inputFile)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 10*1024*1024)
-
 	var total, decoded, skipped, failed int
-	for scanner.Scan() {
-		line := bytes.TrimSpace(scanner.Bytes())
-		if len(line) == 0 {
-			continue
-		}
-		total++
-
-		var record model.LogRecord
-		if err := json.Unmarshal(line, &record); err != nil {
-			failed++
-			writeDecodeError(errWriter, model.DecodeError{Error: err.Error()})
-			continue
+	handle := func(record model.LogRecord) error {
+		if record.BlockNumber < cfg.FromBlock || (cfg.ToBlock != 0 && record.BlockNumber > cfg.ToBlock) {
+			skipped++
+			return nil
 		}
 		if len(record.Topics) == 0 {
 			failed++
 			writeDecodeError(errWriter, decodeErrorFromRecord(record, fmt.Errorf("missing topic0")))
-			continue
+			return nil
 		}
 
 		if !decoder.CanDecode(record.Topics[0]) {
 			skipped++
-			continue
+			return nil
 		}
 
 		event, err := decoder.Decode(record, decodeCtx)
 		if err != nil {
 			failed++
 			writeDecodeError(errWriter, decodeErrorFromRecord(record, err))
-			continue
+			return nil
 		}
 
 		if err := outWriter.Write(event); err != nil {
 			return err
 		}
 		decoded++
+		return nil
+	}
+
+	if cfg.Source == "postgres" {
+		store, err := postgres.NewStore(ctx, cfg.PGDSN)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		logger.Info("decode start",
+			zap.Strings("rpc", cfg.RPC),
+			zap.String("source", cfg.Source),
+			zap.String("pg_dsn", redactDSN(cfg.PGDSN)),
+			zap.String("out", cfg.Out),
+			zap.String("errors", cfg.Errors),
+			zap.Bool("include_live_meta", cfg.IncludeLiveMeta),
+			zap.Int("registered_pools", registered),
+			zap.Int("v4_pools", v4Registry.Len()),
+		)
+
+		filter := postgres.RawLogFilter{FromBlock: cfg.FromBlock, ToBlock: cfg.ToBlock}
+		if err := store.ScanRawLogs(ctx, filter, func(record model.LogRecord) error {
+			total++
+			return handle(record)
+		}); err != nil {
+			return err
+		}
+	} else {
+		input, err := storage.OpenInput(cfg.In, storage.SegmentFilter{FromBlock: cfg.FromBlock, ToBlock: cfg.ToBlock})
+		if err != nil {
+			return err
+		}
+		defer input.Close()
+
+		logger.Info("decode start",
+			zap.Strings("rpc", cfg.RPC),
+			zap.String("in", cfg.In),
+			zap.Int("input_files", len(input.Files)),
+			zap.Int("skipped_segments", input.Skipped),
+			zap.String("out", cfg.Out),
+			zap.String("errors", cfg.Errors),
+			zap.Bool("include_live_meta", cfg.IncludeLiveMeta),
+			zap.Int("registered_pools", registered),
+			zap.Int("v4_pools", v4Registry.Len()),
+		)
+
+		scanner := bufio.NewScanner(input)
+		buf := make([]byte, 0, 64*1024)
+		scanner.Buffer(buf, 10*1024*1024)
+
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			total++
+
+			var record model.LogRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				failed++
+				writeDecodeError(errWriter, model.DecodeError{Error: err.Error()})
+				continue
+			}
+			if err := handle(record); err != nil {
+				return err
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("scan input: %w", err)
+		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("scan input: %w", err)
+	if err := outWriter.Close(); err != nil {
+		return fmt.Errorf("close output: %w", err)
+	}
+	if err := v4Registry.Save(); err != nil {
+		return err
 	}
+	v4Saved = true
 
 	logger.Info("decode complete",
 		zap.Int("total", total),
 		zap.Int("decoded", decoded),
 		zap.Int("skipped", skipped),
 		zap.Int("failed", failed),
+		zap.Int("v4_pools", v4Registry.Len()),
 	)
 
 	return nil
 }
 
+// eventWriter receives decoded typed events.
+type eventWriter interface {
+	Write(event *model.TypedEvent) error
+	Close() error
+}
+
+// openEventWriter builds the typed event writer selected by --format and
+// --partition.
+func openEventWriter(cfg config.DecodeConfig) (eventWriter, error) {
+	if cfg.Out == storage.StdioPath && (cfg.Format == storage.FormatParquet || cfg.Partition != "") {
+		return nil, fmt.Errorf("--out - writes JSONL only, without --partition")
+	}
+	switch cfg.Format {
+	case storage.FormatParquet:
+		if cfg.Partition != "" {
+			return nil, fmt.Errorf("--partition cannot be combined with --format parquet")
+		}
+		return storage.NewParquetEventWriter(cfg.Out, cfg.RowGroupBlocks)
+	case storage.FormatJSONL, "":
+		if cfg.Partition != "" {
+			return newSegmentedEventWriter(cfg.Out, storage.Partitioning{
+				Mode:       cfg.Partition,
+				BlockSpan:  cfg.SegmentBlocks,
+				MaxRecords: cfg.SegmentRecords,
+			})
+		}
+		file, err := newJSONLWriter(cfg.Out, false)
+		if err != nil {
+			return nil, err
+		}
+		return jsonlEventWriter{file}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", cfg.Format)
+	}
+}
+
+// jsonlEventWriter writes typed events to a single JSONL file.
+type jsonlEventWriter struct {
+	*jsonlWriter
+}
+
+func (w jsonlEventWriter) Write(event *model.TypedEvent) error {
+	return w.jsonlWriter.Write(event)
+}
+
+// jsonlWriter writes JSON lines, compressed when path ends in .gz or .zst.
+// The path "-" writes to stdout, flushing every line so the next stage in a
+// pipe sees events as they are decoded.
 type jsonlWriter struct {
 	file   *os.File
+	frame  io.WriteCloser
 	writer *bufio.Writer
+	stream bool
+	closed bool
 }
 
 func newJSONLWriter(path string, appendMode bool) (*jsonlWriter, error) {
+	if path == storage.StdioPath {
+		return &jsonlWriter{
+			frame:  nopWriteCloser{os.Stdout},
+			writer: bufio.NewWriter(os.Stdout),
+			stream: true,
+		}, nil
+	}
+
 	dir := filepath.Dir(path)
 	if dir != "." {
 		if err := os.MkdirAll(dir, 0o755); err != nil {
@@ -176,9 +348,16 @@ func newJSONLWriter(path string, appendMode bool) (*jsonlWriter, error) {
 		return nil, fmt.Errorf("open file: %w", err)
 	}
 
+	frame, err := storage.NewFrameWriter(file, storage.CompressionFor(path))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
 	return &jsonlWriter{
 		file:   file,
-		writer: bufio.NewWriter(file),
+		frame:  frame,
+		writer: bufio.NewWriter(frame),
 	}, nil
 }
 
@@ -193,20 +372,96 @@ func (w *jsonlWriter) Write(value interface{}) error {
 	if err := w.writer.WriteByte('\n'); err != nil {
 		return fmt.Errorf("write newline: %w", err)
 	}
+	if w.stream {
+		if err := w.writer.Flush(); err != nil {
+			return fmt.Errorf("flush: %w", err)
+		}
+	}
 	return nil
 }
 
 func (w *jsonlWriter) Close() error {
-	if w == nil {
+	if w == nil || w.closed {
 		return nil
 	}
+	w.closed = true
 	if err := w.writer.Flush(); err != nil {
-		w.file.Close()
+		w.closeFile()
+		return err
+	}
+	if err := w.frame.Close(); err != nil {
+		w.closeFile()
 		return err
 	}
+	return w.closeFile()
+}
+
+// closeFile closes the output file; stdout is left open.
+func (w *jsonlWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
 	return w.file.Close()
 }
 
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// segmentedEventBatch is the number of events buffered before a segment append.
+const segmentedEventBatch = 1000
+
+// segmentedEventWriter writes typed events to partitioned segments.
+type segmentedEventWriter struct {
+	writer  *storage.SegmentWriter
+	pending []storage.SegmentEntry
+}
+
+// newSegmentedEventWriter starts a fresh manifest under dir. Like the single
+// file output, a rerun replaces earlier events instead of appending to them,
+// which would count them twice downstream.
+func newSegmentedEventWriter(dir string, partitioning storage.Partitioning) (*segmentedEventWriter, error) {
+	writer, err := storage.OpenSegmentWriter(dir, partitioning)
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Truncate(0); err != nil {
+		return nil, fmt.Errorf("reset segmented output: %w", err)
+	}
+	return &segmentedEventWriter{writer: writer}, nil
+}
+
+func (w *segmentedEventWriter) Write(event *model.TypedEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	w.pending = append(w.pending, storage.SegmentEntry{
+		ChainID:     event.ChainID,
+		BlockNumber: event.BlockNumber,
+		Timestamp:   event.Timestamp,
+		Line:        line,
+	})
+	if len(w.pending) >= segmentedEventBatch {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *segmentedEventWriter) flush() error {
+	if err := w.writer.Append(w.pending); err != nil {
+		return err
+	}
+	w.pending = w.pending[:0]
+	return nil
+}
+
+func (w *segmentedEventWriter) Close() error {
+	return w.flush()
+}
+
 func decodeErrorFromRecord(record model.LogRecord, err error) model.DecodeError {
 	topic0 := ""
 	if len(record.Topics) > 0 {
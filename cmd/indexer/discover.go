@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"liquidityScope/internal/config"
+	"liquidityScope/internal/dex"
+	"liquidityScope/internal/indexer"
+)
+
+func runDiscover(cmd *cobra.Command, _ []string) error {
+	cfgFile, _ := cmd.Flags().GetString("config")
+	cfg, err := config.Load(cfgFile, cmd.Flags())
+	if err != nil {
+		return err
+	}
+
+	logger, err := newLogger(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	defer logger.Sync()
+
+	if len(cfg.RPC) == 0 {
+		return fmt.Errorf("rpc url is required")
+	}
+	if cfg.Registry == "" {
+		return fmt.Errorf("registry path is required")
+	}
+
+	factories, err := indexer.ParseAddresses(cfg.Factories)
+	if err != nil {
+		return err
+	}
+	if len(factories) == 0 {
+		return fmt.Errorf("factory list is required")
+	}
+
+	poolCreated, err := dex.PoolCreatedTopic()
+	if err != nil {
+		return err
+	}
+
+	registry, err := dex.LoadPoolRegistry(cfg.Registry)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	chainClient, err := dialChain(ctx, cfg.RPC)
+	if err != nil {
+		return err
+	}
+	defer chainClient.Close()
+	defer logRPCUsage(logger, chainClient)
+
+	blockTimes, err := openBlockTimes(chainClient, cfg.BlockTimes)
+	if err != nil {
+		return err
+	}
+	if blockTimes != nil {
+		defer blockTimes.Close()
+	}
+
+	checkpoint := discoverCheckpointPath(cfg.Registry)
+	runner := indexer.NewRunner(indexer.RunConfig{
+		FromBlock:         cfg.FromBlock,
+		ToBlock:           cfg.ToBlock,
+		Addresses:         factories,
+		Topic0:            []common.Hash{poolCreated},
+		BatchSize:         cfg.BatchSize,
+		CheckpointPath:    checkpoint,
+		CheckpointEnabled: true,
+		MaxRetries:        cfg.MaxRetries,
+		RetryBackoff:      cfg.RetryBackoff,
+		Follow:            cfg.Follow,
+		Confirmations:     cfg.Confirmations,
+		PollInterval:      cfg.PollInterval,
+		ReorgWindow:       cfg.ReorgWindow,
+		Concurrency:       cfg.Concurrency,
+		AddressChunkSize:  cfg.AddressChunk,
+	}, chainClient, dex.NewRegistrySink(registry, logger), logger)
+
+	logger.Info("discover start",
+		zap.Strings("rpc", cfg.RPC),
+		zap.Int("factories", len(factories)),
+		zap.Uint64("from", cfg.FromBlock),
+		zap.Uint64("to", cfg.ToBlock),
+		zap.String("registry", cfg.Registry),
+		zap.Int("registered_pools", registry.Len()),
+		zap.String("checkpoint", checkpoint),
+		zap.Bool("follow", cfg.Follow),
+	)
+
+	if err := runner.Run(ctx); err != nil {
+		return err
+	}
+
+	logger.Info("discover complete", zap.Int("registered_pools", registry.Len()))
+	return nil
+}
+
+// discoverCheckpointPath keeps discovery progress next to the registry, so
+// it never collides with the checkpoint of the log indexer.
+func discoverCheckpointPath(registry string) string {
+	return strings.TrimSuffix(registry, ".json") + ".checkpoint.json"
+}
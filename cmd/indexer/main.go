@@ -5,17 +5,21 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"liquidityScope/internal/chain"
 	"liquidityScope/internal/config"
+	"liquidityScope/internal/dex"
 	"liquidityScope/internal/indexer"
 	"liquidityScope/internal/storage"
+	"liquidityScope/internal/storage/postgres"
 )
 
 func main() {
@@ -33,33 +37,86 @@ func main() {
 		RunE:  runIndexer,
 	}
 
-	runCmd.Flags().String("rpc", "", "BSC RPC URL")
+	runCmd.Flags().StringSlice("rpc", nil, "BSC RPC endpoints (comma-separated, url[;weight=N][;archive])")
 	runCmd.Flags().Uint64("from", 0, "start block (inclusive)")
 	runCmd.Flags().Uint64("to", 0, "end block (inclusive), 0 means latest")
 	runCmd.Flags().StringSlice("address", nil, "contract addresses (comma-separated)")
 	runCmd.Flags().StringSlice("topic0", nil, "topic0 signatures (comma-separated)")
+	runCmd.Flags().String("registry", "./data/pools.json", "discovered pool registry whose pools are indexed too (only when set explicitly alongside --address), empty disables")
+	runCmd.Flags().Bool("topic-only", false, "filter by topic0 only across all contracts and verify emitters as factory pools")
+	runCmd.Flags().String("quarantine", "./data/quarantine.jsonl", "output JSONL for logs from unverified emitters in topic-only mode")
 	runCmd.Flags().Uint64("batch-size", 2000, "blocks per batch")
-	runCmd.Flags().String("out", "./data/logs.jsonl", "output JSONL path")
-	runCmd.Flags().String("checkpoint", "./data/checkpoint.json", "checkpoint file path")
+	runCmd.Flags().Int("concurrency", 4, "block ranges fetched in parallel")
+	runCmd.Flags().Int("address-chunk", 100, "addresses per eth_getLogs filter; larger sets are queried in parallel chunks")
+	runCmd.Flags().String("out", "./data/logs.jsonl", "output JSONL path, - for stdout, or directory when --partition is set")
+	runCmd.Flags().String("format", "jsonl", "output format: jsonl, or parquet (one part file per batch under --out)")
+	runCmd.Flags().Uint64("row-group-blocks", 10000, "blocks per Parquet row group")
+	runCmd.Flags().String("partition", "", "write segments partitioned by \"day\" or \"blocks\" under --out, empty writes a single file")
+	runCmd.Flags().Uint64("segment-blocks", 100000, "blocks per partition when --partition=blocks")
+	runCmd.Flags().Int("segment-records", 1000000, "records per segment before rotating, 0 disables")
+	runCmd.Flags().String("sink", "file", "raw log sink: file (--out), or postgres (raw_logs table, checkpoint in the same transaction)")
+	runCmd.Flags().String("pg-dsn", "", "Postgres DSN when --sink=postgres")
+	runCmd.Flags().String("checkpoint", "./data/checkpoint.json", "checkpoint file path, or checkpoint name when --sink=postgres")
 	runCmd.Flags().Bool("checkpoint-enabled", true, "enable checkpointing")
 	runCmd.Flags().Int("max-retries", 5, "maximum retry attempts")
 	runCmd.Flags().Duration("retry-backoff", 500*time.Millisecond, "initial retry backoff")
+	runCmd.Flags().Bool("follow", false, "keep tailing the chain head after catching up")
+	runCmd.Flags().Uint64("confirmations", 15, "blocks behind head to treat as final in follow mode")
+	runCmd.Flags().Duration("poll-interval", 3*time.Second, "head polling interval in follow mode")
+	runCmd.Flags().Uint64("reorg-window", 64, "recent blocks tracked for reorg detection, 0 disables")
+	runCmd.Flags().String("block-times", "./data/block_times.bin", "persistent block timestamp cache, empty disables")
 	runCmd.Flags().String("log-level", "info", "log level (debug, info, warn, error)")
 
 	root.AddCommand(runCmd)
 
+	discoverCmd := &cobra.Command{
+		Use:   "discover",
+		Short: "Discover V3 pools from factory PoolCreated events",
+		RunE:  runDiscover,
+	}
+
+	discoverCmd.Flags().StringSlice("rpc", nil, "BSC RPC endpoints (comma-separated, url[;weight=N][;archive])")
+	discoverCmd.Flags().StringSlice("factory", nil, "V3 factory addresses (comma-separated, default PancakeSwap V3 and Uniswap V3)")
+	discoverCmd.Flags().Uint64("from", 0, "start block (inclusive)")
+	discoverCmd.Flags().Uint64("to", 0, "end block (inclusive), 0 means latest")
+	discoverCmd.Flags().String("registry", "./data/pools.json", "pool registry path")
+	discoverCmd.Flags().Uint64("batch-size", 2000, "blocks per batch")
+	discoverCmd.Flags().Int("concurrency", 4, "block ranges fetched in parallel")
+	discoverCmd.Flags().Int("max-retries", 5, "maximum retry attempts")
+	discoverCmd.Flags().Duration("retry-backoff", 500*time.Millisecond, "initial retry backoff")
+	discoverCmd.Flags().Bool("follow", false, "keep tailing the chain head after catching up")
+	discoverCmd.Flags().Uint64("confirmations", 15, "blocks behind head to treat as final in follow mode")
+	discoverCmd.Flags().Duration("poll-interval", 3*time.Second, "head polling interval in follow mode")
+	discoverCmd.Flags().Uint64("reorg-window", 64, "recent blocks tracked for reorg detection, 0 disables")
+	discoverCmd.Flags().String("block-times", "./data/block_times.bin", "persistent block timestamp cache, empty disables")
+	discoverCmd.Flags().String("log-level", "info", "log level (debug, info, warn, error)")
+
+	root.AddCommand(discoverCmd)
+
 	decodeCmd := &cobra.Command{
 		Use:   "decode",
 		Short: "Decode raw logs into typed events",
 		RunE:  runDecode,
 	}
 
-	decodeCmd.Flags().String("rpc", "", "BSC RPC URL")
-	decodeCmd.Flags().String("in", "", "input raw logs JSONL")
-	decodeCmd.Flags().String("out", "./data/typed_events.jsonl", "output typed events JSONL")
+	decodeCmd.Flags().StringSlice("rpc", nil, "BSC RPC endpoints (comma-separated, url[;weight=N][;archive])")
+	decodeCmd.Flags().String("in", "", "input raw logs JSONL, - for stdin, or a segmented output directory or manifest")
+	decodeCmd.Flags().String("source", "file", "raw log source: file (--in), or postgres (raw_logs table)")
+	decodeCmd.Flags().String("pg-dsn", "", "Postgres DSN when --source=postgres")
+	decodeCmd.Flags().Uint64("from", 0, "first block to decode, 0 means no lower bound")
+	decodeCmd.Flags().Uint64("to", 0, "last block to decode, 0 means no upper bound")
+	decodeCmd.Flags().String("out", "./data/typed_events.jsonl", "output typed events JSONL, - for stdout, or directory when --partition is set")
+	decodeCmd.Flags().String("format", "jsonl", "output format: jsonl, or parquet (one file per event kind under --out)")
+	decodeCmd.Flags().Uint64("row-group-blocks", 10000, "blocks per Parquet row group")
+	decodeCmd.Flags().String("partition", "", "write segments partitioned by \"day\" or \"blocks\" under --out, empty writes a single file")
+	decodeCmd.Flags().Uint64("segment-blocks", 100000, "blocks per partition when --partition=blocks")
+	decodeCmd.Flags().Int("segment-records", 1000000, "records per segment before rotating, 0 disables")
 	decodeCmd.Flags().String("errors", "./data/decode_errors.jsonl", "decode errors JSONL")
 	decodeCmd.Flags().String("topic0-map", "", "extra topic0->event mappings (comma-separated key=value)")
 	decodeCmd.Flags().Bool("include-live-meta", false, "include optional slot0/liquidity (requires archive RPC for historical accuracy)")
+	decodeCmd.Flags().String("registry", "./data/pools.json", "discovered pool registry used as pool metadata cache, empty disables")
+	decodeCmd.Flags().String("v4-registry", "./data/v4_pools.json", "V4 poolId->PoolKey registry filled from Initialize events, empty keeps it in memory")
+	decodeCmd.Flags().StringSlice("v4-pool-manager", nil, "V4 PoolManager addresses whose events are decoded (comma-separated, default Uniswap V4 on BSC)")
 	decodeCmd.Flags().String("log-level", "info", "log level (debug, info, warn, error)")
 
 	root.AddCommand(decodeCmd)
@@ -70,8 +127,8 @@ func main() {
 		RunE:  runAggregate,
 	}
 
-	aggregateCmd.Flags().String("rpc", "", "BSC RPC URL")
-	aggregateCmd.Flags().String("in", "", "input typed events JSONL")
+	aggregateCmd.Flags().StringSlice("rpc", nil, "BSC RPC endpoints (comma-separated, url[;weight=N][;archive])")
+	aggregateCmd.Flags().String("in", "", "input typed events JSONL, - for stdin, or a segmented output directory or manifest")
 	aggregateCmd.Flags().String("window", "5m", "aggregation window (e.g. 1m, 5m, 1h)")
 	aggregateCmd.Flags().String("pg-dsn", "", "Postgres DSN")
 	aggregateCmd.Flags().Int("batch-size", 1000, "batch size for DB writes")
@@ -81,6 +138,21 @@ func main() {
 
 	root.AddCommand(aggregateCmd)
 
+	prefetchCmd := &cobra.Command{
+		Use:   "prefetch-times",
+		Short: "Fill the block timestamp cache for a block range",
+		RunE:  runPrefetchTimes,
+	}
+
+	prefetchCmd.Flags().StringSlice("rpc", nil, "BSC RPC endpoints (comma-separated, url[;weight=N][;archive])")
+	prefetchCmd.Flags().Uint64("from", 0, "start block (inclusive)")
+	prefetchCmd.Flags().Uint64("to", 0, "end block (inclusive), 0 means latest")
+	prefetchCmd.Flags().Uint64("batch-size", 1000, "blocks per request batch")
+	prefetchCmd.Flags().String("block-times", "./data/block_times.bin", "persistent block timestamp cache")
+	prefetchCmd.Flags().String("log-level", "info", "log level (debug, info, warn, error)")
+
+	root.AddCommand(prefetchCmd)
+
 	if err := root.Execute(); err != nil {
 		os.Exit(1)
 	}
@@ -99,7 +171,7 @@ func runIndexer(cmd *cobra.Command, _ []string) error {
 	}
 	defer logger.Sync()
 
-	if cfg.RPCURL == "" {
+	if len(cfg.RPC) == 0 {
 		return fmt.Errorf("rpc url is required")
 	}
 
@@ -107,25 +179,81 @@ func runIndexer(cmd *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
-	if len(addresses) == 0 {
-		return fmt.Errorf("address list is required")
-	}
-
 	topic0, err := indexer.ParseTopic0(cfg.Topic0)
 	if err != nil {
 		return err
 	}
 
+	// Explicit addresses select exactly those pools; the registry only adds to
+	// them when it was asked for.
+	var registry *dex.PoolRegistry
+	registered := 0
+	if cfg.Registry != "" && (cfg.TopicOnly || len(addresses) == 0 || cfg.RegistrySet) {
+		registry, err = dex.LoadPoolRegistry(cfg.Registry)
+		if err != nil {
+			return err
+		}
+		registered = registry.Len()
+	}
+
+	var refreshAddresses func() ([]common.Address, error)
+	if cfg.TopicOnly {
+		if len(addresses) > 0 {
+			return fmt.Errorf("address list cannot be combined with topic-only mode")
+		}
+		if registry == nil {
+			return fmt.Errorf("registry path is required in topic-only mode")
+		}
+		if cfg.Quarantine == "" {
+			return fmt.Errorf("quarantine path is required in topic-only mode")
+		}
+		if len(topic0) == 0 {
+			topic0, err = dex.V3PoolEventTopics()
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		if registry != nil {
+			refreshAddresses = registryAddresses(cfg.Registry, addresses)
+			addresses = mergeAddresses(addresses, registry.Addresses())
+		}
+		if len(addresses) == 0 {
+			return fmt.Errorf("address list is required")
+		}
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	chainClient, err := chain.NewClient(ctx, cfg.RPCURL)
+	chainClient, err := dialChain(ctx, cfg.RPC)
 	if err != nil {
-		return fmt.Errorf("connect rpc: %w", err)
+		return err
 	}
 	defer chainClient.Close()
+	defer logRPCUsage(logger, chainClient)
 
-	storageSink := storage.NewJsonlStorage(cfg.Out)
+	blockTimes, err := openBlockTimes(chainClient, cfg.BlockTimes)
+	if err != nil {
+		return err
+	}
+	if blockTimes != nil {
+		defer blockTimes.Close()
+	}
+
+	storageSink, closeSink, err := openLogStorage(ctx, cfg, chainClient)
+	if err != nil {
+		return err
+	}
+	defer closeSink()
+
+	var verifier indexer.Verifier
+	var quarantine storage.Storage
+	if cfg.TopicOnly {
+		deployers := []dex.PoolDeployer{dex.PancakeV3Deployer, dex.UniswapV3Deployer}
+		verifier = dex.NewPoolVerifier(chainClient, registry, deployers, logger)
+		quarantine = storage.NewJsonlStorage(cfg.Quarantine)
+	}
 
 	runner := indexer.NewRunner(indexer.RunConfig{
 		FromBlock:         cfg.FromBlock,
@@ -137,23 +265,195 @@ func runIndexer(cmd *cobra.Command, _ []string) error {
 		CheckpointEnabled: cfg.CheckpointEnabled,
 		MaxRetries:        cfg.MaxRetries,
 		RetryBackoff:      cfg.RetryBackoff,
+		Follow:            cfg.Follow,
+		Confirmations:     cfg.Confirmations,
+		PollInterval:      cfg.PollInterval,
+		ReorgWindow:       cfg.ReorgWindow,
+		Concurrency:       cfg.Concurrency,
+		AddressChunkSize:  cfg.AddressChunk,
+		Verifier:          verifier,
+		Quarantine:        quarantine,
+		RefreshAddresses:  refreshAddresses,
+		OutputPath:        absPath(cfg.Out),
+		OutputKind:        outputKind(cfg),
+		QuarantinePath:    absPath(cfg.Quarantine),
 	}, chainClient, storageSink, logger)
 
 	logger.Info("indexer start",
-		zap.String("rpc", cfg.RPCURL),
+		zap.Strings("rpc", cfg.RPC),
 		zap.Uint64("from", cfg.FromBlock),
 		zap.Uint64("to", cfg.ToBlock),
 		zap.Int("addresses", len(addresses)),
+		zap.Int("registered_pools", registered),
+		zap.Bool("topic_only", cfg.TopicOnly),
 		zap.Int("topic0", len(topic0)),
 		zap.Uint64("batch_size", cfg.BatchSize),
+		zap.Int("concurrency", cfg.Concurrency),
+		zap.String("sink", cfg.Sink),
 		zap.String("out", cfg.Out),
+		zap.String("format", cfg.Format),
+		zap.String("partition", cfg.Partition),
 		zap.Bool("checkpoint_enabled", cfg.CheckpointEnabled),
 		zap.String("checkpoint", cfg.Checkpoint),
+		zap.Bool("follow", cfg.Follow),
+		zap.Uint64("confirmations", cfg.Confirmations),
+		zap.Uint64("reorg_window", cfg.ReorgWindow),
+		zap.String("block_times", cfg.BlockTimes),
 	)
 
 	return runner.Run(ctx)
 }
 
+// openLogStorage builds the raw log sink selected by --sink, --format and
+// --partition. The returned func releases it.
+func openLogStorage(ctx context.Context, cfg config.Config, chainClient *chain.Client) (storage.Storage, func(), error) {
+	nop := func() {}
+	switch cfg.Sink {
+	case "postgres":
+		if cfg.PGDSN == "" {
+			return nil, nil, fmt.Errorf("pg dsn is required when sink is postgres")
+		}
+		chainID, err := chainClient.GetChainID(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get chain id: %w", err)
+		}
+		store, err := postgres.NewStore(ctx, cfg.PGDSN)
+		if err != nil {
+			return nil, nil, err
+		}
+		return postgres.NewRawLogSink(ctx, store, cfg.Checkpoint, chainID.Uint64()), store.Close, nil
+	case "file", "":
+	default:
+		return nil, nil, fmt.Errorf("unknown sink %q", cfg.Sink)
+	}
+
+	if cfg.Out == storage.StdioPath {
+		if cfg.Format == storage.FormatParquet || cfg.Partition != "" {
+			return nil, nil, fmt.Errorf("--out - writes JSONL only, without --partition")
+		}
+		return storage.NewJsonlStreamStorage(os.Stdout), nop, nil
+	}
+
+	switch cfg.Format {
+	case storage.FormatParquet:
+		if cfg.Partition != "" {
+			return nil, nil, fmt.Errorf("--partition cannot be combined with --format parquet")
+		}
+		sink, err := storage.NewParquetStorage(cfg.Out, cfg.RowGroupBlocks)
+		return sink, nop, err
+	case storage.FormatJSONL, "":
+		if cfg.Partition == "" {
+			return storage.NewJsonlStorage(cfg.Out), nop, nil
+		}
+		sink, err := storage.NewSegmentedStorage(cfg.Out, storage.Partitioning{
+			Mode:       cfg.Partition,
+			BlockSpan:  cfg.SegmentBlocks,
+			MaxRecords: cfg.SegmentRecords,
+		})
+		return sink, nop, err
+	default:
+		return nil, nil, fmt.Errorf("unknown output format %q", cfg.Format)
+	}
+}
+
+// outputKind names the layout of the raw log sink, recorded in checkpoints
+// next to the output offset.
+func outputKind(cfg config.Config) string {
+	switch {
+	case cfg.Sink == "postgres":
+		return "postgres"
+	case cfg.Out == storage.StdioPath:
+		return "stdout"
+	case cfg.Format == storage.FormatParquet:
+		return storage.FormatParquet
+	case cfg.Partition != "":
+		return "jsonl-" + cfg.Partition
+	default:
+		return storage.FormatJSONL
+	}
+}
+
+// absPath resolves path against the working directory so checkpoints compare
+// outputs by location rather than by spelling. Stdio and empty paths are kept.
+func absPath(path string) string {
+	if path == "" || path == storage.StdioPath {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// registryAddresses returns a loader that rereads the registry at path and
+// merges its pools into explicit, so follow mode picks up discovered pools.
+func registryAddresses(path string, explicit []common.Address) func() ([]common.Address, error) {
+	return func() ([]common.Address, error) {
+		registry, err := dex.LoadPoolRegistry(path)
+		if err != nil {
+			return nil, err
+		}
+		return mergeAddresses(explicit, registry.Addresses()), nil
+	}
+}
+
+// mergeAddresses appends extra addresses that are not already in base.
+func mergeAddresses(base, extra []common.Address) []common.Address {
+	seen := make(map[common.Address]struct{}, len(base)+len(extra))
+	out := make([]common.Address, 0, len(base)+len(extra))
+	for _, list := range [][]common.Address{base, extra} {
+		for _, addr := range list {
+			if _, ok := seen[addr]; ok {
+				continue
+			}
+			seen[addr] = struct{}{}
+			out = append(out, addr)
+		}
+	}
+	return out
+}
+
+// dialChain parses endpoint specs and connects the pooled chain client.
+func dialChain(ctx context.Context, specs []string) (*chain.Client, error) {
+	endpoints, err := chain.ParseEndpoints(specs)
+	if err != nil {
+		return nil, fmt.Errorf("parse rpc endpoints: %w", err)
+	}
+	chainClient, err := chain.NewClient(ctx, endpoints)
+	if err != nil {
+		return nil, fmt.Errorf("connect rpc: %w", err)
+	}
+	return chainClient, nil
+}
+
+// openBlockTimes attaches the persistent timestamp cache at path to the
+// client. An empty path keeps timestamps in memory only.
+func openBlockTimes(chainClient *chain.Client, path string) (*chain.TimestampFile, error) {
+	if path == "" {
+		return nil, nil
+	}
+	store, err := chain.OpenTimestampFile(path)
+	if err != nil {
+		return nil, err
+	}
+	chainClient.UseTimestampFile(store)
+	return store, nil
+}
+
+// logRPCUsage logs the requests sent by the client, by method.
+func logRPCUsage(logger *zap.Logger, chainClient *chain.Client) {
+	usage := chainClient.Usage()
+	fields := []zap.Field{
+		zap.Uint64("total", usage.Total()),
+		zap.Uint64("throttled", usage.Throttled),
+	}
+	for _, method := range usage.Methods() {
+		fields = append(fields, zap.Uint64(method, usage.Calls[method]))
+	}
+	logger.Info("rpc usage", fields...)
+}
+
 func newLogger(level string) (*zap.Logger, error) {
 	cfg := zap.NewProductionConfig()
 	cfg.Level = zap.NewAtomicLevel()
@@ -161,6 +461,9 @@ func newLogger(level string) (*zap.Logger, error) {
 		return nil, err
 	}
 
+	// Logs stay on stderr so stdout can carry data between piped stages.
+	cfg.OutputPaths = []string{"stderr"}
+	cfg.ErrorOutputPaths = []string{"stderr"}
 	cfg.EncoderConfig.TimeKey = "ts"
 	cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
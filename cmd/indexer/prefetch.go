@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"liquidityScope/internal/config"
+)
+
+func runPrefetchTimes(cmd *cobra.Command, _ []string) error {
+	cfgFile, _ := cmd.Flags().GetString("config")
+	cfg, err := config.LoadPrefetch(cfgFile, cmd.Flags())
+	if err != nil {
+		return err
+	}
+
+	logger, err := newLogger(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	defer logger.Sync()
+
+	if len(cfg.RPC) == 0 {
+		return fmt.Errorf("rpc url is required")
+	}
+	if cfg.BlockTimes == "" {
+		return fmt.Errorf("block-times path is required")
+	}
+	if cfg.BatchSize == 0 {
+		return fmt.Errorf("batch size must be > 0")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	chainClient, err := dialChain(ctx, cfg.RPC)
+	if err != nil {
+		return err
+	}
+	defer chainClient.Close()
+	defer logRPCUsage(logger, chainClient)
+
+	blockTimes, err := openBlockTimes(chainClient, cfg.BlockTimes)
+	if err != nil {
+		return err
+	}
+	defer blockTimes.Close()
+
+	to := cfg.ToBlock
+	if to == 0 {
+		to, err = chainClient.LatestBlockNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("get latest block: %w", err)
+		}
+	}
+	if cfg.FromBlock > to {
+		return fmt.Errorf("from block must be <= to block")
+	}
+
+	logger.Info("prefetch start",
+		zap.Strings("rpc", cfg.RPC),
+		zap.Uint64("from", cfg.FromBlock),
+		zap.Uint64("to", to),
+		zap.Uint64("batch_size", cfg.BatchSize),
+		zap.String("block_times", cfg.BlockTimes),
+	)
+
+	var total int
+	for start := cfg.FromBlock; start <= to; start += cfg.BatchSize {
+		end := start + cfg.BatchSize - 1
+		if end > to || end < start {
+			end = to
+		}
+
+		numbers := make([]uint64, 0, end-start+1)
+		for number := start; number <= end; number++ {
+			numbers = append(numbers, number)
+		}
+		if _, err := chainClient.BlockTimestamps(ctx, numbers); err != nil {
+			return fmt.Errorf("prefetch blocks %d-%d: %w", start, end, err)
+		}
+		total += len(numbers)
+
+		logger.Info("prefetch progress", zap.Uint64("from", start), zap.Uint64("to", end))
+		if end == to {
+			break
+		}
+	}
+
+	logger.Info("prefetch complete", zap.Int("blocks", total))
+	return nil
+}
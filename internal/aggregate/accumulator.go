@@ -9,10 +9,11 @@ import (
 	"liquidityScope/internal/model"
 )
 
-// Accumulator holds aggregate values for a pool window.
+// Accumulator holds aggregate values for a pool window. Pool is the pool
+// contract address, or the poolId of a V4 pool.
 type Accumulator struct {
 	ChainID     uint64
-	PoolAddress string
+	Pool        string
 	PoolMeta    model.PoolMeta
 	WindowStart uint64
 	WindowEnd   uint64
@@ -21,25 +22,47 @@ type Accumulator struct {
 	Volume1     *big.Int
 	Fee0        *big.Int
 	Fee1        *big.Int
-	LastBlock   uint64
-	LastTS      uint64
-	FirstBlock  uint64
+	// ProtocolFee0 and ProtocolFee1 are the protocol's share of Fee0 and
+	// Fee1, which are kept net of it.
+	ProtocolFee0 *big.Int
+	ProtocolFee1 *big.Int
+	// Reserve0 and Reserve1 are the reserves of the last V2 Sync in the
+	// window, nil for other pools.
+	Reserve0 *big.Int
+	Reserve1 *big.Int
+	// syncs are the V2 Syncs of the window in log order, kept so an orphaned
+	// Sync hands the reserves back to the one before it.
+	syncs      []reserveSync
+	LastBlock  uint64
+	LastTS     uint64
+	FirstBlock uint64
+	// FeeTrack is the fee history of a dynamic-fee pool, shared by all its
+	// windows. Swaps use the fee in force at their log instead of the fee tier.
+	FeeTrack *FeeTrack
+	// UnknownFeeSwaps counts the swaps of a dynamic-fee pool that precede its
+	// first known fee, so their fees are missing from Fee0 and Fee1.
+	UnknownFeeSwaps uint64
+	// ProtocolTrack is the fee protocol history of a V3 pool, shared by all
+	// its windows. Flash fees are split by the fee protocol at their log.
+	ProtocolTrack *ProtocolTrack
 }
 
 func NewAccumulator(record model.TypedEventRecord, windowStart, windowEnd uint64) *Accumulator {
 	return &Accumulator{
-		ChainID:     record.ChainID,
-		PoolAddress: record.Address,
-		PoolMeta:    record.PoolMeta,
-		WindowStart: windowStart,
-		WindowEnd:   windowEnd,
-		Volume0:     big.NewInt(0),
-		Volume1:     big.NewInt(0),
-		Fee0:        big.NewInt(0),
-		Fee1:        big.NewInt(0),
-		LastBlock:   record.BlockNumber,
-		LastTS:      record.Timestamp,
-		FirstBlock:  record.BlockNumber,
+		ChainID:      record.ChainID,
+		Pool:         record.Pool(),
+		PoolMeta:     record.PoolMeta,
+		WindowStart:  windowStart,
+		WindowEnd:    windowEnd,
+		Volume0:      big.NewInt(0),
+		Volume1:      big.NewInt(0),
+		Fee0:         big.NewInt(0),
+		Fee1:         big.NewInt(0),
+		ProtocolFee0: big.NewInt(0),
+		ProtocolFee1: big.NewInt(0),
+		LastBlock:    record.BlockNumber,
+		LastTS:       record.Timestamp,
+		FirstBlock:   record.BlockNumber,
 	}
 }
 
@@ -54,39 +77,204 @@ func (a *Accumulator) AddEvent(record model.TypedEventRecord) error {
 
 	switch strings.ToLower(record.EventName) {
 	case "swap":
-		var swap model.SwapEventData
-		if err := json.Unmarshal(record.Decoded, &swap); err != nil {
-			return fmt.Errorf("decode swap: %w", err)
+		swap, err := decodeSwapRecord(record)
+		if err != nil {
+			return err
+		}
+		unknownFee := false
+		if a.FeeTrack != nil {
+			zeroForOne := swap.amount0.Sign() > 0
+			swap.feeRate = record.PoolMeta.SwapFee(zeroForOne)
+			if fee, ok := a.FeeTrack.At(record.BlockNumber, record.LogIndex, zeroForOne); ok {
+				swap.feeRate = fee
+			}
+			unknownFee = swap.feeRate == 0
+		}
+		if record.Removed {
+			if unknownFee && a.UnknownFeeSwaps > 0 {
+				a.UnknownFeeSwaps--
+			}
+			return a.revertSwap(swap)
+		}
+		if unknownFee {
+			a.UnknownFeeSwaps++
 		}
 		return a.applySwap(swap)
+	case "sync":
+		if record.PoolMeta.Protocol != model.ProtocolV2 {
+			return nil
+		}
+		if record.Removed {
+			a.revertSync(record.BlockNumber, record.LogIndex)
+			return nil
+		}
+		var sync model.SyncEventData
+		if err := json.Unmarshal(record.Decoded, &sync); err != nil {
+			return fmt.Errorf("decode sync: %w", err)
+		}
+		reserves, err := parseBigInts(sync.Reserve0, sync.Reserve1)
+		if err != nil {
+			return err
+		}
+		a.applySync(reserveSync{block: record.BlockNumber, logIndex: record.LogIndex, reserve0: reserves[0], reserve1: reserves[1]})
+		return nil
+	case "flash":
+		var flash model.FlashEventData
+		if err := json.Unmarshal(record.Decoded, &flash); err != nil {
+			return fmt.Errorf("decode flash: %w", err)
+		}
+		paid, err := parseBigInts(flash.Paid0, flash.Paid1)
+		if err != nil {
+			return err
+		}
+		share0, share1 := big.NewInt(0), big.NewInt(0)
+		if a.ProtocolTrack != nil {
+			if protocol, ok := a.ProtocolTrack.At(record.BlockNumber, record.LogIndex); ok {
+				share0, share1 = protocol.Share(paid[0], paid[1])
+			}
+		}
+		if record.Removed {
+			a.Fee0.Sub(a.Fee0, paid[0])
+			a.Fee1.Sub(a.Fee1, paid[1])
+			a.addProtocolFees(share0.Neg(share0), share1.Neg(share1))
+			return nil
+		}
+		a.Fee0.Add(a.Fee0, paid[0])
+		a.Fee1.Add(a.Fee1, paid[1])
+		a.addProtocolFees(share0, share1)
+		return nil
 	default:
 		return nil
 	}
 }
 
-func (a *Accumulator) applySwap(swap model.SwapEventData) error {
-	amount0, err := parseBigInt(swap.Amount0)
-	if err != nil {
-		return err
+// reserveSync is the reserves reported by a V2 Sync at its log position.
+type reserveSync struct {
+	block    uint64
+	logIndex uint64
+	reserve0 *big.Int
+	reserve1 *big.Int
+}
+
+func (s reserveSync) before(other reserveSync) bool {
+	return s.block < other.block || (s.block == other.block && s.logIndex < other.logIndex)
+}
+
+// applySync records a Sync and moves the reserves to the latest one.
+func (a *Accumulator) applySync(sync reserveSync) {
+	i := len(a.syncs)
+	for i > 0 && sync.before(a.syncs[i-1]) {
+		i--
+	}
+	a.syncs = append(a.syncs, reserveSync{})
+	copy(a.syncs[i+1:], a.syncs[i:])
+	a.syncs[i] = sync
+	a.setReserves()
+}
+
+// revertSync drops a Sync orphaned by a reorg. The reserves fall back to the
+// Sync before it, or are cleared when none is left in the window so TVL is
+// read from the chain instead.
+func (a *Accumulator) revertSync(block, logIndex uint64) {
+	for i, sync := range a.syncs {
+		if sync.block == block && sync.logIndex == logIndex {
+			a.syncs = append(a.syncs[:i], a.syncs[i+1:]...)
+			break
+		}
+	}
+	a.setReserves()
+}
+
+func (a *Accumulator) setReserves() {
+	if len(a.syncs) == 0 {
+		a.Reserve0, a.Reserve1 = nil, nil
+		return
 	}
-	amount1, err := parseBigInt(swap.Amount1)
-	if err != nil {
-		return err
+	last := a.syncs[len(a.syncs)-1]
+	a.Reserve0, a.Reserve1 = last.reserve0, last.reserve1
+}
+
+// swapDelta is a swap as signed pool balance deltas, positive for the token
+// paid in, with the fee rate charged on it.
+type swapDelta struct {
+	amount0      *big.Int
+	amount1      *big.Int
+	protocolFee0 *big.Int
+	protocolFee1 *big.Int
+	feeRate      uint32
+}
+
+// decodeSwapRecord reads a swap payload. V2 swaps are turned into pool deltas
+// from their in and out amounts, and V4 swaps, which report the swapper's
+// deltas, are negated and charged the fee of the swap itself.
+func decodeSwapRecord(record model.TypedEventRecord) (swapDelta, error) {
+	switch record.PoolMeta.Protocol {
+	case model.ProtocolV2:
+		var swap model.V2SwapEventData
+		if err := json.Unmarshal(record.Decoded, &swap); err != nil {
+			return swapDelta{}, fmt.Errorf("decode swap: %w", err)
+		}
+		amounts, err := parseBigInts(swap.Amount0In, swap.Amount0Out, swap.Amount1In, swap.Amount1Out)
+		if err != nil {
+			return swapDelta{}, err
+		}
+		return swapDelta{
+			amount0:      new(big.Int).Sub(amounts[0], amounts[1]),
+			amount1:      new(big.Int).Sub(amounts[2], amounts[3]),
+			protocolFee0: big.NewInt(0),
+			protocolFee1: big.NewInt(0),
+			feeRate:      record.PoolMeta.Fee,
+		}, nil
+	case model.ProtocolV4:
+		var swap model.V4SwapEventData
+		if err := json.Unmarshal(record.Decoded, &swap); err != nil {
+			return swapDelta{}, fmt.Errorf("decode swap: %w", err)
+		}
+		amounts, err := parseBigInts(swap.Amount0, swap.Amount1)
+		if err != nil {
+			return swapDelta{}, err
+		}
+		return swapDelta{
+			amount0:      amounts[0].Neg(amounts[0]),
+			amount1:      amounts[1].Neg(amounts[1]),
+			protocolFee0: big.NewInt(0),
+			protocolFee1: big.NewInt(0),
+			feeRate:      swap.Fee,
+		}, nil
+	default:
+		var swap model.SwapEventData
+		if err := json.Unmarshal(record.Decoded, &swap); err != nil {
+			return swapDelta{}, fmt.Errorf("decode swap: %w", err)
+		}
+		amounts, err := parseBigInts(swap.Amount0, swap.Amount1, swap.ProtocolFeesToken0, swap.ProtocolFeesToken1)
+		if err != nil {
+			return swapDelta{}, err
+		}
+		return swapDelta{
+			amount0:      amounts[0],
+			amount1:      amounts[1],
+			protocolFee0: amounts[2],
+			protocolFee1: amounts[3],
+			feeRate:      record.PoolMeta.Fee,
+		}, nil
 	}
+}
 
-	absAdd(a.Volume0, amount0)
-	absAdd(a.Volume1, amount1)
-	feeRate := a.PoolMeta.Fee
+func (a *Accumulator) applySwap(swap swapDelta) error {
+	absAdd(a.Volume0, swap.amount0)
+	absAdd(a.Volume1, swap.amount1)
+	feeRate := swap.feeRate
 	if feeRate == 0 {
 		a.SwapCount++
 		return nil
 	}
+	a.addProtocolFees(swap.protocolFee0, swap.protocolFee1)
 
-	if amount0.Sign() < 0 && amount1.Sign() > 0 {
-		fee := feeFromAmount(amount1, feeRate)
+	if swap.amount0.Sign() < 0 && swap.amount1.Sign() > 0 {
+		fee := feeFromAmount(swap.amount1, feeRate)
 		a.Fee1.Add(a.Fee1, fee)
-	} else if amount1.Sign() < 0 && amount0.Sign() > 0 {
-		fee := feeFromAmount(amount0, feeRate)
+	} else if swap.amount1.Sign() < 0 && swap.amount0.Sign() > 0 {
+		fee := feeFromAmount(swap.amount0, feeRate)
 		a.Fee0.Add(a.Fee0, fee)
 	}
 
@@ -94,6 +282,42 @@ func (a *Accumulator) applySwap(swap model.SwapEventData) error {
 	return nil
 }
 
+// revertSwap removes the contribution of a swap whose log was orphaned by a reorg.
+func (a *Accumulator) revertSwap(swap swapDelta) error {
+	absSub(a.Volume0, swap.amount0)
+	absSub(a.Volume1, swap.amount1)
+	if a.SwapCount > 0 {
+		a.SwapCount--
+	}
+	feeRate := swap.feeRate
+	if feeRate == 0 {
+		return nil
+	}
+	a.addProtocolFees(new(big.Int).Neg(swap.protocolFee0), new(big.Int).Neg(swap.protocolFee1))
+
+	if swap.amount0.Sign() < 0 && swap.amount1.Sign() > 0 {
+		a.Fee1.Sub(a.Fee1, feeFromAmount(swap.amount1, feeRate))
+	} else if swap.amount1.Sign() < 0 && swap.amount0.Sign() > 0 {
+		a.Fee0.Sub(a.Fee0, feeFromAmount(swap.amount0, feeRate))
+	}
+	return nil
+}
+
+// addProtocolFees moves the protocol fees of a swap or flash out of the LP
+// fees. Pools with an unknown fee tier have no LP fee estimate to net them from.
+func (a *Accumulator) addProtocolFees(fee0, fee1 *big.Int) {
+	a.ProtocolFee0.Add(a.ProtocolFee0, fee0)
+	a.ProtocolFee1.Add(a.ProtocolFee1, fee1)
+	a.Fee0.Sub(a.Fee0, fee0)
+	a.Fee1.Sub(a.Fee1, fee1)
+}
+
+// HasProtocolFees reports whether any swap or flash in the window paid
+// protocol fees.
+func (a *Accumulator) HasProtocolFees() bool {
+	return a.ProtocolFee0.Sign() != 0 || a.ProtocolFee1.Sign() != 0
+}
+
 func parseBigInt(value string) (*big.Int, error) {
 	if value == "" {
 		return big.NewInt(0), nil
@@ -105,6 +329,18 @@ func parseBigInt(value string) (*big.Int, error) {
 	return parsed, nil
 }
 
+func parseBigInts(values ...string) ([]*big.Int, error) {
+	out := make([]*big.Int, 0, len(values))
+	for _, value := range values {
+		parsed, err := parseBigInt(value)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, parsed)
+	}
+	return out, nil
+}
+
 func absAdd(target *big.Int, value *big.Int) {
 	if value == nil || target == nil {
 		return
@@ -113,6 +349,14 @@ func absAdd(target *big.Int, value *big.Int) {
 	target.Add(target, abs)
 }
 
+func absSub(target *big.Int, value *big.Int) {
+	if value == nil || target == nil {
+		return
+	}
+	abs := new(big.Int).Abs(value)
+	target.Sub(target, abs)
+}
+
 func feeFromAmount(amountIn *big.Int, feeRate uint32) *big.Int {
 	if amountIn == nil {
 		return big.NewInt(0)
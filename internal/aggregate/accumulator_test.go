@@ -0,0 +1,109 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"liquidityScope/internal/model"
+)
+
+const testPool = "0x4444444444444444444444444444444444444444"
+
+func testRecord(t *testing.T, event string, meta model.PoolMeta, block, logIndex uint64, payload interface{}) model.TypedEventRecord {
+	t.Helper()
+	decoded, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	return model.TypedEventRecord{
+		ChainID:     56,
+		BlockNumber: block,
+		LogIndex:    logIndex,
+		Address:     testPool,
+		EventName:   event,
+		Timestamp:   1000 + block,
+		Decoded:     decoded,
+		PoolMeta:    meta,
+	}
+}
+
+func TestAccumulatorRevertSync(t *testing.T) {
+	meta := model.PoolMeta{Protocol: model.ProtocolV2, Fee: 2500}
+	first := testRecord(t, "Sync", meta, 10, 1, model.SyncEventData{Reserve0: "100", Reserve1: "200"})
+	second := testRecord(t, "Sync", meta, 11, 4, model.SyncEventData{Reserve0: "150", Reserve1: "180"})
+
+	acc := NewAccumulator(first, 960, 1020)
+	for _, record := range []model.TypedEventRecord{first, second} {
+		if err := acc.AddEvent(record); err != nil {
+			t.Fatalf("add sync: %v", err)
+		}
+	}
+	if acc.Reserve0.String() != "150" || acc.Reserve1.String() != "180" {
+		t.Fatalf("expected latest reserves, got %s/%s", acc.Reserve0, acc.Reserve1)
+	}
+
+	second.Removed = true
+	if err := acc.AddEvent(second); err != nil {
+		t.Fatalf("revert sync: %v", err)
+	}
+	if acc.Reserve0.String() != "100" || acc.Reserve1.String() != "200" {
+		t.Fatalf("expected previous reserves after revert, got %s/%s", acc.Reserve0, acc.Reserve1)
+	}
+
+	first.Removed = true
+	if err := acc.AddEvent(first); err != nil {
+		t.Fatalf("revert sync: %v", err)
+	}
+	if acc.Reserve0 != nil || acc.Reserve1 != nil {
+		t.Fatalf("expected reserves cleared, got %s/%s", acc.Reserve0, acc.Reserve1)
+	}
+}
+
+func TestAccumulatorFlashNetsProtocolShare(t *testing.T) {
+	meta := model.PoolMeta{Fee: 500}
+	track := &ProtocolTrack{}
+	track.Set(FeeProtocol{Block: 10, LogIndex: 0, Timestamp: 1010, Token0: 3200, Token1: 0, Denominator: 10000})
+	flash := testRecord(t, "Flash", meta, 11, 3, model.FlashEventData{Paid0: "1000", Paid1: "500"})
+
+	acc := NewAccumulator(flash, 960, 1020)
+	acc.ProtocolTrack = track
+	if err := acc.AddEvent(flash); err != nil {
+		t.Fatalf("add flash: %v", err)
+	}
+	if acc.Fee0.String() != "680" || acc.ProtocolFee0.String() != "320" {
+		t.Fatalf("expected token0 fees split 680/320, got %s/%s", acc.Fee0, acc.ProtocolFee0)
+	}
+	if acc.Fee1.String() != "500" || acc.ProtocolFee1.Sign() != 0 {
+		t.Fatalf("expected token1 fees to LPs only, got %s/%s", acc.Fee1, acc.ProtocolFee1)
+	}
+
+	flash.Removed = true
+	if err := acc.AddEvent(flash); err != nil {
+		t.Fatalf("revert flash: %v", err)
+	}
+	if acc.Fee0.Sign() != 0 || acc.Fee1.Sign() != 0 || acc.HasProtocolFees() {
+		t.Fatalf("expected fees cleared, got %s/%s protocol %s/%s", acc.Fee0, acc.Fee1, acc.ProtocolFee0, acc.ProtocolFee1)
+	}
+}
+
+func TestAccumulatorDirectionalFee(t *testing.T) {
+	meta := model.PoolMeta{Protocol: model.ProtocolAlgebra, DynamicFee: true, Fee: 100, FeeOtz: 200}
+	track := &FeeTrack{}
+	zeroForOne := testRecord(t, "Swap", meta, 10, 0, model.SwapEventData{Amount0: "1000000", Amount1: "-990000"})
+	oneForZero := testRecord(t, "Swap", meta, 10, 1, model.SwapEventData{Amount0: "-990000", Amount1: "1000000"})
+	track.Set(FeeChange{Block: 11, LogIndex: 0, Timestamp: 1011, Fee: 400, FeeOtz: 800})
+	laterZeroForOne := testRecord(t, "Swap", meta, 11, 1, model.SwapEventData{Amount0: "1000000", Amount1: "-990000"})
+	laterOneForZero := testRecord(t, "Swap", meta, 11, 2, model.SwapEventData{Amount0: "-990000", Amount1: "1000000"})
+
+	acc := NewAccumulator(zeroForOne, 960, 1020)
+	acc.FeeTrack = track
+	for _, record := range []model.TypedEventRecord{zeroForOne, oneForZero, laterZeroForOne, laterOneForZero} {
+		if err := acc.AddEvent(record); err != nil {
+			t.Fatalf("add swap: %v", err)
+		}
+	}
+	// Token0 in: 100 then 400 per million; token1 in: 200 then 800.
+	if acc.Fee0.String() != "500" || acc.Fee1.String() != "1000" {
+		t.Fatalf("expected directional fees 500/1000, got %s/%s", acc.Fee0, acc.Fee1)
+	}
+}
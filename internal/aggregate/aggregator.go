@@ -6,8 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math/big"
-	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,15 +14,28 @@ import (
 	"go.uber.org/zap"
 
 	"liquidityScope/internal/chain"
+	"liquidityScope/internal/dex"
 	"liquidityScope/internal/model"
+	"liquidityScope/internal/storage"
 	"liquidityScope/internal/storage/postgres"
 )
 
 const (
 	feeMethodApprox = "approx_from_feeTier"
-	tvlMethodBlock  = "balance_of_block"
-	tvlMethodLatest = "balance_of_latest"
-	tvlMethodNone   = "unavailable"
+	// feeMethodApproxNet marks fees approximated from the fee tier minus the
+	// protocol fees reported by the pool.
+	feeMethodApproxNet = "approx_from_feeTier_net_protocol"
+	// feeMethodFeeEvents marks fees approximated from the fee in force at each
+	// swap, tracked from the pool's fee-change events.
+	feeMethodFeeEvents = "approx_from_fee_events"
+	// feeMethodFeeUnknown marks windows of dynamic-fee pools with swaps before
+	// the first known fee, whose fees are left out.
+	feeMethodFeeUnknown = "fee_unknown"
+	tvlMethodBlock      = "balance_of_block"
+	tvlMethodLatest     = "balance_of_latest"
+	// tvlMethodReserves marks V2 pairs valued at the reserves of their last Sync.
+	tvlMethodReserves = "sync_reserves"
+	tvlMethodNone     = "unavailable"
 )
 
 // Config controls aggregation behavior.
@@ -42,7 +54,13 @@ type Aggregator struct {
 	logger       *zap.Logger
 	decimals     *TokenDecimalsCache
 	accumulators map[string]*Accumulator
+	closed       []*Accumulator
 	poolSeen     map[string]model.Pool
+	feeTracks    map[string]*FeeTrack
+	protocols    map[string]*ProtocolTrack
+	// tombstones holds the tombstones already netted out, marked once read
+	// again in this run.
+	tombstones map[string]bool
 }
 
 func NewAggregator(cfg Config, store *postgres.Store, chainClient *chain.Client, logger *zap.Logger) *Aggregator {
@@ -58,10 +76,13 @@ func NewAggregator(cfg Config, store *postgres.Store, chainClient *chain.Client,
 		decimals:     NewTokenDecimalsCache(),
 		accumulators: make(map[string]*Accumulator),
 		poolSeen:     make(map[string]model.Pool),
+		feeTracks:    make(map[string]*FeeTrack),
+		protocols:    make(map[string]*ProtocolTrack),
+		tombstones:   make(map[string]bool),
 	}
 }
 
-// Run executes aggregation over a typed events JSONL file.
+// Run executes aggregation over a typed events JSONL file or segmented output.
 func (a *Aggregator) Run(ctx context.Context, inputPath string) error {
 	if a.store == nil {
 		return fmt.Errorf("store is nil")
@@ -76,85 +97,50 @@ func (a *Aggregator) Run(ctx context.Context, inputPath string) error {
 		a.cfg.BatchSize = 1000
 	}
 
-	startTs, err := a.loadStartTimestamp(ctx)
+	startTs, err := a.loadState(ctx)
 	if err != nil {
 		return err
 	}
 
-	file, err := os.Open(inputPath)
+	// Segments whose events all precede the resume point are not read. The fee
+	// changes they hold come from the saved state instead.
+	input, err := storage.OpenInput(inputPath, storage.SegmentFilter{AfterTimestamp: startTs})
 	if err != nil {
-		return fmt.Errorf("open input: %w", err)
+		return err
+	}
+	defer input.Close()
+	if input.Skipped > 0 {
+		a.logger.Info("skipping processed segments", zap.Int("skipped", input.Skipped), zap.Int("remaining", len(input.Files)))
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(input)
 	buf := make([]byte, 0, 64*1024)
 	scanner.Buffer(buf, 10*1024*1024)
 
-	batch := make([]model.PoolWindowMetrics, 0, a.cfg.BatchSize)
-	pools := make([]model.Pool, 0, 256)
-	maxTs := startTs
-	var total, decoded, skipped, failed int
-
+	stats := runStats{maxTs: startTs}
 	for scanner.Scan() {
 		line := bytes.TrimSpace(scanner.Bytes())
 		if len(line) == 0 {
 			continue
 		}
-		total++
+		stats.total++
 
 		var record model.TypedEventRecord
 		if err := json.Unmarshal(line, &record); err != nil {
-			failed++
+			stats.failed++
 			a.logger.Warn("decode typed event", zap.Error(err))
 			continue
 		}
-
-		if record.Timestamp <= startTs {
-			skipped++
-			continue
+		if err := a.consume(record, startTs, &stats); err != nil {
+			return err
 		}
 
-		windowStart := windowStart(record.Timestamp, a.cfg.WindowSeconds)
-		windowEnd := windowStart + a.cfg.WindowSeconds
-
-		accKey := poolKey(record.Address)
-		acc := a.accumulators[accKey]
-		if acc == nil {
-			acc = NewAccumulator(record, windowStart, windowEnd)
-			a.accumulators[accKey] = acc
-		} else if acc.WindowStart != windowStart {
-			metrics, pool, err := a.flushAccumulator(ctx, acc)
+		if len(a.closed) >= a.cfg.BatchSize {
+			flushed, err := a.flushClosed(ctx)
 			if err != nil {
 				return err
 			}
-			if metrics != nil {
-				batch = append(batch, *metrics)
-				decoded++
-			}
-			if pool != nil {
-				pools = append(pools, *pool)
-			}
-			acc = NewAccumulator(record, windowStart, windowEnd)
-			a.accumulators[accKey] = acc
-		}
-
-		if err := acc.AddEvent(record); err != nil {
-			failed++
-			a.logger.Warn("aggregate event", zap.Error(err), zap.String("pool", record.Address), zap.String("event", record.EventName))
-			continue
-		}
-
-		if record.Timestamp > maxTs {
-			maxTs = record.Timestamp
-		}
-
-		if len(batch) >= a.cfg.BatchSize {
-			if err := a.flushBatches(ctx, batch, pools); err != nil {
-				return err
-			}
-			batch = batch[:0]
-			pools = pools[:0]
+			stats.decoded += flushed
 
 			if err := a.saveState(ctx); err != nil {
 				return err
@@ -167,75 +153,216 @@ func (a *Aggregator) Run(ctx context.Context, inputPath string) error {
 	}
 
 	for _, acc := range a.accumulators {
-		metrics, pool, err := a.flushAccumulator(ctx, acc)
-		if err != nil {
-			return err
-		}
-		if metrics != nil {
-			batch = append(batch, *metrics)
-			decoded++
-		}
-		if pool != nil {
-			pools = append(pools, *pool)
-		}
+		a.closed = append(a.closed, acc)
 	}
 	a.accumulators = make(map[string]*Accumulator)
 
-	if len(batch) > 0 || len(pools) > 0 {
-		if err := a.flushBatches(ctx, batch, pools); err != nil {
-			return err
-		}
+	flushed, err := a.flushClosed(ctx)
+	if err != nil {
+		return err
 	}
+	stats.decoded += flushed
 
-	a.cfg.RecomputeFrom = maxTs
+	// Tombstones not read again are in segments no longer read.
+	for key, read := range a.tombstones {
+		if !read {
+			delete(a.tombstones, key)
+		}
+	}
+	a.cfg.RecomputeFrom = stats.maxTs
 	if err := a.saveState(ctx); err != nil {
 		return err
 	}
 
 	a.logger.Info("aggregate complete",
-		zap.Int("total", total),
-		zap.Int("decoded", decoded),
-		zap.Int("skipped", skipped),
-		zap.Int("failed", failed),
+		zap.Int("total", stats.total),
+		zap.Int("decoded", stats.decoded),
+		zap.Int("skipped", stats.skipped),
+		zap.Int("failed", stats.failed),
 	)
 
 	return nil
 }
 
-func (a *Aggregator) loadStartTimestamp(ctx context.Context) (uint64, error) {
+// runStats counts the records of a run.
+type runStats struct {
+	total, decoded, skipped, failed int
+	maxTs                           uint64
+}
+
+// consume adds a record to the window of its pool. Only errors that must stop
+// the run are returned; bad records are counted as failed.
+func (a *Aggregator) consume(record model.TypedEventRecord, startTs uint64, stats *runStats) error {
+	accKey := poolKey(record.Pool())
+	// Fee changes before the resume point still set the fee of later swaps.
+	if isFeeChange(record) {
+		if err := a.trackFee(accKey, record); err != nil {
+			stats.failed++
+			a.logger.Warn("track fee", zap.Error(err), zap.String("pool", record.Pool()))
+			return nil
+		}
+	}
+	if isFeeProtocolChange(record) {
+		if err := a.trackFeeProtocol(accKey, record); err != nil {
+			stats.failed++
+			a.logger.Warn("track fee protocol", zap.Error(err), zap.String("pool", record.Pool()))
+			return nil
+		}
+	}
+
+	windowStart := windowStart(record.Timestamp, a.cfg.WindowSeconds)
+	windowEnd := windowStart + a.cfg.WindowSeconds
+
+	if record.Timestamp <= startTs {
+		// A tombstone not netted out by an earlier run falls in a window that
+		// was already written.
+		if record.Removed && affectsMetrics(record) && !a.nettedTombstone(record) {
+			return fmt.Errorf("orphaned %s of pool %s at block %d falls in window %d, which was already written: rerun with --recompute-from %d", record.EventName, record.Pool(), record.BlockNumber, windowStart, windowStart)
+		}
+		stats.skipped++
+		return nil
+	}
+
+	acc := a.accumulators[accKey]
+	if record.Removed {
+		// Tombstones are netted out of the window they fall in while it is
+		// still held in memory. A window already written needs a recompute.
+		if acc == nil || acc.WindowStart != windowStart {
+			acc = a.closedWindow(accKey, windowStart)
+		}
+		if acc == nil {
+			if !affectsMetrics(record) {
+				stats.skipped++
+				return nil
+			}
+			return fmt.Errorf("orphaned %s of pool %s at block %d falls in window %d, which was already written: rerun with --recompute-from %d", record.EventName, record.Pool(), record.BlockNumber, windowStart, windowStart)
+		}
+	} else if acc == nil {
+		acc = a.newAccumulator(accKey, record, windowStart, windowEnd)
+	} else if acc.WindowStart != windowStart {
+		a.closed = append(a.closed, acc)
+		acc = a.newAccumulator(accKey, record, windowStart, windowEnd)
+	}
+
+	if err := acc.AddEvent(record); err != nil {
+		stats.failed++
+		a.logger.Warn("aggregate event", zap.Error(err), zap.String("pool", record.Pool()), zap.String("event", record.EventName))
+		return nil
+	}
+	if record.Removed {
+		a.tombstones[tombstoneKey(record)] = true
+	}
+
+	if record.Timestamp > stats.maxTs {
+		stats.maxTs = record.Timestamp
+	}
+	return nil
+}
+
+// loadState returns the timestamp to resume after and seeds the fee and
+// protocol tracks with the fees and fee protocols in force there.
+func (a *Aggregator) loadState(ctx context.Context) (uint64, error) {
+	var startTs uint64
 	if a.cfg.RecomputeFrom > 0 {
-		return a.cfg.RecomputeFrom - 1, nil
+		startTs = a.cfg.RecomputeFrom - 1
 	}
 	if a.cfg.StateStore == nil {
-		return 0, nil
+		return startTs, nil
 	}
-	last, ok, err := a.cfg.StateStore.Load(ctx)
+	state, ok, err := a.cfg.StateStore.Load(ctx)
 	if err != nil {
 		return 0, err
 	}
 	if !ok {
-		return 0, nil
+		return startTs, nil
+	}
+	if a.cfg.RecomputeFrom == 0 {
+		startTs = state.LastProcessed
 	}
-	return last, nil
+
+	for key, change := range state.Fees {
+		// A fee set after the resume point is read again from the input, and
+		// the one it replaced is not known.
+		if change.Timestamp > startTs {
+			a.logger.Warn("saved fee postdates the resume point", zap.String("pool", key), zap.Uint64("fee_ts", change.Timestamp), zap.Uint64("resume_ts", startTs))
+			continue
+		}
+		a.feeTrack(key).Set(change)
+	}
+	for key, protocol := range state.FeeProtocols {
+		if protocol.Timestamp > startTs {
+			a.logger.Warn("saved fee protocol postdates the resume point", zap.String("pool", key), zap.Uint64("fee_protocol_ts", protocol.Timestamp), zap.Uint64("resume_ts", startTs))
+			continue
+		}
+		a.protocolTrack(key).Set(protocol)
+	}
+	for _, key := range state.Tombstones {
+		a.tombstones[key] = false
+	}
+	return startTs, nil
 }
 
+// saveState records the progress up to the first window not yet written,
+// along with the fees and fee protocols in force there.
 func (a *Aggregator) saveState(ctx context.Context) error {
+	safeTs := a.safeTimestamp()
+	for _, track := range a.feeTracks {
+		track.Prune(safeTs)
+	}
+	for _, track := range a.protocols {
+		track.Prune(safeTs)
+	}
 	if a.cfg.StateStore == nil {
 		return nil
 	}
 
-	if len(a.accumulators) == 0 {
-		return a.cfg.StateStore.Save(ctx, a.cfg.RecomputeFrom)
+	state := State{LastProcessed: safeTs}
+	for key, track := range a.feeTracks {
+		change, ok := track.InForce(safeTs)
+		if !ok {
+			continue
+		}
+		if state.Fees == nil {
+			state.Fees = make(map[string]FeeChange)
+		}
+		state.Fees[key] = change
+	}
+	for key, track := range a.protocols {
+		protocol, ok := track.InForce(safeTs)
+		if !ok {
+			continue
+		}
+		if state.FeeProtocols == nil {
+			state.FeeProtocols = make(map[string]FeeProtocol)
+		}
+		state.FeeProtocols[key] = protocol
+	}
+	for key := range a.tombstones {
+		state.Tombstones = append(state.Tombstones, key)
+	}
+	sort.Strings(state.Tombstones)
+	return a.cfg.StateStore.Save(ctx, state)
+}
+
+// safeTimestamp returns the last timestamp whose windows are all written.
+func (a *Aggregator) safeTimestamp() uint64 {
+	if len(a.accumulators) == 0 && len(a.closed) == 0 {
+		return a.cfg.RecomputeFrom
 	}
 
 	safeTs := minOpenWindowStart(a.accumulators)
+	for _, acc := range a.closed {
+		if safeTs == 0 || acc.WindowStart < safeTs {
+			safeTs = acc.WindowStart
+		}
+	}
 	if safeTs > 0 {
 		safeTs = safeTs - 1
 	}
 	if safeTs == 0 {
 		safeTs = a.cfg.RecomputeFrom
 	}
-	return a.cfg.StateStore.Save(ctx, safeTs)
+	return safeTs
 }
 
 func (a *Aggregator) flushBatches(ctx context.Context, batch []model.PoolWindowMetrics, pools []model.Pool) error {
@@ -252,17 +379,50 @@ func (a *Aggregator) flushBatches(ctx context.Context, batch []model.PoolWindowM
 	return nil
 }
 
-func (a *Aggregator) flushAccumulator(ctx context.Context, acc *Accumulator) (*model.PoolWindowMetrics, *model.Pool, error) {
-	if acc == nil {
-		return nil, nil, nil
+// flushClosed turns every closed window into metrics and writes them. Token
+// decimals and pool balances for the whole set are read in bulk so a flush
+// costs a handful of RPC round-trips regardless of the number of pools.
+func (a *Aggregator) flushClosed(ctx context.Context) (int, error) {
+	if len(a.closed) == 0 {
+		return 0, nil
 	}
 
-	poolMeta := acc.PoolMeta
-	if poolMeta.Token0 == "" || poolMeta.Token1 == "" {
-		a.logger.Warn("missing pool meta", zap.String("pool", acc.PoolAddress))
-		return nil, nil, nil
+	ready := make([]*Accumulator, 0, len(a.closed))
+	tokens := make([]string, 0, 2*len(a.closed))
+	for _, acc := range a.closed {
+		if acc == nil {
+			continue
+		}
+		if acc.PoolMeta.Token0 == "" || acc.PoolMeta.Token1 == "" {
+			a.logger.Warn("missing pool meta", zap.String("pool", acc.Pool))
+			continue
+		}
+		ready = append(ready, acc)
+		tokens = append(tokens, acc.PoolMeta.Token0, acc.PoolMeta.Token1)
 	}
 
+	a.loadTokenDecimals(ctx, tokens)
+	tvls := a.fetchTVLs(ctx, ready)
+
+	batch := make([]model.PoolWindowMetrics, 0, len(ready))
+	pools := make([]model.Pool, 0, len(ready))
+	for i, acc := range ready {
+		metrics, pool := a.buildMetrics(ctx, acc, tvls[i])
+		batch = append(batch, *metrics)
+		if pool != nil {
+			pools = append(pools, *pool)
+		}
+	}
+
+	if err := a.flushBatches(ctx, batch, pools); err != nil {
+		return 0, err
+	}
+	a.closed = a.closed[:0]
+	return len(batch), nil
+}
+
+func (a *Aggregator) buildMetrics(ctx context.Context, acc *Accumulator, tvl tvlResult) (*model.PoolWindowMetrics, *model.Pool) {
+	poolMeta := acc.PoolMeta
 	poolRecord := a.registerPool(acc)
 
 	decimals0, err := a.getTokenDecimals(ctx, poolMeta.Token0)
@@ -278,38 +438,36 @@ func (a *Aggregator) flushAccumulator(ctx context.Context, acc *Accumulator) (*m
 	volume1 := formatTokenAmount(acc.Volume1, decimals1)
 	fee0 := formatTokenAmount(acc.Fee0, decimals0)
 	fee1 := formatTokenAmount(acc.Fee1, decimals1)
+	protocolFee0 := formatTokenAmount(acc.ProtocolFee0, decimals0)
+	protocolFee1 := formatTokenAmount(acc.ProtocolFee1, decimals1)
+	feeMethod := feeMethodApprox
+	if acc.UnknownFeeSwaps > 0 {
+		feeMethod = feeMethodFeeUnknown
+	} else if acc.FeeTrack != nil {
+		feeMethod = feeMethodFeeEvents
+	} else if acc.HasProtocolFees() {
+		feeMethod = feeMethodApproxNet
+	}
 
 	var tvl0Str, tvl1Str *string
-	var tvl0Int, tvl1Int *big.Int
-	var tvlMethod string
-	if acc.LastBlock > 0 {
-		balance0, balance1, method, err := a.fetchTVL(ctx, poolMeta.Token0, poolMeta.Token1, acc.PoolAddress, acc.LastBlock)
-		if err != nil {
-			a.logger.Warn("tvl fetch failed", zap.String("pool", acc.PoolAddress), zap.Error(err))
-			tvlMethod = tvlMethodNone
-		} else {
-			tvl0Int = balance0
-			tvl1Int = balance1
-			if balance0 != nil {
-				val := formatTokenAmount(balance0, decimals0)
-				tvl0Str = &val
-			}
-			if balance1 != nil {
-				val := formatTokenAmount(balance1, decimals1)
-				tvl1Str = &val
-			}
-			tvlMethod = method
-		}
-	} else {
-		tvlMethod = tvlMethodNone
+	if tvl.err != nil {
+		a.logger.Warn("tvl fetch failed", zap.String("pool", acc.Pool), zap.Error(tvl.err))
+	}
+	if tvl.balance0 != nil {
+		val := formatTokenAmount(tvl.balance0, decimals0)
+		tvl0Str = &val
+	}
+	if tvl.balance1 != nil {
+		val := formatTokenAmount(tvl.balance1, decimals1)
+		tvl1Str = &val
 	}
 
-	feeRate0, feeRate1 := computeFeeRates(acc.Fee0, acc.Fee1, tvl0Int, tvl1Int)
+	feeRate0, feeRate1 := computeFeeRates(acc.Fee0, acc.Fee1, tvl.balance0, tvl.balance1)
 	apr := computeAPR(feeRate0, feeRate1, a.cfg.WindowSeconds)
 
 	metrics := &model.PoolWindowMetrics{
 		ChainID:        acc.ChainID,
-		PoolAddress:    acc.PoolAddress,
+		PoolAddress:    acc.Pool,
 		WindowSizeSecs: int64(a.cfg.WindowSeconds),
 		WindowStart:    time.Unix(int64(acc.WindowStart), 0).UTC(),
 		WindowEnd:      time.Unix(int64(acc.WindowEnd), 0).UTC(),
@@ -318,6 +476,8 @@ func (a *Aggregator) flushAccumulator(ctx context.Context, acc *Accumulator) (*m
 		Volume1:        volume1,
 		Fee0:           fee0,
 		Fee1:           fee1,
+		ProtocolFee0:   protocolFee0,
+		ProtocolFee1:   protocolFee1,
 		FeeUSD:         nil,
 		FeeRate0:       feeRate0,
 		FeeRate1:       feeRate1,
@@ -325,22 +485,24 @@ func (a *Aggregator) flushAccumulator(ctx context.Context, acc *Accumulator) (*m
 		TVL1:           tvl1Str,
 		TVLUSD:         nil,
 		APR:            apr,
-		FeeMethod:      feeMethodApprox,
-		TVLMethod:      tvlMethod,
+		FeeMethod:      feeMethod,
+		TVLMethod:      tvl.method,
 	}
 
-	return metrics, poolRecord, nil
+	return metrics, poolRecord
 }
 
 func (a *Aggregator) registerPool(acc *Accumulator) *model.Pool {
-	key := poolKey(acc.PoolAddress)
+	key := poolKey(acc.Pool)
 	pool := model.Pool{
 		ChainID:        acc.ChainID,
-		Address:        acc.PoolAddress,
+		Address:        acc.Pool,
 		Token0:         acc.PoolMeta.Token0,
 		Token1:         acc.PoolMeta.Token1,
 		Fee:            acc.PoolMeta.Fee,
 		TickSpacing:    acc.PoolMeta.TickSpacing,
+		Protocol:       acc.PoolMeta.Protocol,
+		Hooks:          acc.PoolMeta.Hooks,
 		FirstSeenBlock: acc.FirstBlock,
 	}
 
@@ -371,12 +533,175 @@ func (a *Aggregator) getTokenDecimals(ctx context.Context, token string) (uint8,
 	return meta, nil
 }
 
+// loadTokenDecimals fetches decimals for all uncached tokens in one batch.
+// Failures are left uncached and retried per token by getTokenDecimals.
+func (a *Aggregator) loadTokenDecimals(ctx context.Context, tokens []string) {
+	seen := make(map[common.Address]struct{}, len(tokens))
+	missing := make([]common.Address, 0, len(tokens))
+	for _, token := range tokens {
+		if !common.IsHexAddress(token) {
+			continue
+		}
+		addr := common.HexToAddress(token)
+		if _, ok := seen[addr]; ok {
+			continue
+		}
+		seen[addr] = struct{}{}
+		if _, ok := a.decimals.Get(addr); !ok {
+			missing = append(missing, addr)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	metas, errs := dex.FetchTokenMetas(ctx, a.chainClient, missing, a.logger)
+	for i, addr := range missing {
+		if errs[i] != nil {
+			continue
+		}
+		a.decimals.Set(addr, metas[i].Decimals)
+	}
+}
+
+// newAccumulator opens the window of a pool. Dynamic-fee pools share their fee
+// track with the window, and V3 pools their fee protocol track. A pool seen
+// for the first time starts its fee protocol track from the fee protocol
+// decoded with it, since pools can be deployed with one and no event for it.
+func (a *Aggregator) newAccumulator(key string, record model.TypedEventRecord, windowStart, windowEnd uint64) *Accumulator {
+	acc := NewAccumulator(record, windowStart, windowEnd)
+	if record.PoolMeta.Protocol == model.ProtocolAlgebra {
+		acc.FeeTrack = a.feeTrack(key)
+	}
+	if record.PoolMeta.Protocol == "" {
+		track := a.protocolTrack(key)
+		meta := record.PoolMeta
+		if track.Len() == 0 && (meta.FeeProtocol0 != 0 || meta.FeeProtocol1 != 0) {
+			track.Set(FeeProtocol{Token0: meta.FeeProtocol0, Token1: meta.FeeProtocol1, Denominator: meta.FeeProtocolDenominator})
+		}
+		acc.ProtocolTrack = track
+	}
+	a.accumulators[key] = acc
+	return acc
+}
+
+func (a *Aggregator) feeTrack(key string) *FeeTrack {
+	track, ok := a.feeTracks[key]
+	if !ok {
+		track = &FeeTrack{}
+		a.feeTracks[key] = track
+	}
+	return track
+}
+
+// trackFee applies a fee-change event to the fee track of its pool.
+func (a *Aggregator) trackFee(key string, record model.TypedEventRecord) error {
+	var change model.AlgebraFeeEventData
+	if err := json.Unmarshal(record.Decoded, &change); err != nil {
+		return fmt.Errorf("decode fee: %w", err)
+	}
+	track := a.feeTrack(key)
+	if record.Removed {
+		track.Remove(record.BlockNumber, record.LogIndex)
+		return nil
+	}
+	track.Set(FeeChange{
+		Block:     record.BlockNumber,
+		LogIndex:  record.LogIndex,
+		Timestamp: record.Timestamp,
+		Fee:       change.Fee,
+		FeeOtz:    change.FeeOtz,
+	})
+	return nil
+}
+
+func (a *Aggregator) protocolTrack(key string) *ProtocolTrack {
+	track, ok := a.protocols[key]
+	if !ok {
+		track = &ProtocolTrack{}
+		a.protocols[key] = track
+	}
+	return track
+}
+
+// trackFeeProtocol applies a SetFeeProtocol event to the protocol track of its
+// pool.
+func (a *Aggregator) trackFeeProtocol(key string, record model.TypedEventRecord) error {
+	var change model.SetFeeProtocolEventData
+	if err := json.Unmarshal(record.Decoded, &change); err != nil {
+		return fmt.Errorf("decode fee protocol: %w", err)
+	}
+	track := a.protocolTrack(key)
+	if record.Removed {
+		track.Remove(record.BlockNumber, record.LogIndex)
+		return nil
+	}
+	track.Set(FeeProtocol{
+		Block:       record.BlockNumber,
+		LogIndex:    record.LogIndex,
+		Timestamp:   record.Timestamp,
+		Token0:      change.FeeProtocol0New,
+		Token1:      change.FeeProtocol1New,
+		Denominator: change.Denominator,
+	})
+	return nil
+}
+
+// closedWindow returns the closed but not yet written window of a pool.
+func (a *Aggregator) closedWindow(key string, windowStart uint64) *Accumulator {
+	for _, acc := range a.closed {
+		if acc != nil && acc.WindowStart == windowStart && poolKey(acc.Pool) == key {
+			return acc
+		}
+	}
+	return nil
+}
+
+// affectsMetrics reports whether an event contributes to window metrics, so
+// that losing it to a reorg changes a written window.
+func affectsMetrics(record model.TypedEventRecord) bool {
+	switch strings.ToLower(record.EventName) {
+	case "swap", "flash":
+		return true
+	case "sync":
+		return record.PoolMeta.Protocol == model.ProtocolV2
+	default:
+		return false
+	}
+}
+
+func isFeeChange(record model.TypedEventRecord) bool {
+	return record.PoolMeta.Protocol == model.ProtocolAlgebra && strings.EqualFold(record.EventName, "fee")
+}
+
+// isFeeProtocolChange reports whether a record sets the fee protocol of a V3
+// pool.
+func isFeeProtocolChange(record model.TypedEventRecord) bool {
+	return record.PoolMeta.Protocol == "" && strings.EqualFold(record.EventName, "setfeeprotocol")
+}
+
 func windowStart(ts uint64, windowSec uint64) uint64 {
 	return ts - (ts % windowSec)
 }
 
-func poolKey(address string) string {
-	return strings.ToLower(address)
+// poolKey normalizes a pool identity, an address or a V4 poolId, for map keys.
+// nettedTombstone reports whether an earlier run netted the tombstone out.
+func (a *Aggregator) nettedTombstone(record model.TypedEventRecord) bool {
+	key := tombstoneKey(record)
+	if _, ok := a.tombstones[key]; !ok {
+		return false
+	}
+	a.tombstones[key] = true
+	return true
+}
+
+// tombstoneKey identifies the orphaned log a tombstone removes.
+func tombstoneKey(record model.TypedEventRecord) string {
+	return fmt.Sprintf("%s:%d:%s:%d", poolKey(record.Pool()), record.BlockNumber, strings.ToLower(record.BlockHash), record.LogIndex)
+}
+
+func poolKey(pool string) string {
+	return strings.ToLower(pool)
 }
 
 func minOpenWindowStart(acc map[string]*Accumulator) uint64 {
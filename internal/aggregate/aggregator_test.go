@@ -0,0 +1,198 @@
+package aggregate
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"liquidityScope/internal/model"
+)
+
+func TestAggregatorResumesAcrossFeeChange(t *testing.T) {
+	ctx := context.Background()
+	store := &FileStateStore{Path: filepath.Join(t.TempDir(), "state.json")}
+	meta := model.PoolMeta{Protocol: model.ProtocolAlgebra, Fee: 500}
+	cfg := Config{WindowSeconds: 60, BatchSize: 100, StateStore: store}
+
+	// The first run sees the fee change and writes its window.
+	first := NewAggregator(cfg, nil, nil, nil)
+	startTs, err := first.loadState(ctx)
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	feeChange := testRecord(t, "Fee", meta, 10, 2, model.AlgebraFeeEventData{Fee: 3000})
+	stats := runStats{maxTs: startTs}
+	if err := first.consume(feeChange, startTs, &stats); err != nil {
+		t.Fatalf("consume fee: %v", err)
+	}
+	first.accumulators = make(map[string]*Accumulator)
+	first.cfg.RecomputeFrom = stats.maxTs
+	if err := first.saveState(ctx); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+
+	// The second run skips the input holding the fee change.
+	second := NewAggregator(cfg, nil, nil, nil)
+	startTs, err = second.loadState(ctx)
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if startTs != feeChange.Timestamp {
+		t.Fatalf("expected resume after %d, got %d", feeChange.Timestamp, startTs)
+	}
+	swap := testRecord(t, "Swap", meta, 200, 0, model.SwapEventData{Amount0: "1000000", Amount1: "-990000"})
+	stats = runStats{maxTs: startTs}
+	if err := second.consume(swap, startTs, &stats); err != nil {
+		t.Fatalf("consume swap: %v", err)
+	}
+
+	acc := second.accumulators[poolKey(testPool)]
+	if acc == nil {
+		t.Fatalf("expected an open window")
+	}
+	if acc.Fee0.String() != "3000" {
+		t.Fatalf("expected fee at the saved rate, got %s", acc.Fee0)
+	}
+}
+
+func TestFeeTrackPrune(t *testing.T) {
+	var track FeeTrack
+	track.Set(FeeChange{Block: 10, LogIndex: 1, Timestamp: 100, Fee: 500})
+	track.Set(FeeChange{Block: 20, LogIndex: 1, Timestamp: 200, Fee: 800})
+	track.Set(FeeChange{Block: 30, LogIndex: 1, Timestamp: 300, Fee: 1000})
+
+	track.Prune(250)
+	if _, ok := track.At(20, 1, true); ok {
+		t.Fatalf("expected changes before the one in force to be pruned")
+	}
+	if fee, ok := track.At(25, 0, true); !ok || fee != 800 {
+		t.Fatalf("expected fee in force kept, got %d %v", fee, ok)
+	}
+	if change, ok := track.InForce(350); !ok || change.Fee != 1000 {
+		t.Fatalf("expected latest change in force, got %+v %v", change, ok)
+	}
+}
+
+func TestAggregatorFlashUsesFeeProtocol(t *testing.T) {
+	agg := NewAggregator(Config{WindowSeconds: 60, BatchSize: 100}, nil, nil, nil)
+	meta := model.PoolMeta{Fee: 3000}
+	records := []model.TypedEventRecord{
+		testRecord(t, "Flash", meta, 10, 0, model.FlashEventData{Paid0: "1000", Paid1: "1000"}),
+		testRecord(t, "SetFeeProtocol", meta, 10, 1, model.SetFeeProtocolEventData{FeeProtocol0New: 4, FeeProtocol1New: 5}),
+		testRecord(t, "Flash", meta, 11, 0, model.FlashEventData{Paid0: "1000", Paid1: "1000"}),
+	}
+	stats := runStats{}
+	for _, record := range records {
+		if err := agg.consume(record, 0, &stats); err != nil {
+			t.Fatalf("consume %s: %v", record.EventName, err)
+		}
+	}
+
+	acc := agg.accumulators[poolKey(testPool)]
+	if acc == nil {
+		t.Fatalf("expected an open window")
+	}
+	if acc.ProtocolFee0.String() != "250" || acc.ProtocolFee1.String() != "200" {
+		t.Fatalf("expected protocol share of the second flash only, got %s/%s", acc.ProtocolFee0, acc.ProtocolFee1)
+	}
+	if acc.Fee0.String() != "1750" || acc.Fee1.String() != "1800" {
+		t.Fatalf("expected LP fees net of protocol share, got %s/%s", acc.Fee0, acc.Fee1)
+	}
+}
+
+func TestAggregatorLeavesUnknownAlgebraFeeOut(t *testing.T) {
+	agg := NewAggregator(Config{WindowSeconds: 60, BatchSize: 100}, nil, nil, nil)
+	// The fee before the first event could not be read at decode time.
+	meta := model.PoolMeta{Protocol: model.ProtocolAlgebra, DynamicFee: true}
+	swap := model.SwapEventData{Amount0: "1000000", Amount1: "-990000"}
+	records := []model.TypedEventRecord{
+		testRecord(t, "Swap", meta, 10, 0, swap),
+		testRecord(t, "Fee", meta, 10, 1, model.AlgebraFeeEventData{Fee: 2000}),
+		testRecord(t, "Swap", meta, 11, 0, swap),
+	}
+	stats := runStats{}
+	for _, record := range records {
+		if err := agg.consume(record, 0, &stats); err != nil {
+			t.Fatalf("consume %s: %v", record.EventName, err)
+		}
+	}
+
+	acc := agg.accumulators[poolKey(testPool)]
+	if acc == nil {
+		t.Fatalf("expected an open window")
+	}
+	if acc.SwapCount != 2 || acc.UnknownFeeSwaps != 1 {
+		t.Fatalf("expected one of two swaps with an unknown fee, got %d/%d", acc.UnknownFeeSwaps, acc.SwapCount)
+	}
+	if acc.Fee0.String() != "2000" {
+		t.Fatalf("expected only the second swap charged, got %s", acc.Fee0)
+	}
+}
+
+func TestAggregatorFlashUsesDeployedFeeProtocol(t *testing.T) {
+	agg := NewAggregator(Config{WindowSeconds: 60, BatchSize: 100}, nil, nil, nil)
+	// A PancakeSwap V3 pool deployed with a 32% fee protocol and no
+	// SetFeeProtocol event since.
+	meta := model.PoolMeta{Fee: 2500, FeeProtocol0: 3200, FeeProtocol1: 3200, FeeProtocolDenominator: 10000}
+	flash := testRecord(t, "Flash", meta, 10, 0, model.FlashEventData{Paid0: "1000", Paid1: "500"})
+	stats := runStats{}
+	if err := agg.consume(flash, 0, &stats); err != nil {
+		t.Fatalf("consume flash: %v", err)
+	}
+
+	acc := agg.accumulators[poolKey(testPool)]
+	if acc == nil {
+		t.Fatalf("expected an open window")
+	}
+	if acc.ProtocolFee0.String() != "320" || acc.ProtocolFee1.String() != "160" {
+		t.Fatalf("expected deployed fee protocol share, got %s/%s", acc.ProtocolFee0, acc.ProtocolFee1)
+	}
+	if acc.Fee0.String() != "680" || acc.Fee1.String() != "340" {
+		t.Fatalf("expected LP fees net of protocol share, got %s/%s", acc.Fee0, acc.Fee1)
+	}
+}
+
+func TestAggregatorRejectsTombstoneOfWrittenWindow(t *testing.T) {
+	ctx := context.Background()
+	store := &FileStateStore{Path: filepath.Join(t.TempDir(), "state.json")}
+	meta := model.PoolMeta{Fee: 3000}
+	cfg := Config{WindowSeconds: 60, BatchSize: 100, StateStore: store}
+	swap := model.SwapEventData{Amount0: "1000000", Amount1: "-990000"}
+	orphaned := testRecord(t, "Swap", meta, 10, 0, swap)
+	netted := orphaned
+	netted.Removed = true
+
+	// The first run nets the tombstone out of the open window.
+	first := NewAggregator(cfg, nil, nil, nil)
+	stats := runStats{}
+	for _, record := range []model.TypedEventRecord{orphaned, netted} {
+		if err := first.consume(record, 0, &stats); err != nil {
+			t.Fatalf("consume: %v", err)
+		}
+	}
+	first.accumulators = make(map[string]*Accumulator)
+	first.cfg.RecomputeFrom = stats.maxTs
+	if err := first.saveState(ctx); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+
+	// The second run reads the same input again, then a new tombstone.
+	second := NewAggregator(cfg, nil, nil, nil)
+	startTs, err := second.loadState(ctx)
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	stats = runStats{maxTs: startTs}
+	for _, record := range []model.TypedEventRecord{orphaned, netted} {
+		if err := second.consume(record, startTs, &stats); err != nil {
+			t.Fatalf("expected netted tombstone skipped, got %v", err)
+		}
+	}
+	late := testRecord(t, "Swap", meta, 10, 1, swap)
+	late.Removed = true
+	err = second.consume(late, startTs, &stats)
+	if err == nil || !strings.Contains(err.Error(), "--recompute-from 960") {
+		t.Fatalf("expected recompute error, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package aggregate
+
+import (
+	"math/big"
+	"sort"
+)
+
+// FeeProtocol is the protocol's cut of the fees of a V3 pool, set by a
+// SetFeeProtocol event at a log position.
+type FeeProtocol struct {
+	Block     uint64 `json:"block"`
+	LogIndex  uint64 `json:"log_index"`
+	Timestamp uint64 `json:"timestamp"`
+	Token0    uint32 `json:"token0"`
+	Token1    uint32 `json:"token1"`
+	// Denominator is set by PancakeSwap V3 pools, which take Token/Denominator
+	// of the fees. Uniswap V3 pools take 1/Token.
+	Denominator uint32 `json:"denominator,omitempty"`
+}
+
+// Share returns the protocol's part of fees paid in token0 and token1.
+func (p FeeProtocol) Share(paid0, paid1 *big.Int) (*big.Int, *big.Int) {
+	return p.share(paid0, p.Token0), p.share(paid1, p.Token1)
+}
+
+func (p FeeProtocol) share(paid *big.Int, protocol uint32) *big.Int {
+	if protocol == 0 {
+		return big.NewInt(0)
+	}
+	if p.Denominator == 0 {
+		return new(big.Int).Quo(paid, big.NewInt(int64(protocol)))
+	}
+	share := new(big.Int).Mul(paid, big.NewInt(int64(protocol)))
+	return share.Quo(share, big.NewInt(int64(p.Denominator)))
+}
+
+// ProtocolTrack is the fee protocol history of a V3 pool, fed by its
+// SetFeeProtocol events in log order.
+type ProtocolTrack struct {
+	points []FeeProtocol
+}
+
+// Len returns the number of changes held.
+func (t *ProtocolTrack) Len() int {
+	return len(t.points)
+}
+
+// search returns the index of the first point at or after the position.
+func (t *ProtocolTrack) search(block, logIndex uint64) int {
+	return sort.Search(len(t.points), func(i int) bool {
+		p := t.points[i]
+		return p.Block > block || (p.Block == block && p.LogIndex >= logIndex)
+	})
+}
+
+// Set records a fee protocol change at its log position.
+func (t *ProtocolTrack) Set(change FeeProtocol) {
+	i := t.search(change.Block, change.LogIndex)
+	if i < len(t.points) && t.points[i].Block == change.Block && t.points[i].LogIndex == change.LogIndex {
+		t.points[i] = change
+		return
+	}
+	t.points = append(t.points, FeeProtocol{})
+	copy(t.points[i+1:], t.points[i:])
+	t.points[i] = change
+}
+
+// Remove drops the change at a log position, e.g. when it was orphaned by a
+// reorg.
+func (t *ProtocolTrack) Remove(block, logIndex uint64) {
+	i := t.search(block, logIndex)
+	if i < len(t.points) && t.points[i].Block == block && t.points[i].LogIndex == logIndex {
+		t.points = append(t.points[:i], t.points[i+1:]...)
+	}
+}
+
+// At returns the fee protocol in force at a log position.
+func (t *ProtocolTrack) At(block, logIndex uint64) (FeeProtocol, bool) {
+	i := t.search(block, logIndex)
+	if i == 0 {
+		return FeeProtocol{}, false
+	}
+	return t.points[i-1], true
+}
+
+// InForce returns the last change at or before timestamp ts.
+func (t *ProtocolTrack) InForce(ts uint64) (FeeProtocol, bool) {
+	i := sort.Search(len(t.points), func(i int) bool { return t.points[i].Timestamp > ts })
+	if i == 0 {
+		return FeeProtocol{}, false
+	}
+	return t.points[i-1], true
+}
+
+// Prune drops the changes that no longer matter for events after timestamp
+// ts, keeping the one in force at ts.
+func (t *ProtocolTrack) Prune(ts uint64) {
+	i := sort.Search(len(t.points), func(i int) bool { return t.points[i].Timestamp > ts })
+	if i <= 1 {
+		return
+	}
+	t.points = append(t.points[:0], t.points[i-1:]...)
+}
@@ -0,0 +1,86 @@
+package aggregate
+
+import "sort"
+
+// FeeChange is a fee that takes effect at a log position. Pools with
+// directional fees charge Fee on zero-for-one swaps and FeeOtz on one-for-zero
+// swaps; other pools leave FeeOtz 0.
+type FeeChange struct {
+	Block     uint64 `json:"block"`
+	LogIndex  uint64 `json:"log_index"`
+	Timestamp uint64 `json:"timestamp"`
+	Fee       uint32 `json:"fee"`
+	FeeOtz    uint32 `json:"fee_otz,omitempty"`
+}
+
+// SwapFee returns the fee of a swap in the given direction.
+func (c FeeChange) SwapFee(zeroForOne bool) uint32 {
+	if !zeroForOne && c.FeeOtz != 0 {
+		return c.FeeOtz
+	}
+	return c.Fee
+}
+
+// FeeTrack is the fee history of a dynamic-fee pool, fed by its fee-change
+// events in log order.
+type FeeTrack struct {
+	points []FeeChange
+}
+
+// search returns the index of the first point at or after the position.
+func (t *FeeTrack) search(block, logIndex uint64) int {
+	return sort.Search(len(t.points), func(i int) bool {
+		p := t.points[i]
+		return p.Block > block || (p.Block == block && p.LogIndex >= logIndex)
+	})
+}
+
+// Set records a fee change at its log position.
+func (t *FeeTrack) Set(change FeeChange) {
+	i := t.search(change.Block, change.LogIndex)
+	if i < len(t.points) && t.points[i].Block == change.Block && t.points[i].LogIndex == change.LogIndex {
+		t.points[i] = change
+		return
+	}
+	t.points = append(t.points, FeeChange{})
+	copy(t.points[i+1:], t.points[i:])
+	t.points[i] = change
+}
+
+// Remove drops the fee change at a log position, e.g. when it was orphaned by
+// a reorg.
+func (t *FeeTrack) Remove(block, logIndex uint64) {
+	i := t.search(block, logIndex)
+	if i < len(t.points) && t.points[i].Block == block && t.points[i].LogIndex == logIndex {
+		t.points = append(t.points[:i], t.points[i+1:]...)
+	}
+}
+
+// At returns the fee in force for a swap in the given direction at a log
+// position: that of the last change logged before it.
+func (t *FeeTrack) At(block, logIndex uint64, zeroForOne bool) (uint32, bool) {
+	i := t.search(block, logIndex)
+	if i == 0 {
+		return 0, false
+	}
+	return t.points[i-1].SwapFee(zeroForOne), true
+}
+
+// InForce returns the last change at or before timestamp ts.
+func (t *FeeTrack) InForce(ts uint64) (FeeChange, bool) {
+	i := sort.Search(len(t.points), func(i int) bool { return t.points[i].Timestamp > ts })
+	if i == 0 {
+		return FeeChange{}, false
+	}
+	return t.points[i-1], true
+}
+
+// Prune drops the changes that no longer matter for events after timestamp
+// ts, keeping the one in force at ts.
+func (t *FeeTrack) Prune(ts uint64) {
+	i := sort.Search(len(t.points), func(i int) bool { return t.points[i].Timestamp > ts })
+	if i <= 1 {
+		return
+	}
+	t.points = append(t.points[:0], t.points[i-1:]...)
+}
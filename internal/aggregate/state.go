@@ -9,10 +9,26 @@ import (
 	"time"
 )
 
-// StateStore persists the last processed timestamp.
+// State is the aggregation progress saved between runs.
+type State struct {
+	// LastProcessed is the timestamp up to which windows are written.
+	LastProcessed uint64
+	// Fees holds the fee in force at LastProcessed for every dynamic-fee pool,
+	// keyed like the accumulators. A resumed run skips the input before
+	// LastProcessed, fee changes included.
+	Fees map[string]FeeChange
+	// FeeProtocols holds the fee protocol in force at LastProcessed for every
+	// V3 pool that set one, keyed the same way.
+	FeeProtocols map[string]FeeProtocol
+	// Tombstones holds the orphaned logs already netted out of their windows,
+	// so reading them again before LastProcessed is not taken for a reorg.
+	Tombstones []string
+}
+
+// StateStore persists the aggregation state.
 type StateStore interface {
-	Load(ctx context.Context) (uint64, bool, error)
-	Save(ctx context.Context, ts uint64) error
+	Load(ctx context.Context) (State, bool, error)
+	Save(ctx context.Context, state State) error
 }
 
 // FileStateStore stores state in a local JSON file.
@@ -21,30 +37,33 @@ type FileStateStore struct {
 }
 
 type stateRecord struct {
-	LastProcessed uint64 `json:"last_processed_ts"`
-	UpdatedAt     string `json:"updated_at"`
+	LastProcessed uint64                 `json:"last_processed_ts"`
+	Fees          map[string]FeeChange   `json:"fees,omitempty"`
+	FeeProtocols  map[string]FeeProtocol `json:"fee_protocols,omitempty"`
+	Tombstones    []string               `json:"tombstones,omitempty"`
+	UpdatedAt     string                 `json:"updated_at"`
 }
 
-func (s *FileStateStore) Load(ctx context.Context) (uint64, bool, error) {
+func (s *FileStateStore) Load(ctx context.Context) (State, bool, error) {
 	if s == nil || s.Path == "" {
-		return 0, false, nil
+		return State{}, false, nil
 	}
 	data, err := os.ReadFile(s.Path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return 0, false, nil
+			return State{}, false, nil
 		}
-		return 0, false, fmt.Errorf("read state: %w", err)
+		return State{}, false, fmt.Errorf("read state: %w", err)
 	}
 
 	var rec stateRecord
 	if err := json.Unmarshal(data, &rec); err != nil {
-		return 0, false, fmt.Errorf("parse state: %w", err)
+		return State{}, false, fmt.Errorf("parse state: %w", err)
 	}
-	return rec.LastProcessed, true, nil
+	return State{LastProcessed: rec.LastProcessed, Fees: rec.Fees, FeeProtocols: rec.FeeProtocols, Tombstones: rec.Tombstones}, true, nil
 }
 
-func (s *FileStateStore) Save(ctx context.Context, ts uint64) error {
+func (s *FileStateStore) Save(ctx context.Context, state State) error {
 	if s == nil || s.Path == "" {
 		return nil
 	}
@@ -56,7 +75,10 @@ func (s *FileStateStore) Save(ctx context.Context, ts uint64) error {
 	}
 
 	rec := stateRecord{
-		LastProcessed: ts,
+		LastProcessed: state.LastProcessed,
+		Fees:          state.Fees,
+		FeeProtocols:  state.FeeProtocols,
+		Tombstones:    state.Tombstones,
 		UpdatedAt:     time.Now().UTC().Format(time.RFC3339Nano),
 	}
 	data, err := json.Marshal(rec)
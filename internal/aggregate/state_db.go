@@ -2,6 +2,8 @@ package aggregate
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 
 	"liquidityScope/internal/storage/postgres"
 )
@@ -12,16 +14,45 @@ type DBStateStore struct {
 	Name  string
 }
 
-func (s *DBStateStore) Load(ctx context.Context) (uint64, bool, error) {
+// stateFees is the JSON stored in indexer_state.fees.
+type stateFees struct {
+	Fees         map[string]FeeChange   `json:"fees,omitempty"`
+	FeeProtocols map[string]FeeProtocol `json:"fee_protocols,omitempty"`
+	Tombstones   []string               `json:"tombstones,omitempty"`
+}
+
+func (s *DBStateStore) Load(ctx context.Context) (State, bool, error) {
 	if s == nil || s.Store == nil {
-		return 0, false, nil
+		return State{}, false, nil
+	}
+	ts, data, ok, err := s.Store.LoadState(ctx, s.Name)
+	if err != nil || !ok {
+		return State{}, ok, err
 	}
-	return s.Store.LoadState(ctx, s.Name)
+	state := State{LastProcessed: ts}
+	if len(data) > 0 {
+		var fees stateFees
+		if err := json.Unmarshal(data, &fees); err != nil {
+			return State{}, false, fmt.Errorf("parse state fees: %w", err)
+		}
+		state.Fees = fees.Fees
+		state.FeeProtocols = fees.FeeProtocols
+		state.Tombstones = fees.Tombstones
+	}
+	return state, true, nil
 }
 
-func (s *DBStateStore) Save(ctx context.Context, ts uint64) error {
+func (s *DBStateStore) Save(ctx context.Context, state State) error {
 	if s == nil || s.Store == nil {
 		return nil
 	}
-	return s.Store.SaveState(ctx, s.Name, ts)
+	var data []byte
+	if len(state.Fees) > 0 || len(state.FeeProtocols) > 0 || len(state.Tombstones) > 0 {
+		var err error
+		data, err = json.Marshal(stateFees{Fees: state.Fees, FeeProtocols: state.FeeProtocols, Tombstones: state.Tombstones})
+		if err != nil {
+			return fmt.Errorf("marshal state fees: %w", err)
+		}
+	}
+	return s.Store.SaveState(ctx, s.Name, state.LastProcessed, data)
 }
@@ -11,14 +11,15 @@ import (
 	"liquidityScope/internal/dex"
 )
 
-// TokenDecimalsCache caches token decimals by address.
+// TokenDecimalsCache caches token decimals by address. The V4 native currency
+// is always present.
 type TokenDecimalsCache struct {
 	mu   sync.RWMutex
 	data map[common.Address]uint8
 }
 
 func NewTokenDecimalsCache() *TokenDecimalsCache {
-	return &TokenDecimalsCache{data: make(map[common.Address]uint8)}
+	return &TokenDecimalsCache{data: map[common.Address]uint8{dex.NativeCurrency: dex.NativeDecimals}}
 }
 
 func (c *TokenDecimalsCache) Get(address common.Address) (uint8, bool) {
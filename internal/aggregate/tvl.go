@@ -7,11 +7,11 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 
 	"liquidityScope/internal/chain"
+	"liquidityScope/internal/model"
 )
 
 const erc20BalanceOfABIJSON = `[
@@ -19,9 +19,9 @@ const erc20BalanceOfABIJSON = `[
 ]`
 
 var (
-	balanceOfABI     abi.ABI
-	balanceOfOnce    sync.Once
-	balanceOfABIErr  error
+	balanceOfABI    abi.ABI
+	balanceOfOnce   sync.Once
+	balanceOfABIErr error
 )
 
 func getBalanceOfABI() (abi.ABI, error) {
@@ -31,49 +31,165 @@ func getBalanceOfABI() (abi.ABI, error) {
 	return balanceOfABI, balanceOfABIErr
 }
 
-func (a *Aggregator) fetchTVL(ctx context.Context, token0, token1, poolAddr string, blockNumber uint64) (*big.Int, *big.Int, string, error) {
-	if !common.IsHexAddress(token0) || !common.IsHexAddress(token1) || !common.IsHexAddress(poolAddr) {
-		return nil, nil, tvlMethodNone, fmt.Errorf("invalid address")
+// tvlResult holds the pool balances read for one closed window.
+type tvlResult struct {
+	balance0 *big.Int
+	balance1 *big.Int
+	method   string
+	err      error
+}
+
+// fetchTVLs reads token balances for every accumulator in one Multicall3
+// round-trip, grouping calls by each window's last block. Transient failures
+// of the round-trip are retried; pools whose historical read still fails are
+// read together at the latest block. V2 pairs that emitted a Sync in the
+// window use its reserves and are not read at all. V4 pools keep their tokens
+// in the PoolManager, so they have no balances of their own to read.
+func (a *Aggregator) fetchTVLs(ctx context.Context, accs []*Accumulator) []tvlResult {
+	results := make([]tvlResult, len(accs))
+	for i := range results {
+		results[i].method = tvlMethodNone
 	}
 
-	pool := common.HexToAddress(poolAddr)
-	blockPtr := new(big.Int).SetUint64(blockNumber)
+	balanceABI, err := getBalanceOfABI()
+	if err != nil {
+		for i := range results {
+			results[i].err = err
+		}
+		return results
+	}
 
-	bal0, err0 := balanceOf(ctx, a.chainClient, common.HexToAddress(token0), pool, blockPtr)
-	bal1, err1 := balanceOf(ctx, a.chainClient, common.HexToAddress(token1), pool, blockPtr)
-	if err0 == nil && err1 == nil {
-		return bal0, bal1, tvlMethodBlock, nil
+	type pending struct {
+		index  int
+		tokens [2]common.Address
+		pool   common.Address
 	}
 
-	bal0, err0 = balanceOf(ctx, a.chainClient, common.HexToAddress(token0), pool, nil)
-	bal1, err1 = balanceOf(ctx, a.chainClient, common.HexToAddress(token1), pool, nil)
-	if err0 == nil && err1 == nil {
-		return bal0, bal1, tvlMethodLatest, nil
+	var queued []pending
+	for i, acc := range accs {
+		if acc.Reserve0 != nil && acc.Reserve1 != nil {
+			results[i] = tvlResult{balance0: acc.Reserve0, balance1: acc.Reserve1, method: tvlMethodReserves}
+			continue
+		}
+		if acc.LastBlock == 0 || acc.PoolMeta.Protocol == model.ProtocolV4 {
+			continue
+		}
+		meta := acc.PoolMeta
+		if !common.IsHexAddress(meta.Token0) || !common.IsHexAddress(meta.Token1) || !common.IsHexAddress(acc.Pool) {
+			results[i].err = fmt.Errorf("invalid address")
+			continue
+		}
+		queued = append(queued, pending{
+			index:  i,
+			tokens: [2]common.Address{common.HexToAddress(meta.Token0), common.HexToAddress(meta.Token1)},
+			pool:   common.HexToAddress(acc.Pool),
+		})
+	}
+	if len(queued) == 0 {
+		return results
 	}
 
-	return nil, nil, tvlMethodNone, fmt.Errorf("balanceOf failed")
-}
+	balanceCalls := func(item pending) ([]chain.CallRequest, error) {
+		data, err := balanceABI.Pack("balanceOf", item.pool)
+		if err != nil {
+			return nil, fmt.Errorf("pack balanceOf: %w", err)
+		}
+		return []chain.CallRequest{{To: item.tokens[0], Data: data}, {To: item.tokens[1], Data: data}}, nil
+	}
 
-func balanceOf(ctx context.Context, chainClient *chain.Client, token common.Address, owner common.Address, blockNumber *big.Int) (*big.Int, error) {
-	if chainClient == nil {
-		return nil, fmt.Errorf("chain client is nil")
+	// Historical reads, one group per block.
+	groupIndex := make(map[uint64]int)
+	var groups []chain.MulticallGroup
+	var members [][]pending
+	for _, item := range queued {
+		calls, err := balanceCalls(item)
+		if err != nil {
+			results[item.index].err = err
+			continue
+		}
+		block := accs[item.index].LastBlock
+		g, ok := groupIndex[block]
+		if !ok {
+			g = len(groups)
+			groupIndex[block] = g
+			groups = append(groups, chain.MulticallGroup{Block: new(big.Int).SetUint64(block)})
+			members = append(members, nil)
+		}
+		groups[g].Calls = append(groups[g].Calls, calls...)
+		members[g] = append(members[g], item)
 	}
-	balanceABI, err := getBalanceOfABI()
-	if err != nil {
-		return nil, err
+
+	var retry []pending
+	var outputs [][]chain.CallResult
+	err = chain.Retry(ctx, chain.DefaultRetryPolicy, func(ctx context.Context) error {
+		var err error
+		outputs, err = a.chainClient.Aggregate3Groups(ctx, groups)
+		return err
+	})
+	for g := range groups {
+		for j, item := range members[g] {
+			if err != nil {
+				results[item.index].err = err
+				retry = append(retry, item)
+				continue
+			}
+			balance0, balance1, berr := unpackBalances(balanceABI, item.tokens, outputs[g][2*j:2*j+2])
+			if berr != nil {
+				results[item.index].err = berr
+				retry = append(retry, item)
+				continue
+			}
+			results[item.index] = tvlResult{balance0: balance0, balance1: balance1, method: tvlMethodBlock}
+		}
+	}
+	if len(retry) == 0 {
+		return results
 	}
 
-	data, err := balanceABI.Pack("balanceOf", owner)
-	if err != nil {
-		return nil, fmt.Errorf("pack balanceOf: %w", err)
+	// Fallback to latest state for pools the historical read could not serve.
+	latest := make([]chain.CallRequest, 0, 2*len(retry))
+	for _, item := range retry {
+		calls, _ := balanceCalls(item)
+		latest = append(latest, calls...)
+	}
+	var latestOutputs []chain.CallResult
+	err = chain.Retry(ctx, chain.DefaultRetryPolicy, func(ctx context.Context) error {
+		var err error
+		latestOutputs, err = a.chainClient.Aggregate3(ctx, latest, nil)
+		return err
+	})
+	for j, item := range retry {
+		if err != nil {
+			results[item.index].err = fmt.Errorf("balanceOf failed: %w", err)
+			continue
+		}
+		balance0, balance1, berr := unpackBalances(balanceABI, item.tokens, latestOutputs[2*j:2*j+2])
+		if berr != nil {
+			results[item.index].err = fmt.Errorf("balanceOf failed: %w", berr)
+			continue
+		}
+		results[item.index] = tvlResult{balance0: balance0, balance1: balance1, method: tvlMethodLatest}
 	}
 
-	msg := ethereum.CallMsg{To: &token, Data: data}
-	resp, err := chainClient.CallContract(ctx, msg, blockNumber)
-	if err != nil {
-		return nil, fmt.Errorf("call balanceOf: %w", err)
+	return results
+}
+
+func unpackBalances(balanceABI abi.ABI, tokens [2]common.Address, outputs []chain.CallResult) (*big.Int, *big.Int, error) {
+	balances := make([]*big.Int, 2)
+	for i, output := range outputs {
+		if output.Err != nil {
+			return nil, nil, fmt.Errorf("call balanceOf %s: %w", tokens[i].Hex(), output.Err)
+		}
+		bal, err := unpackBalance(balanceABI, output.Data)
+		if err != nil {
+			return nil, nil, err
+		}
+		balances[i] = bal
 	}
+	return balances[0], balances[1], nil
+}
 
+func unpackBalance(balanceABI abi.ABI, resp []byte) (*big.Int, error) {
 	values, err := balanceABI.Unpack("balanceOf", resp)
 	if err != nil {
 		return nil, fmt.Errorf("unpack balanceOf: %w", err)
@@ -0,0 +1,173 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxBatchSize caps the number of requests sent in one JSON-RPC batch.
+const maxBatchSize = 100
+
+// CallRequest is a single eth_call in a batch.
+type CallRequest struct {
+	To   common.Address
+	Data []byte
+}
+
+// CallResult holds the outcome of one batched eth_call.
+type CallResult struct {
+	Data []byte
+	Err  error
+}
+
+// BlockTimestamps returns timestamps for the given blocks. Cached blocks are
+// served from the timestamp cache and the rest are fetched with batched
+// eth_getBlockByNumber.
+func (c *Client) BlockTimestamps(ctx context.Context, numbers []uint64) (map[uint64]uint64, error) {
+	out := make(map[uint64]uint64, len(numbers))
+	missing := make([]uint64, 0, len(numbers))
+
+	for _, number := range numbers {
+		if _, ok := out[number]; ok {
+			continue
+		}
+		if ts, ok := c.timestamps.get(number); ok {
+			out[number] = ts
+			continue
+		}
+		out[number] = 0
+		missing = append(missing, number)
+	}
+
+	for start := 0; start < len(missing); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		chunk := missing[start:end]
+
+		refs := make([]*rpcBlockRef, len(chunk))
+		err := c.do(ctx, route{}, "eth_getBlockByNumber", len(chunk), func(ctx context.Context, ep *endpoint) error {
+			elems := make([]rpc.BatchElem, len(chunk))
+			for i, number := range chunk {
+				refs[i] = nil
+				elems[i] = rpc.BatchElem{
+					Method: "eth_getBlockByNumber",
+					Args:   []interface{}{hexutil.EncodeUint64(number), false},
+					Result: &refs[i],
+				}
+			}
+			if err := ep.rpc.BatchCallContext(ctx, elems); err != nil {
+				return err
+			}
+			for i, elem := range elems {
+				if elem.Error != nil {
+					return fmt.Errorf("block %d: %w", chunk[i], elem.Error)
+				}
+				if refs[i] == nil {
+					return fmt.Errorf("block %d: %w", chunk[i], ethereum.NotFound)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		fetched := make(map[uint64]uint64, len(chunk))
+		for i, number := range chunk {
+			ts := uint64(refs[i].Timestamp)
+			fetched[number] = ts
+			out[number] = ts
+		}
+		if err := c.timestamps.put(fetched); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// BatchCallContract performs many eth_calls at the same block using JSON-RPC
+// batches. Failures of individual calls are reported in the results; the
+// returned error is reserved for failures of the batch as a whole.
+func (c *Client) BatchCallContract(ctx context.Context, calls []CallRequest, blockNumber *big.Int) ([]CallResult, error) {
+	pinned := make([]blockCall, len(calls))
+	for i, call := range calls {
+		pinned[i] = blockCall{CallRequest: call, block: blockNumber}
+	}
+	return c.batchEthCall(ctx, pinned)
+}
+
+// blockCall is an eth_call pinned to a block.
+type blockCall struct {
+	CallRequest
+	block *big.Int
+}
+
+func (c *Client) batchEthCall(ctx context.Context, calls []blockCall) ([]CallResult, error) {
+	results := make([]CallResult, len(calls))
+
+	for start := 0; start < len(calls); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(calls) {
+			end = len(calls)
+		}
+		chunk := calls[start:end]
+
+		historical := false
+		for _, call := range chunk {
+			if c.isHistorical(call.block) {
+				historical = true
+				break
+			}
+		}
+
+		outputs := make([]hexutil.Bytes, len(chunk))
+		elems := make([]rpc.BatchElem, len(chunk))
+		err := c.do(ctx, route{archive: historical}, "eth_call", len(chunk), func(ctx context.Context, ep *endpoint) error {
+			for i, call := range chunk {
+				outputs[i] = nil
+				elems[i] = rpc.BatchElem{
+					Method: "eth_call",
+					Args: []interface{}{
+						map[string]interface{}{"to": call.To, "data": hexutil.Bytes(call.Data)},
+						toBlockNumArg(call.block),
+					},
+					Result: &outputs[i],
+				}
+			}
+			if err := ep.rpc.BatchCallContext(ctx, elems); err != nil {
+				return err
+			}
+			for _, elem := range elems {
+				if elem.Error != nil && isMissingState(elem.Error) {
+					return elem.Error
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range chunk {
+			results[start+i] = CallResult{Data: outputs[i], Err: elems[i].Error}
+		}
+	}
+
+	return results, nil
+}
+
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(number)
+}
@@ -2,72 +2,219 @@ package chain
 
 import (
 	"context"
+	"fmt"
 	"math/big"
-	"sync"
+	"net/http"
+	"sort"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
-// Client wraps go-ethereum RPC and provides helper methods.
+// BlockRef identifies a block and links it to its parent.
+type BlockRef struct {
+	Number     uint64
+	Hash       common.Hash
+	ParentHash common.Hash
+	Timestamp  uint64
+}
+
+type rpcBlockRef struct {
+	Number     hexutil.Uint64 `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	ParentHash common.Hash    `json:"parentHash"`
+	Timestamp  hexutil.Uint64 `json:"timestamp"`
+}
+
+// Client wraps a pool of go-ethereum RPC endpoints and provides helper
+// methods. Requests are routed to the healthiest eligible endpoint and fail
+// over to the next one when an endpoint breaks.
 type Client struct {
-	rpcClient *rpc.Client
-	ethClient *ethclient.Client
+	endpoints  []*endpoint
+	hasArchive bool
 
-	mu        sync.RWMutex
-	tsCache   map[uint64]uint64
+	timestamps *timestampCache
+	usage      *usageCounter
 }
 
-// NewClient creates a new chain client from the RPC URL.
-func NewClient(ctx context.Context, rpcURL string) (*Client, error) {
-	rpcClient, err := rpc.DialContext(ctx, rpcURL)
-	if err != nil {
-		return nil, err
+// route restricts which endpoints may serve a request.
+type route struct {
+	archive bool
+}
+
+// NewClient dials every endpoint and builds a pooled chain client.
+func NewClient(ctx context.Context, endpoints []Endpoint) (*Client, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("at least one rpc endpoint is required")
 	}
 
-	return &Client{
-		rpcClient: rpcClient,
-		ethClient: ethclient.NewClient(rpcClient),
-		tsCache:   make(map[uint64]uint64),
-	}, nil
+	c := &Client{
+		timestamps: newTimestampCache(defaultTimestampCacheSize),
+		usage:      newUsageCounter(),
+	}
+	for _, spec := range endpoints {
+		if spec.Weight <= 0 {
+			spec.Weight = 1
+		}
+		limiter := newTokenBucket(spec.RPS, spec.Burst)
+		httpClient := &http.Client{Transport: &throttleTransport{base: http.DefaultTransport, bucket: limiter}}
+		rpcClient, err := rpc.DialOptions(ctx, spec.URL, rpc.WithHTTPClient(httpClient))
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("dial %s: %w", spec.URL, err)
+		}
+		c.endpoints = append(c.endpoints, &endpoint{
+			Endpoint: spec,
+			rpc:      rpcClient,
+			eth:      ethclient.NewClient(rpcClient),
+			limiter:  limiter,
+		})
+		if spec.Archive {
+			c.hasArchive = true
+		}
+	}
+
+	return c, nil
+}
+
+// UseTimestampFile persists block timestamps to store, so they survive
+// restarts and are shared by every stage using the same file. The caller
+// keeps ownership of the store and closes it after the client.
+func (c *Client) UseTimestampFile(store *TimestampFile) {
+	c.timestamps.setStore(store)
+}
+
+// ForgetTimestamps drops the cached timestamps of blocks from..to, so blocks
+// replaced by a reorg are fetched again.
+func (c *Client) ForgetTimestamps(from, to uint64) error {
+	return c.timestamps.forget(from, to)
+}
+
+// Usage returns the requests sent so far, by method.
+func (c *Client) Usage() Usage {
+	return c.usage.snapshot()
 }
 
-// Close closes the underlying RPC client.
+// Close closes the underlying RPC clients.
 func (c *Client) Close() {
-	if c.rpcClient != nil {
-		c.rpcClient.Close()
+	for _, ep := range c.endpoints {
+		if ep.rpc != nil {
+			ep.rpc.Close()
+		}
 	}
 }
 
 // GetChainID returns the chain ID.
 func (c *Client) GetChainID(ctx context.Context) (*big.Int, error) {
-	return c.ethClient.ChainID(ctx)
+	var chainID *big.Int
+	err := c.do(ctx, route{}, "eth_chainId", 1, func(ctx context.Context, ep *endpoint) error {
+		var err error
+		chainID, err = ep.eth.ChainID(ctx)
+		return err
+	})
+	return chainID, err
 }
 
-// LatestBlockNumber returns the latest block number.
+// LatestBlockNumber returns the highest head reported by the pool. Every
+// available endpoint is asked so head lag can be tracked per endpoint.
 func (c *Client) LatestBlockNumber(ctx context.Context) (uint64, error) {
-	return c.ethClient.BlockNumber(ctx)
+	type headResult struct {
+		head uint64
+		err  error
+	}
+
+	now := time.Now()
+	results := make(chan headResult, len(c.endpoints))
+	asked := 0
+	for _, ep := range c.endpoints {
+		if ep.score(0, now) < 0 && len(c.endpoints) > 1 {
+			continue
+		}
+		asked++
+		go func(ep *endpoint) {
+			if err := ep.limiter.wait(ctx, 1); err != nil {
+				results <- headResult{err: err}
+				return
+			}
+			c.usage.add("eth_blockNumber", 1)
+			start := time.Now()
+			head, err := ep.eth.BlockNumber(ctx)
+			c.observe(ep, time.Since(start), err)
+			if err == nil {
+				ep.setHead(head)
+			}
+			results <- headResult{head: head, err: err}
+		}(ep)
+	}
+	if asked == 0 {
+		return c.firstLatestBlockNumber(ctx)
+	}
+
+	var latest uint64
+	var firstErr error
+	ok := false
+	for i := 0; i < asked; i++ {
+		res := <-results
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			continue
+		}
+		ok = true
+		if res.head > latest {
+			latest = res.head
+		}
+	}
+	if !ok {
+		return 0, firstErr
+	}
+	return latest, nil
+}
+
+func (c *Client) firstLatestBlockNumber(ctx context.Context) (uint64, error) {
+	var head uint64
+	err := c.do(ctx, route{}, "eth_blockNumber", 1, func(ctx context.Context, ep *endpoint) error {
+		var err error
+		head, err = ep.eth.BlockNumber(ctx)
+		if err == nil {
+			ep.setHead(head)
+		}
+		return err
+	})
+	return head, err
 }
 
 // BlockByNumber returns the block by number.
 func (c *Client) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
-	return c.ethClient.BlockByNumber(ctx, number)
+	var block *types.Block
+	err := c.do(ctx, route{}, "eth_getBlockByNumber", 1, func(ctx context.Context, ep *endpoint) error {
+		var err error
+		block, err = ep.eth.BlockByNumber(ctx, number)
+		return err
+	})
+	return block, err
 }
 
 // HeaderByNumber returns the block header by number.
 func (c *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
-	return c.ethClient.HeaderByNumber(ctx, number)
+	var header *types.Header
+	err := c.do(ctx, route{}, "eth_getBlockByNumber", 1, func(ctx context.Context, ep *endpoint) error {
+		var err error
+		header, err = ep.eth.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
 }
 
-// BlockTimestamp returns the block timestamp, using an in-memory cache.
+// BlockTimestamp returns the block timestamp, using the timestamp cache.
 func (c *Client) BlockTimestamp(ctx context.Context, number uint64) (uint64, error) {
-	c.mu.RLock()
-	ts, ok := c.tsCache[number]
-	c.mu.RUnlock()
-	if ok {
+	if ts, ok := c.timestamps.get(number); ok {
 		return ts, nil
 	}
 
@@ -76,15 +223,49 @@ func (c *Client) BlockTimestamp(ctx context.Context, number uint64) (uint64, err
 		return 0, err
 	}
 
-	ts = header.Time
-	c.mu.Lock()
-	c.tsCache[number] = ts
-	c.mu.Unlock()
+	ts := header.Time
+	if err := c.timestamps.put(map[uint64]uint64{number: ts}); err != nil {
+		return 0, err
+	}
 
 	return ts, nil
 }
 
+// BlockRefByNumber returns the hash, parent hash and timestamp of a block.
+// The hash is taken from the node response rather than recomputed locally, so
+// it stays correct for chains whose headers carry extra fields.
+func (c *Client) BlockRefByNumber(ctx context.Context, number uint64) (BlockRef, error) {
+	var raw *rpcBlockRef
+	err := c.do(ctx, route{}, "eth_getBlockByNumber", 1, func(ctx context.Context, ep *endpoint) error {
+		raw = nil
+		if err := ep.rpc.CallContext(ctx, &raw, "eth_getBlockByNumber", hexutil.EncodeUint64(number), false); err != nil {
+			return err
+		}
+		if raw == nil {
+			return ethereum.NotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return BlockRef{}, err
+	}
+
+	ref := BlockRef{
+		Number:     uint64(raw.Number),
+		Hash:       raw.Hash,
+		ParentHash: raw.ParentHash,
+		Timestamp:  uint64(raw.Timestamp),
+	}
+	if err := c.timestamps.put(map[uint64]uint64{ref.Number: ref.Timestamp}); err != nil {
+		return BlockRef{}, err
+	}
+
+	return ref, nil
+}
+
 // FilterLogs returns logs in the given range for addresses and topic0 filters.
+// With no addresses the query is sent without an address filter, matching
+// logs from every contract.
 func (c *Client) FilterLogs(
 	ctx context.Context,
 	fromBlock uint64,
@@ -92,6 +273,10 @@ func (c *Client) FilterLogs(
 	addresses []common.Address,
 	topic0 []common.Hash,
 ) ([]types.Log, error) {
+	if len(addresses) == 0 {
+		return c.filterLogsByTopic(ctx, fromBlock, toBlock, topic0)
+	}
+
 	query := ethereum.FilterQuery{
 		FromBlock: new(big.Int).SetUint64(fromBlock),
 		ToBlock:   new(big.Int).SetUint64(toBlock),
@@ -100,10 +285,139 @@ func (c *Client) FilterLogs(
 	if len(topic0) > 0 {
 		query.Topics = [][]common.Hash{topic0}
 	}
-	return c.ethClient.FilterLogs(ctx, query)
+
+	var logs []types.Log
+	err := c.do(ctx, route{}, "eth_getLogs", 1, func(ctx context.Context, ep *endpoint) error {
+		var err error
+		logs, err = ep.eth.FilterLogs(ctx, query)
+		return err
+	})
+	return logs, err
 }
 
-// CallContract performs an eth_call for a contract method.
+// filterLogsByTopic sends eth_getLogs without the address field, which
+// ethclient always includes (as null when empty) and some providers reject.
+func (c *Client) filterLogsByTopic(ctx context.Context, fromBlock, toBlock uint64, topic0 []common.Hash) ([]types.Log, error) {
+	arg := map[string]interface{}{
+		"fromBlock": hexutil.EncodeUint64(fromBlock),
+		"toBlock":   hexutil.EncodeUint64(toBlock),
+	}
+	if len(topic0) > 0 {
+		arg["topics"] = [][]common.Hash{topic0}
+	}
+
+	var logs []types.Log
+	err := c.do(ctx, route{}, "eth_getLogs", 1, func(ctx context.Context, ep *endpoint) error {
+		logs = nil
+		return ep.rpc.CallContext(ctx, &logs, "eth_getLogs", arg)
+	})
+	return logs, err
+}
+
+// CallContract performs an eth_call for a contract method. Calls against
+// historical state are only routed to archive-capable endpoints.
 func (c *Client) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
-	return c.ethClient.CallContract(ctx, msg, blockNumber)
+	var out []byte
+	err := c.do(ctx, route{archive: c.isHistorical(blockNumber)}, "eth_call", 1, func(ctx context.Context, ep *endpoint) error {
+		var err error
+		out, err = ep.eth.CallContract(ctx, msg, blockNumber)
+		return err
+	})
+	return out, err
+}
+
+// isHistorical reports whether a block is old enough that pruned nodes may
+// no longer hold its state.
+func (c *Client) isHistorical(blockNumber *big.Int) bool {
+	if blockNumber == nil || !blockNumber.IsUint64() {
+		return false
+	}
+	head := c.maxHead()
+	if head == 0 {
+		return true
+	}
+	number := blockNumber.Uint64()
+	return number < head && head-number > recentStateBlocks
+}
+
+func (c *Client) maxHead() uint64 {
+	var head uint64
+	for _, ep := range c.endpoints {
+		if h := ep.currentHead(); h > head {
+			head = h
+		}
+	}
+	return head
+}
+
+// candidates returns the endpoints eligible for a route, best first. When no
+// endpoint is tagged as archive, every endpoint is assumed to serve history.
+func (c *Client) candidates(r route) []*endpoint {
+	now := time.Now()
+	maxHead := c.maxHead()
+
+	type scored struct {
+		ep    *endpoint
+		score float64
+	}
+	list := make([]scored, 0, len(c.endpoints))
+	for _, ep := range c.endpoints {
+		if r.archive && c.hasArchive && !ep.Archive {
+			continue
+		}
+		list = append(list, scored{ep: ep, score: ep.score(maxHead, now)})
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].score > list[j].score })
+
+	out := make([]*endpoint, 0, len(list))
+	for _, item := range list {
+		out = append(out, item.ep)
+	}
+	return out
+}
+
+// do runs fn against the best endpoint for the route and fails over to the
+// next candidate when the endpoint itself is at fault or throttles. calls is
+// the number of method requests fn sends, counted against the endpoint rate
+// limit and the usage totals.
+func (c *Client) do(ctx context.Context, r route, method string, calls int, fn func(ctx context.Context, ep *endpoint) error) error {
+	candidates := c.candidates(r)
+	if len(candidates) == 0 {
+		return fmt.Errorf("no rpc endpoint available")
+	}
+
+	var lastErr error
+	for _, ep := range candidates {
+		if err := ep.limiter.wait(ctx, calls); err != nil {
+			return err
+		}
+		c.usage.add(method, calls)
+
+		start := time.Now()
+		err := fn(ctx, ep)
+		if !c.observe(ep, time.Since(start), err) {
+			return err
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return lastErr
+}
+
+// observe records the outcome of a request and reports whether another
+// endpoint should be tried. Throttled endpoints are paused rather than
+// counted as failing.
+func (c *Client) observe(ep *endpoint, latency time.Duration, err error) bool {
+	if isRateLimited(err) {
+		c.usage.throttle()
+		if !ep.limiter.paused(time.Now()) {
+			ep.limiter.pause(time.Now().Add(defaultThrottle))
+		}
+		return true
+	}
+	failed := isEndpointFailure(err)
+	ep.observe(latency, failed)
+	return failed
 }
@@ -0,0 +1,202 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// recentStateBlocks is how far behind head a non-archive node still serves state.
+	recentStateBlocks = 128
+	// maxFailures is the number of consecutive failures before an endpoint is benched.
+	maxFailures  = 3
+	baseCooldown = 15 * time.Second
+	maxCooldown  = 5 * time.Minute
+	// healthDecay is the EWMA weight given to each new latency/error sample.
+	healthDecay = 0.2
+)
+
+// Endpoint describes one RPC provider in the pool.
+type Endpoint struct {
+	URL     string
+	Weight  int
+	Archive bool
+	// RPS caps requests per second sent to the endpoint; 0 is unlimited.
+	RPS float64
+	// Burst is the number of requests that may be sent at once; 0 means RPS.
+	Burst int
+}
+
+// ParseEndpoint parses an endpoint spec of the form
+// `url[;weight=N][;archive][;rps=N][;burst=N]`, e.g.
+// `https://node.example;weight=3;archive;rps=25`.
+func ParseEndpoint(spec string) (Endpoint, error) {
+	parts := strings.Split(strings.TrimSpace(spec), ";")
+	ep := Endpoint{URL: strings.TrimSpace(parts[0]), Weight: 1}
+	if ep.URL == "" {
+		return Endpoint{}, fmt.Errorf("empty rpc url in %q", spec)
+	}
+
+	for _, opt := range parts[1:] {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(opt, "=")
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "archive":
+			ep.Archive = value == "" || strings.EqualFold(value, "true")
+		case "weight":
+			weight, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || weight <= 0 {
+				return Endpoint{}, fmt.Errorf("invalid weight in %q", spec)
+			}
+			ep.Weight = weight
+		case "rps":
+			rps, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || rps <= 0 {
+				return Endpoint{}, fmt.Errorf("invalid rps in %q", spec)
+			}
+			ep.RPS = rps
+		case "burst":
+			burst, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || burst <= 0 {
+				return Endpoint{}, fmt.Errorf("invalid burst in %q", spec)
+			}
+			ep.Burst = burst
+		default:
+			return Endpoint{}, fmt.Errorf("unknown endpoint option %q in %q", key, spec)
+		}
+	}
+	return ep, nil
+}
+
+// ParseEndpoints parses a list of endpoint specs.
+func ParseEndpoints(specs []string) ([]Endpoint, error) {
+	endpoints := make([]Endpoint, 0, len(specs))
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		ep, err := ParseEndpoint(spec)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints, nil
+}
+
+// endpoint is a dialed Endpoint with its health statistics.
+type endpoint struct {
+	Endpoint
+	rpc     *rpc.Client
+	eth     *ethclient.Client
+	limiter *tokenBucket
+
+	mu        sync.Mutex
+	latency   time.Duration
+	errorRate float64
+	head      uint64
+	failures  int
+	downUntil time.Time
+}
+
+// observe folds the outcome of a request into the endpoint health.
+func (e *endpoint) observe(latency time.Duration, failed bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	sample := 0.0
+	if failed {
+		sample = 1
+	}
+	e.errorRate = e.errorRate*(1-healthDecay) + sample*healthDecay
+
+	if failed {
+		e.failures++
+		if e.failures >= maxFailures {
+			cooldown := baseCooldown << (e.failures - maxFailures)
+			if cooldown > maxCooldown || cooldown <= 0 {
+				cooldown = maxCooldown
+			}
+			e.downUntil = time.Now().Add(cooldown)
+		}
+		return
+	}
+
+	e.failures = 0
+	e.downUntil = time.Time{}
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = time.Duration(float64(e.latency)*(1-healthDecay) + float64(latency)*healthDecay)
+	}
+}
+
+func (e *endpoint) setHead(head uint64) {
+	e.mu.Lock()
+	if head > e.head {
+		e.head = head
+	}
+	e.mu.Unlock()
+}
+
+func (e *endpoint) currentHead() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.head
+}
+
+// score ranks endpoints for routing; higher is better. Benched and
+// throttled endpoints score below zero and are only tried when nothing else
+// is left.
+func (e *endpoint) score(maxHead uint64, now time.Time) float64 {
+	if e.limiter != nil && e.limiter.paused(now) {
+		return -0.5
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if now.Before(e.downUntil) {
+		return -1
+	}
+
+	score := float64(e.Weight) * (1 - e.errorRate)
+	score /= 1 + e.latency.Seconds()*10
+	if maxHead > e.head && e.head > 0 {
+		score /= 1 + float64(maxHead-e.head)
+	}
+	return score
+}
+
+// isEndpointFailure reports whether err indicates a broken endpoint that
+// another provider might not share. JSON-RPC errors mean the node answered,
+// unless the answer is that it lacks the requested state.
+func isEndpointFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return isMissingState(err)
+	}
+	return true
+}
+
+func isMissingState(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "missing trie node") ||
+		strings.Contains(msg, "header not found") ||
+		strings.Contains(msg, "state not available") ||
+		strings.Contains(msg, "state is not available")
+}
@@ -0,0 +1,95 @@
+package chain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	ep, err := ParseEndpoint("https://node.example/key;weight=3;archive")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ep.URL != "https://node.example/key" || ep.Weight != 3 || !ep.Archive {
+		t.Fatalf("endpoint mismatch: %+v", ep)
+	}
+
+	ep, err = ParseEndpoint("https://plain.example")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ep.Weight != 1 || ep.Archive {
+		t.Fatalf("defaults mismatch: %+v", ep)
+	}
+
+	ep, err = ParseEndpoint("https://paid.example;rps=12.5;burst=20")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ep.RPS != 12.5 || ep.Burst != 20 {
+		t.Fatalf("rate limit mismatch: %+v", ep)
+	}
+
+	for _, spec := range []string{"", "https://x;weight=0", "https://x;weight=abc", "https://x;fast", "https://x;rps=0", "https://x;burst=-1"} {
+		if _, err := ParseEndpoint(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestEndpointScore(t *testing.T) {
+	now := time.Now()
+	fast := &endpoint{Endpoint: Endpoint{Weight: 1}, latency: 50 * time.Millisecond, head: 100}
+	slow := &endpoint{Endpoint: Endpoint{Weight: 1}, latency: 800 * time.Millisecond, head: 100}
+	lagging := &endpoint{Endpoint: Endpoint{Weight: 1}, latency: 50 * time.Millisecond, head: 80}
+	heavy := &endpoint{Endpoint: Endpoint{Weight: 5}, latency: 800 * time.Millisecond, head: 100}
+
+	if fast.score(100, now) <= slow.score(100, now) {
+		t.Fatalf("lower latency should score higher")
+	}
+	if fast.score(100, now) <= lagging.score(100, now) {
+		t.Fatalf("head lag should lower the score")
+	}
+	if heavy.score(100, now) <= slow.score(100, now) {
+		t.Fatalf("weight should raise the score")
+	}
+
+	for i := 0; i < maxFailures; i++ {
+		fast.observe(0, true)
+	}
+	if fast.score(100, now) >= 0 {
+		t.Fatalf("endpoint should be benched after %d failures", maxFailures)
+	}
+	fast.observe(10*time.Millisecond, false)
+	if fast.score(100, time.Now()) < 0 {
+		t.Fatalf("endpoint should recover after a success")
+	}
+}
+
+func TestIsEndpointFailure(t *testing.T) {
+	if isEndpointFailure(nil) {
+		t.Fatalf("nil is not a failure")
+	}
+	if !isEndpointFailure(errors.New("dial tcp: connection refused")) {
+		t.Fatalf("transport errors should fail over")
+	}
+	if !isEndpointFailure(rpc.HTTPError{StatusCode: 502, Status: "502 Bad Gateway"}) {
+		t.Fatalf("http errors should fail over")
+	}
+	if isEndpointFailure(&testRPCError{msg: "execution reverted"}) {
+		t.Fatalf("reverts should not fail over")
+	}
+	if !isEndpointFailure(&testRPCError{msg: "missing trie node abc"}) {
+		t.Fatalf("missing state should fail over")
+	}
+}
+
+type testRPCError struct {
+	msg string
+}
+
+func (e *testRPCError) Error() string  { return e.msg }
+func (e *testRPCError) ErrorCode() int { return -32000 }
@@ -0,0 +1,142 @@
+package chain
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrorClass groups RPC failures by how they should be handled.
+type ErrorClass int
+
+const (
+	// ErrorTransient covers network failures, timeouts and node hiccups that
+	// are likely to succeed on retry.
+	ErrorTransient ErrorClass = iota
+	// ErrorRateLimited means the provider throttled the request.
+	ErrorRateLimited
+	// ErrorProviderLimit means the query was too large for the provider and
+	// has to be split rather than retried.
+	ErrorProviderLimit
+	// ErrorReverted means the contract call executed and reverted.
+	ErrorReverted
+	// ErrorInvalidInput means the request itself is malformed.
+	ErrorInvalidInput
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorTransient:
+		return "transient"
+	case ErrorRateLimited:
+		return "rate_limited"
+	case ErrorProviderLimit:
+		return "provider_limit"
+	case ErrorReverted:
+		return "execution_reverted"
+	case ErrorInvalidInput:
+		return "invalid_input"
+	default:
+		return "unknown"
+	}
+}
+
+// Retryable reports whether retrying the same request may succeed.
+func (c ErrorClass) Retryable() bool {
+	return c == ErrorTransient || c == ErrorRateLimited
+}
+
+// providerLimitMessages are substrings RPC providers use when an eth_getLogs
+// query covers too many blocks or returns too many results.
+var providerLimitMessages = []string{
+	"query returned more than",
+	"block range too large",
+	"block range is too large",
+	"exceed maximum block range",
+	"exceeds the range allowed",
+	"range too large",
+	"response size exceeded",
+	"too many results",
+}
+
+// invalidInputMessages are substrings nodes use for malformed requests.
+var invalidInputMessages = []string{
+	"invalid argument",
+	"invalid params",
+	"invalid address",
+	"hex string without 0x prefix",
+	"cannot unmarshal",
+	"method not found",
+	"does not exist/is not available",
+}
+
+// JSON-RPC error codes for malformed requests.
+var invalidInputCodes = map[int]bool{
+	-32700: true, // parse error
+	-32600: true, // invalid request
+	-32601: true, // method not found
+	-32602: true, // invalid params
+}
+
+// classifiedError pins the class of an error produced outside the RPC layer.
+type classifiedError struct {
+	class ErrorClass
+	err   error
+}
+
+func (e *classifiedError) Error() string { return e.err.Error() }
+
+func (e *classifiedError) Unwrap() error { return e.err }
+
+// WithClass tags err with a class, overriding classification by message.
+func WithClass(err error, class ErrorClass) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{class: class, err: err}
+}
+
+// Classify returns the class of err. Unknown errors are treated as transient.
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ErrorTransient
+	}
+
+	var tagged *classifiedError
+	if errors.As(err, &tagged) {
+		return tagged.class
+	}
+	if errors.Is(err, ErrCallFailed) {
+		return ErrorReverted
+	}
+	if isRateLimited(err) {
+		return ErrorRateLimited
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, candidate := range providerLimitMessages {
+		if strings.Contains(msg, candidate) {
+			return ErrorProviderLimit
+		}
+	}
+	if strings.Contains(msg, "execution reverted") {
+		return ErrorReverted
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && invalidInputCodes[rpcErr.ErrorCode()] {
+		return ErrorInvalidInput
+	}
+	for _, candidate := range invalidInputMessages {
+		if strings.Contains(msg, candidate) {
+			return ErrorInvalidInput
+		}
+	}
+	return ErrorTransient
+}
+
+// IsProviderLimit reports whether err means the query was too large for the provider.
+func IsProviderLimit(err error) bool {
+	return err != nil && Classify(err) == ErrorProviderLimit
+}
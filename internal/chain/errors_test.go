@@ -0,0 +1,44 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{errors.New("query returned more than 10000 results"), ErrorProviderLimit},
+		{errors.New("Block range too large"), ErrorProviderLimit},
+		{errors.New("exceed maximum block range: 5000"), ErrorProviderLimit},
+		{errors.New("connection reset by peer"), ErrorTransient},
+		{rpc.HTTPError{StatusCode: 502, Status: "502 Bad Gateway"}, ErrorTransient},
+		{rpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, ErrorRateLimited},
+		{&testRPCError{msg: "execution reverted"}, ErrorReverted},
+		{fmt.Errorf("call decimals: %w", ErrCallFailed), ErrorReverted},
+		{errors.New("invalid argument 0: hex string without 0x prefix"), ErrorInvalidInput},
+		{&codeRPCError{msg: "bad request", code: -32602}, ErrorInvalidInput},
+		{WithClass(errors.New("invalid address"), ErrorTransient), ErrorTransient},
+	}
+	for _, tc := range cases {
+		if got := Classify(tc.err); got != tc.want {
+			t.Fatalf("Classify(%q) = %s, want %s", tc.err, got, tc.want)
+		}
+	}
+	if IsProviderLimit(nil) {
+		t.Fatalf("nil error should not be a provider limit")
+	}
+}
+
+type codeRPCError struct {
+	msg  string
+	code int
+}
+
+func (e *codeRPCError) Error() string  { return e.msg }
+func (e *codeRPCError) ErrorCode() int { return e.code }
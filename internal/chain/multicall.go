@@ -0,0 +1,209 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Multicall3Address is the canonical Multicall3 deployment shared by BSC and
+// most EVM chains.
+var Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// multicallChunkSize caps the number of calls packed into one aggregate3.
+const multicallChunkSize = 200
+
+// ErrCallFailed is reported for calls that reverted inside aggregate3.
+var ErrCallFailed = errors.New("execution reverted")
+
+const multicall3ABIJSON = `[
+  {
+    "inputs": [
+      {
+        "components": [
+          {"internalType": "address", "name": "target", "type": "address"},
+          {"internalType": "bool", "name": "allowFailure", "type": "bool"},
+          {"internalType": "bytes", "name": "callData", "type": "bytes"}
+        ],
+        "internalType": "struct Multicall3.Call3[]",
+        "name": "calls",
+        "type": "tuple[]"
+      }
+    ],
+    "name": "aggregate3",
+    "outputs": [
+      {
+        "components": [
+          {"internalType": "bool", "name": "success", "type": "bool"},
+          {"internalType": "bytes", "name": "returnData", "type": "bytes"}
+        ],
+        "internalType": "struct Multicall3.Result[]",
+        "name": "returnData",
+        "type": "tuple[]"
+      }
+    ],
+    "stateMutability": "payable",
+    "type": "function"
+  }
+]`
+
+var (
+	multicall3ABI     abi.ABI
+	multicall3ABIOnce sync.Once
+	multicall3ABIErr  error
+)
+
+func getMulticall3ABI() (abi.ABI, error) {
+	multicall3ABIOnce.Do(func() {
+		multicall3ABI, multicall3ABIErr = abi.JSON(strings.NewReader(multicall3ABIJSON))
+	})
+	return multicall3ABI, multicall3ABIErr
+}
+
+type multicall3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type multicall3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// MulticallGroup is a set of calls evaluated at the same block.
+type MulticallGroup struct {
+	Block *big.Int
+	Calls []CallRequest
+}
+
+// Aggregate3 executes calls through Multicall3 at the given block. Every call
+// may fail on its own without affecting the others.
+func (c *Client) Aggregate3(ctx context.Context, calls []CallRequest, blockNumber *big.Int) ([]CallResult, error) {
+	results, err := c.Aggregate3Groups(ctx, []MulticallGroup{{Block: blockNumber, Calls: calls}})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// Aggregate3Groups executes several call groups, each pinned to its own
+// block, sending every aggregate3 in one JSON-RPC batch. Chunks for which
+// aggregate3 itself fails, e.g. before Multicall3 was deployed, or returns a
+// result count that does not match its calls, fall back to plain batched
+// eth_calls.
+func (c *Client) Aggregate3Groups(ctx context.Context, groups []MulticallGroup) ([][]CallResult, error) {
+	parsed, err := getMulticall3ABI()
+	if err != nil {
+		return nil, fmt.Errorf("parse multicall3 abi: %w", err)
+	}
+
+	type chunkRef struct {
+		group int
+		start int
+		calls []CallRequest
+	}
+
+	var chunks []chunkRef
+	var requests []blockCall
+	results := make([][]CallResult, len(groups))
+	for g, group := range groups {
+		results[g] = make([]CallResult, len(group.Calls))
+		for start := 0; start < len(group.Calls); start += multicallChunkSize {
+			end := start + multicallChunkSize
+			if end > len(group.Calls) {
+				end = len(group.Calls)
+			}
+			chunk := group.Calls[start:end]
+
+			packed := make([]multicall3Call, len(chunk))
+			for i, call := range chunk {
+				packed[i] = multicall3Call{Target: call.To, AllowFailure: true, CallData: call.Data}
+			}
+			data, err := parsed.Pack("aggregate3", packed)
+			if err != nil {
+				return nil, fmt.Errorf("pack aggregate3: %w", err)
+			}
+
+			chunks = append(chunks, chunkRef{group: g, start: start, calls: chunk})
+			requests = append(requests, blockCall{
+				CallRequest: CallRequest{To: Multicall3Address, Data: data},
+				block:       group.Block,
+			})
+		}
+	}
+	if len(requests) == 0 {
+		return results, nil
+	}
+
+	outputs, err := c.batchEthCall(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	var fallback []blockCall
+	var fallbackTargets []*CallResult
+	for i, chunk := range chunks {
+		decoded, err := unpackAggregate3(parsed, outputs[i], len(chunk.calls))
+		if err != nil {
+			for j, call := range chunk.calls {
+				fallback = append(fallback, blockCall{CallRequest: call, block: requests[i].block})
+				fallbackTargets = append(fallbackTargets, &results[chunk.group][chunk.start+j])
+			}
+			continue
+		}
+		for j, res := range decoded {
+			target := &results[chunk.group][chunk.start+j]
+			if res.Success {
+				target.Data = res.ReturnData
+			} else {
+				target.Err = ErrCallFailed
+			}
+		}
+	}
+
+	if len(fallback) > 0 {
+		direct, err := c.batchEthCall(ctx, fallback)
+		if err != nil {
+			return nil, err
+		}
+		for i, res := range direct {
+			*fallbackTargets[i] = res
+		}
+	}
+
+	return results, nil
+}
+
+// unpackAggregate3 decodes the results of an aggregate3 of expected calls.
+func unpackAggregate3(parsed abi.ABI, output CallResult, expected int) ([]multicall3Result, error) {
+	if output.Err != nil {
+		return nil, output.Err
+	}
+	if len(output.Data) == 0 {
+		return nil, fmt.Errorf("aggregate3 returned no data")
+	}
+
+	values, err := parsed.Unpack("aggregate3", output.Data)
+	if err != nil {
+		return nil, fmt.Errorf("unpack aggregate3: %w", err)
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("aggregate3 return size %d", len(values))
+	}
+
+	var decoded []multicall3Result
+	if err := parsed.Methods["aggregate3"].Outputs.Copy(&decoded, values); err != nil {
+		return nil, fmt.Errorf("copy aggregate3: %w", err)
+	}
+	if len(decoded) != expected {
+		return nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(decoded), expected)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,42 @@
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestUnpackAggregate3(t *testing.T) {
+	parsed, err := getMulticall3ABI()
+	if err != nil {
+		t.Fatalf("parse abi: %v", err)
+	}
+
+	want := []multicall3Result{
+		{Success: true, ReturnData: []byte{0x01, 0x02}},
+		{Success: false, ReturnData: nil},
+	}
+	data, err := parsed.Methods["aggregate3"].Outputs.Pack(want)
+	if err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+
+	got, err := unpackAggregate3(parsed, CallResult{Data: data}, len(want))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || !got[0].Success || !bytes.Equal(got[0].ReturnData, want[0].ReturnData) || got[1].Success {
+		t.Fatalf("results mismatch: %+v", got)
+	}
+
+	if _, err := unpackAggregate3(parsed, CallResult{Data: data}, len(want)+1); err == nil {
+		t.Fatalf("expected error for a short result list")
+	}
+	if _, err := unpackAggregate3(parsed, CallResult{}, 1); err == nil {
+		t.Fatalf("expected error for empty output")
+	}
+	callErr := errors.New("execution reverted")
+	if _, err := unpackAggregate3(parsed, CallResult{Err: callErr}, 1); !errors.Is(err, callErr) {
+		t.Fatalf("expected call error, got %v", err)
+	}
+}
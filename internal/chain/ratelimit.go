@@ -0,0 +1,220 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// defaultThrottle is how long an endpoint is paused after a rate-limit
+// response that carries no usable Retry-After.
+const defaultThrottle = time.Second
+
+// tokenBucket limits the request rate of one endpoint. A zero rate disables
+// limiting but still honours pauses requested by the provider.
+type tokenBucket struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = int(rate)
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait blocks until n requests may be sent. Batches larger than the burst
+// are let through once a token is available and leave the bucket in debt,
+// which keeps the long-run rate while never blocking forever.
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	for {
+		delay := b.reserve(n, time.Now())
+		if delay <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes n tokens and returns zero, or returns how long to wait
+// before trying again.
+func (b *tokenBucket) reserve(n int, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Before(b.pausedUntil) {
+		return b.pausedUntil.Sub(now)
+	}
+	if b.rate <= 0 {
+		return 0
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	}
+	b.tokens -= float64(n)
+	return 0
+}
+
+// pause stops all requests until the given time.
+func (b *tokenBucket) pause(until time.Time) {
+	b.mu.Lock()
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+	b.mu.Unlock()
+}
+
+func (b *tokenBucket) paused(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.Before(b.pausedUntil)
+}
+
+// throttleTransport pauses the endpoint bucket when the provider answers
+// with 429 or 503, for as long as its Retry-After header asks.
+type throttleTransport struct {
+	base   http.RoundTripper
+	bucket *tokenBucket
+}
+
+func (t *throttleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		now := time.Now()
+		delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+		if !ok && resp.StatusCode == http.StatusTooManyRequests {
+			delay, ok = defaultThrottle, true
+		}
+		if ok {
+			t.bucket.pause(now.Add(delay))
+		}
+	}
+	return resp, nil
+}
+
+// parseRetryAfter reads a Retry-After value given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if !at.After(now) {
+			return 0, true
+		}
+		return at.Sub(now), true
+	}
+	return 0, false
+}
+
+// isRateLimited reports whether err is a provider throttling response.
+func isRateLimited(err error) bool {
+	if err == nil {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "too many requests") ||
+		strings.Contains(msg, "rate limit") ||
+		strings.Contains(msg, "rate-limit")
+}
+
+// Usage summarizes the requests sent by a Client.
+type Usage struct {
+	// Calls counts requests by JSON-RPC method, including retries and
+	// every element of a batch.
+	Calls map[string]uint64
+	// Throttled counts responses rejected by provider rate limits.
+	Throttled uint64
+}
+
+// Total returns the number of requests across all methods.
+func (u Usage) Total() uint64 {
+	var total uint64
+	for _, n := range u.Calls {
+		total += n
+	}
+	return total
+}
+
+// Methods returns the called methods in name order.
+func (u Usage) Methods() []string {
+	methods := make([]string, 0, len(u.Calls))
+	for method := range u.Calls {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+type usageCounter struct {
+	mu        sync.Mutex
+	calls     map[string]uint64
+	throttled uint64
+}
+
+func newUsageCounter() *usageCounter {
+	return &usageCounter{calls: make(map[string]uint64)}
+}
+
+func (u *usageCounter) add(method string, n int) {
+	u.mu.Lock()
+	u.calls[method] += uint64(n)
+	u.mu.Unlock()
+}
+
+func (u *usageCounter) throttle() {
+	u.mu.Lock()
+	u.throttled++
+	u.mu.Unlock()
+}
+
+func (u *usageCounter) snapshot() Usage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	calls := make(map[string]uint64, len(u.calls))
+	for method, n := range u.calls {
+		calls[method] = n
+	}
+	return Usage{Calls: calls, Throttled: u.throttled}
+}
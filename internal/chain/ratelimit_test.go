@@ -0,0 +1,73 @@
+package chain
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestTokenBucketReserve(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(10, 2)
+	bucket.last = now
+
+	if delay := bucket.reserve(1, now); delay != 0 {
+		t.Fatalf("expected first token immediately, got %v", delay)
+	}
+	if delay := bucket.reserve(1, now); delay != 0 {
+		t.Fatalf("expected burst token immediately, got %v", delay)
+	}
+	if delay := bucket.reserve(1, now); delay <= 0 {
+		t.Fatalf("expected to wait once the burst is spent")
+	}
+	if delay := bucket.reserve(1, now.Add(100*time.Millisecond)); delay != 0 {
+		t.Fatalf("expected a token after refill, got %v", delay)
+	}
+
+	bucket.pause(now.Add(time.Second))
+	if delay := bucket.reserve(1, now.Add(200*time.Millisecond)); delay != 800*time.Millisecond {
+		t.Fatalf("expected pause to hold requests, got %v", delay)
+	}
+
+	unlimited := newTokenBucket(0, 0)
+	for i := 0; i < 1000; i++ {
+		if delay := unlimited.reserve(100, now); delay != 0 {
+			t.Fatalf("unlimited bucket should not wait, got %v", delay)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if delay, ok := parseRetryAfter("3", now); !ok || delay != 3*time.Second {
+		t.Fatalf("seconds: delay=%v ok=%v", delay, ok)
+	}
+	date := now.Add(5 * time.Second).Format(http.TimeFormat)
+	if delay, ok := parseRetryAfter(date, now); !ok || delay != 5*time.Second {
+		t.Fatalf("http date: delay=%v ok=%v", delay, ok)
+	}
+	for _, value := range []string{"", "soon", "-1"} {
+		if _, ok := parseRetryAfter(value, now); ok {
+			t.Fatalf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestIsRateLimited(t *testing.T) {
+	if !isRateLimited(rpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}) {
+		t.Fatalf("http 429 should be rate limited")
+	}
+	if !isRateLimited(&testRPCError{msg: "rate limit exceeded, retry later"}) {
+		t.Fatalf("rate limit message should be rate limited")
+	}
+	if isRateLimited(rpc.HTTPError{StatusCode: 502, Status: "502 Bad Gateway"}) {
+		t.Fatalf("502 is not a rate limit")
+	}
+	if isRateLimited(errors.New("query returned more than 10000 results")) {
+		t.Fatalf("result limits are not rate limits")
+	}
+}
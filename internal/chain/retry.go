@@ -0,0 +1,68 @@
+package chain
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how Retry repeats a failing request.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+}
+
+// DefaultRetryPolicy is used by callers without their own retry settings.
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 3, BaseDelay: 500 * time.Millisecond}
+
+// Retry runs fn until it succeeds or fails with an error whose class is not
+// retryable. Delays grow exponentially with jitter so concurrent workers do
+// not retry against a throttled provider in step; rate-limited attempts wait
+// at least the default throttle pause.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(context.Context) error) error {
+	maxRetries := policy.MaxRetries
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	delay := policy.BaseDelay
+	if delay <= 0 {
+		delay = 100 * time.Millisecond
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		class := Classify(err)
+		if !class.Retryable() || attempt >= maxRetries {
+			return err
+		}
+
+		wait := jitter(delay)
+		if class == ErrorRateLimited && wait < defaultThrottle {
+			wait = defaultThrottle
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		delay *= 2
+	}
+}
+
+// jitter returns a random duration in [d/2, d).
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half)
+}
@@ -0,0 +1,37 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryStopsOnPermanentClass(t *testing.T) {
+	calls := 0
+	limitErr := errors.New("query returned more than 10000 results")
+	err := Retry(context.Background(), RetryPolicy{MaxRetries: 5, BaseDelay: time.Millisecond}, func(context.Context) error {
+		calls++
+		return limitErr
+	})
+	if !errors.Is(err, limitErr) {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestRetryRetriesTransient(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond}, func(context.Context) error {
+		calls++
+		return errors.New("connection reset by peer")
+	})
+	if err == nil {
+		t.Fatalf("expected error after retries")
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+}
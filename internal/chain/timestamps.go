@@ -0,0 +1,211 @@
+package chain
+
+import (
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// defaultTimestampCacheSize bounds the in-memory timestamp LRU.
+const defaultTimestampCacheSize = 100_000
+
+// timestampSlot is the size of one block entry in a TimestampFile.
+const timestampSlot = 8
+
+// TimestampFile is a persistent block->timestamp store. Each block owns a
+// fixed 8-byte slot at offset number*8, so the file is sparse and lookups are
+// a single positioned read; a zero slot means the block is unknown.
+type TimestampFile struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenTimestampFile opens or creates a timestamp store at path.
+func OpenTimestampFile(path string) (*TimestampFile, error) {
+	dir := filepath.Dir(path)
+	if dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create timestamp dir: %w", err)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open timestamp file: %w", err)
+	}
+	return &TimestampFile{file: file}, nil
+}
+
+// Get returns the stored timestamp of a block.
+func (f *TimestampFile) Get(number uint64) (uint64, bool, error) {
+	var buf [timestampSlot]byte
+	f.mu.Lock()
+	_, err := f.file.ReadAt(buf[:], int64(number*timestampSlot))
+	f.mu.Unlock()
+	if errors.Is(err, io.EOF) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("read timestamp %d: %w", number, err)
+	}
+	ts := binary.BigEndian.Uint64(buf[:])
+	return ts, ts != 0, nil
+}
+
+// Put stores timestamps for several blocks.
+func (f *TimestampFile) Put(timestamps map[uint64]uint64) error {
+	var buf [timestampSlot]byte
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for number, ts := range timestamps {
+		binary.BigEndian.PutUint64(buf[:], ts)
+		if _, err := f.file.WriteAt(buf[:], int64(number*timestampSlot)); err != nil {
+			return fmt.Errorf("write timestamp %d: %w", number, err)
+		}
+	}
+	return nil
+}
+
+// Forget clears the stored timestamps of blocks from..to, e.g. after they
+// were reorged out.
+func (f *TimestampFile) Forget(from, to uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, err := f.file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat timestamp file: %w", err)
+	}
+	end := uint64(info.Size()) / timestampSlot
+	if from >= end {
+		return nil
+	}
+	if to >= end {
+		to = end - 1
+	}
+	zero := make([]byte, (to-from+1)*timestampSlot)
+	if _, err := f.file.WriteAt(zero, int64(from*timestampSlot)); err != nil {
+		return fmt.Errorf("clear timestamps %d-%d: %w", from, to, err)
+	}
+	return nil
+}
+
+// Close syncs and closes the file.
+func (f *TimestampFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.file.Sync(); err != nil {
+		f.file.Close()
+		return fmt.Errorf("sync timestamp file: %w", err)
+	}
+	return f.file.Close()
+}
+
+// timestampCache is a bounded LRU of block timestamps backed by an optional
+// persistent store.
+type timestampCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[uint64]*list.Element
+	store    *TimestampFile
+}
+
+type timestampEntry struct {
+	number uint64
+	ts     uint64
+}
+
+func newTimestampCache(capacity int) *timestampCache {
+	if capacity <= 0 {
+		capacity = defaultTimestampCacheSize
+	}
+	return &timestampCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[uint64]*list.Element),
+	}
+}
+
+func (c *timestampCache) setStore(store *TimestampFile) {
+	c.mu.Lock()
+	c.store = store
+	c.mu.Unlock()
+}
+
+// get looks the block up in memory first and then in the store. Store read
+// errors are treated as misses so the block is simply fetched again.
+func (c *timestampCache) get(number uint64) (uint64, bool) {
+	c.mu.Lock()
+	if elem, ok := c.entries[number]; ok {
+		c.order.MoveToFront(elem)
+		ts := elem.Value.(*timestampEntry).ts
+		c.mu.Unlock()
+		return ts, true
+	}
+	store := c.store
+	c.mu.Unlock()
+
+	if store == nil {
+		return 0, false
+	}
+	ts, ok, err := store.Get(number)
+	if err != nil || !ok {
+		return 0, false
+	}
+	c.mu.Lock()
+	c.add(number, ts)
+	c.mu.Unlock()
+	return ts, true
+}
+
+// put records fetched timestamps in memory and writes them through to the store.
+func (c *timestampCache) put(timestamps map[uint64]uint64) error {
+	c.mu.Lock()
+	for number, ts := range timestamps {
+		c.add(number, ts)
+	}
+	store := c.store
+	c.mu.Unlock()
+
+	if store == nil {
+		return nil
+	}
+	return store.Put(timestamps)
+}
+
+// forget drops the timestamps of blocks from..to from memory and the store.
+func (c *timestampCache) forget(from, to uint64) error {
+	c.mu.Lock()
+	for number, elem := range c.entries {
+		if number < from || number > to {
+			continue
+		}
+		c.order.Remove(elem)
+		delete(c.entries, number)
+	}
+	store := c.store
+	c.mu.Unlock()
+
+	if store == nil {
+		return nil
+	}
+	return store.Forget(from, to)
+}
+
+func (c *timestampCache) add(number, ts uint64) {
+	if elem, ok := c.entries[number]; ok {
+		elem.Value.(*timestampEntry).ts = ts
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[number] = c.order.PushFront(&timestampEntry{number: number, ts: ts})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*timestampEntry).number)
+	}
+}
@@ -0,0 +1,94 @@
+package chain
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTimestampFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "times.bin")
+	store, err := OpenTimestampFile(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := store.Put(map[uint64]uint64{10: 1000, 12: 1006}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	store, err = OpenTimestampFile(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+
+	if ts, ok, err := store.Get(12); err != nil || !ok || ts != 1006 {
+		t.Fatalf("get 12: ts=%d ok=%v err=%v", ts, ok, err)
+	}
+	if _, ok, err := store.Get(11); err != nil || ok {
+		t.Fatalf("expected hole at 11, ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := store.Get(1_000_000); err != nil || ok {
+		t.Fatalf("expected miss past end, ok=%v err=%v", ok, err)
+	}
+}
+
+func TestTimestampCacheEvictsAndFallsBack(t *testing.T) {
+	store, err := OpenTimestampFile(filepath.Join(t.TempDir(), "times.bin"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer store.Close()
+
+	cache := newTimestampCache(2)
+	cache.setStore(store)
+	if err := cache.put(map[uint64]uint64{1: 100, 2: 103, 3: 106}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if cache.order.Len() != 2 {
+		t.Fatalf("expected 2 entries in memory, got %d", cache.order.Len())
+	}
+	for number, want := range map[uint64]uint64{1: 100, 2: 103, 3: 106} {
+		if ts, ok := cache.get(number); !ok || ts != want {
+			t.Fatalf("block %d: ts=%d ok=%v", number, ts, ok)
+		}
+	}
+
+	memory := newTimestampCache(2)
+	for number := uint64(1); number <= 3; number++ {
+		memory.put(map[uint64]uint64{number: 100 + 3*number})
+	}
+	if _, ok := memory.get(1); ok {
+		t.Fatalf("expected block 1 to be evicted")
+	}
+}
+
+func TestTimestampCacheForget(t *testing.T) {
+	store, err := OpenTimestampFile(filepath.Join(t.TempDir(), "times.bin"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer store.Close()
+
+	cache := newTimestampCache(10)
+	cache.setStore(store)
+	if err := cache.put(map[uint64]uint64{1: 100, 2: 103, 3: 106}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := cache.forget(2, 10); err != nil {
+		t.Fatalf("forget: %v", err)
+	}
+	if ts, ok := cache.get(1); !ok || ts != 100 {
+		t.Fatalf("expected block 1 kept, ts=%d ok=%v", ts, ok)
+	}
+	for _, number := range []uint64{2, 3} {
+		if _, ok := cache.get(number); ok {
+			t.Fatalf("expected block %d forgotten", number)
+		}
+	}
+	if err := cache.forget(50, 60); err != nil {
+		t.Fatalf("forget past end: %v", err)
+	}
+}
@@ -12,7 +12,7 @@ import (
 
 // AggregateConfig holds configuration for aggregation.
 type AggregateConfig struct {
-	RPCURL        string
+	RPC           []string
 	Input         string
 	Window        string
 	PGDSN         string
@@ -55,7 +55,7 @@ func LoadAggregate(cfgFile string, flags *pflag.FlagSet) (AggregateConfig, error
 	}
 
 	cfg := AggregateConfig{
-		RPCURL:        v.GetString("rpc"),
+		RPC:           getStringSlice(v, "rpc"),
 		Input:         v.GetString("in"),
 		Window:        v.GetString("window"),
 		PGDSN:         v.GetString("pg-dsn"),
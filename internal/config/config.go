@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -11,17 +12,36 @@ import (
 
 // Config holds configuration values loaded from flags, env, or config file.
 type Config struct {
-	RPCURL            string
+	RPC               []string
 	FromBlock         uint64
 	ToBlock           uint64
 	Addresses         []string
 	Topic0            []string
+	Factories         []string
+	Registry          string
+	RegistrySet       bool
+	TopicOnly         bool
+	Quarantine        string
 	BatchSize         uint64
 	Out               string
+	Sink              string
+	PGDSN             string
 	Checkpoint        string
 	CheckpointEnabled bool
 	MaxRetries        int
 	RetryBackoff      time.Duration
+	Follow            bool
+	Confirmations     uint64
+	PollInterval      time.Duration
+	ReorgWindow       uint64
+	Concurrency       int
+	AddressChunk      int
+	BlockTimes        string
+	Format            string
+	RowGroupBlocks    uint64
+	Partition         string
+	SegmentBlocks     uint64
+	SegmentRecords    int
 	LogLevel          string
 }
 
@@ -33,11 +53,31 @@ func Load(cfgFile string, flags *pflag.FlagSet) (Config, error) {
 	v.AutomaticEnv()
 
 	v.SetDefault("batch-size", uint64(2000))
+	v.SetDefault("factory", []string{
+		"0x0BFbCF9fa4f9C56B0F40a671Ad40E0805A091865",
+		"0xdB1d10011AD0Ff90774D0C6Bb92e5C5c8b4461F7",
+	})
+	v.SetDefault("registry", "./data/pools.json")
+	v.SetDefault("topic-only", false)
+	v.SetDefault("quarantine", "./data/quarantine.jsonl")
 	v.SetDefault("out", "./data/logs.jsonl")
+	v.SetDefault("sink", "file")
 	v.SetDefault("checkpoint", "./data/checkpoint.json")
 	v.SetDefault("checkpoint-enabled", true)
 	v.SetDefault("max-retries", 5)
 	v.SetDefault("retry-backoff", 500*time.Millisecond)
+	v.SetDefault("follow", false)
+	v.SetDefault("confirmations", uint64(15))
+	v.SetDefault("poll-interval", 3*time.Second)
+	v.SetDefault("reorg-window", uint64(64))
+	v.SetDefault("concurrency", 4)
+	v.SetDefault("address-chunk", 100)
+	v.SetDefault("block-times", "./data/block_times.bin")
+	v.SetDefault("format", "jsonl")
+	v.SetDefault("row-group-blocks", uint64(10000))
+	v.SetDefault("partition", "")
+	v.SetDefault("segment-blocks", uint64(100000))
+	v.SetDefault("segment-records", 1000000)
 	v.SetDefault("log-level", "info")
 
 	if flags != nil {
@@ -62,23 +102,56 @@ func Load(cfgFile string, flags *pflag.FlagSet) (Config, error) {
 	}
 
 	cfg := Config{
-		RPCURL:            v.GetString("rpc"),
+		RPC:               getStringSlice(v, "rpc"),
 		FromBlock:         v.GetUint64("from"),
 		ToBlock:           v.GetUint64("to"),
 		Addresses:         getStringSlice(v, "address"),
 		Topic0:            getStringSlice(v, "topic0"),
+		Factories:         getStringSlice(v, "factory"),
+		Registry:          v.GetString("registry"),
+		RegistrySet:       explicitlySet(v, flags, "registry"),
+		TopicOnly:         v.GetBool("topic-only"),
+		Quarantine:        v.GetString("quarantine"),
 		BatchSize:         v.GetUint64("batch-size"),
 		Out:               v.GetString("out"),
+		Sink:              v.GetString("sink"),
+		PGDSN:             v.GetString("pg-dsn"),
 		Checkpoint:        v.GetString("checkpoint"),
 		CheckpointEnabled: v.GetBool("checkpoint-enabled"),
 		MaxRetries:        v.GetInt("max-retries"),
 		RetryBackoff:      v.GetDuration("retry-backoff"),
+		Follow:            v.GetBool("follow"),
+		Confirmations:     v.GetUint64("confirmations"),
+		PollInterval:      v.GetDuration("poll-interval"),
+		ReorgWindow:       v.GetUint64("reorg-window"),
+		Concurrency:       v.GetInt("concurrency"),
+		AddressChunk:      v.GetInt("address-chunk"),
+		BlockTimes:        v.GetString("block-times"),
+		Format:            v.GetString("format"),
+		RowGroupBlocks:    v.GetUint64("row-group-blocks"),
+		Partition:         v.GetString("partition"),
+		SegmentBlocks:     v.GetUint64("segment-blocks"),
+		SegmentRecords:    v.GetInt("segment-records"),
 		LogLevel:          v.GetString("log-level"),
 	}
 
 	return cfg, nil
 }
 
+// explicitlySet reports whether key was given by flag, environment or config
+// file. viper.IsSet also counts defaults, so it cannot tell.
+func explicitlySet(v *viper.Viper, flags *pflag.FlagSet, key string) bool {
+	if flags != nil {
+		if flag := flags.Lookup(key); flag != nil && flag.Changed {
+			return true
+		}
+	}
+	if _, ok := os.LookupEnv("INDEXER_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))); ok {
+		return true
+	}
+	return v.InConfig(key)
+}
+
 func getStringSlice(v *viper.Viper, key string) []string {
 	if !v.IsSet(key) {
 		return nil
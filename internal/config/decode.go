@@ -10,13 +10,25 @@ import (
 
 // DecodeConfig holds configuration for the decode command.
 type DecodeConfig struct {
-	RPCURL          string
+	RPC             []string
 	In              string
+	Source          string
+	PGDSN           string
+	FromBlock       uint64
+	ToBlock         uint64
 	Out             string
 	Errors          string
 	LogLevel        string
 	Topic0Map       map[string]string
 	IncludeLiveMeta bool
+	Registry        string
+	V4Registry      string
+	V4PoolManagers  []string
+	Format          string
+	RowGroupBlocks  uint64
+	Partition       string
+	SegmentBlocks   uint64
+	SegmentRecords  int
 }
 
 // LoadDecode merges config file, environment variables, and flags into DecodeConfig.
@@ -26,9 +38,18 @@ func LoadDecode(cfgFile string, flags *pflag.FlagSet) (DecodeConfig, error) {
 	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	v.AutomaticEnv()
 
+	v.SetDefault("source", "file")
 	v.SetDefault("out", "./data/typed_events.jsonl")
 	v.SetDefault("errors", "./data/decode_errors.jsonl")
 	v.SetDefault("include-live-meta", false)
+	v.SetDefault("registry", "./data/pools.json")
+	v.SetDefault("v4-registry", "./data/v4_pools.json")
+	v.SetDefault("v4-pool-manager", []string{"0x28e2Ea090877bF75740558f6BFB36A5ffeE9e9dF"})
+	v.SetDefault("format", "jsonl")
+	v.SetDefault("row-group-blocks", uint64(10000))
+	v.SetDefault("partition", "")
+	v.SetDefault("segment-blocks", uint64(100000))
+	v.SetDefault("segment-records", 1000000)
 	v.SetDefault("log-level", "info")
 
 	if flags != nil {
@@ -53,13 +74,25 @@ func LoadDecode(cfgFile string, flags *pflag.FlagSet) (DecodeConfig, error) {
 	}
 
 	cfg := DecodeConfig{
-		RPCURL:          v.GetString("rpc"),
+		RPC:             getStringSlice(v, "rpc"),
 		In:              v.GetString("in"),
+		Source:          v.GetString("source"),
+		PGDSN:           v.GetString("pg-dsn"),
+		FromBlock:       v.GetUint64("from"),
+		ToBlock:         v.GetUint64("to"),
 		Out:             v.GetString("out"),
 		Errors:          v.GetString("errors"),
 		LogLevel:        v.GetString("log-level"),
 		Topic0Map:       getStringMap(v, "topic0-map"),
 		IncludeLiveMeta: v.GetBool("include-live-meta"),
+		Registry:        v.GetString("registry"),
+		V4Registry:      v.GetString("v4-registry"),
+		V4PoolManagers:  getStringSlice(v, "v4-pool-manager"),
+		Format:          v.GetString("format"),
+		RowGroupBlocks:  v.GetUint64("row-group-blocks"),
+		Partition:       v.GetString("partition"),
+		SegmentBlocks:   v.GetUint64("segment-blocks"),
+		SegmentRecords:  v.GetInt("segment-records"),
 	}
 
 	return cfg, nil
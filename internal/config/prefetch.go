@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// PrefetchConfig holds configuration for the prefetch-times command.
+type PrefetchConfig struct {
+	RPC        []string
+	FromBlock  uint64
+	ToBlock    uint64
+	BatchSize  uint64
+	BlockTimes string
+	LogLevel   string
+}
+
+// LoadPrefetch merges config file, environment variables, and flags into PrefetchConfig.
+func LoadPrefetch(cfgFile string, flags *pflag.FlagSet) (PrefetchConfig, error) {
+	v := viper.New()
+	v.SetEnvPrefix("INDEXER")
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+
+	v.SetDefault("batch-size", uint64(1000))
+	v.SetDefault("block-times", "./data/block_times.bin")
+	v.SetDefault("log-level", "info")
+
+	if flags != nil {
+		if err := v.BindPFlags(flags); err != nil {
+			return PrefetchConfig{}, fmt.Errorf("bind flags: %w", err)
+		}
+	}
+
+	if cfgFile != "" {
+		v.SetConfigFile(cfgFile)
+		if err := v.ReadInConfig(); err != nil {
+			return PrefetchConfig{}, fmt.Errorf("read config: %w", err)
+		}
+	} else {
+		v.SetConfigName("config")
+		v.AddConfigPath(".")
+		if err := v.ReadInConfig(); err != nil {
+			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+				return PrefetchConfig{}, fmt.Errorf("read config: %w", err)
+			}
+		}
+	}
+
+	cfg := PrefetchConfig{
+		RPC:        getStringSlice(v, "rpc"),
+		FromBlock:  v.GetUint64("from"),
+		ToBlock:    v.GetUint64("to"),
+		BatchSize:  v.GetUint64("batch-size"),
+		BlockTimes: v.GetString("block-times"),
+		LogLevel:   v.GetString("log-level"),
+	}
+
+	return cfg, nil
+}
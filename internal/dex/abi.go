@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
 )
 
 const v3PoolABIJSON = `[
@@ -62,6 +63,59 @@ const v3PoolABIJSON = `[
     "name": "Collect",
     "type": "event"
   },
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": false, "internalType": "uint160", "name": "sqrtPriceX96", "type": "uint160"},
+      {"indexed": false, "internalType": "int24", "name": "tick", "type": "int24"}
+    ],
+    "name": "Initialize",
+    "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": true, "internalType": "address", "name": "sender", "type": "address"},
+      {"indexed": true, "internalType": "address", "name": "recipient", "type": "address"},
+      {"indexed": false, "internalType": "uint256", "name": "amount0", "type": "uint256"},
+      {"indexed": false, "internalType": "uint256", "name": "amount1", "type": "uint256"},
+      {"indexed": false, "internalType": "uint256", "name": "paid0", "type": "uint256"},
+      {"indexed": false, "internalType": "uint256", "name": "paid1", "type": "uint256"}
+    ],
+    "name": "Flash",
+    "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": true, "internalType": "address", "name": "sender", "type": "address"},
+      {"indexed": true, "internalType": "address", "name": "recipient", "type": "address"},
+      {"indexed": false, "internalType": "uint128", "name": "amount0", "type": "uint128"},
+      {"indexed": false, "internalType": "uint128", "name": "amount1", "type": "uint128"}
+    ],
+    "name": "CollectProtocol",
+    "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": false, "internalType": "uint8", "name": "feeProtocol0Old", "type": "uint8"},
+      {"indexed": false, "internalType": "uint8", "name": "feeProtocol1Old", "type": "uint8"},
+      {"indexed": false, "internalType": "uint8", "name": "feeProtocol0New", "type": "uint8"},
+      {"indexed": false, "internalType": "uint8", "name": "feeProtocol1New", "type": "uint8"}
+    ],
+    "name": "SetFeeProtocol",
+    "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": false, "internalType": "uint16", "name": "observationCardinalityNextOld", "type": "uint16"},
+      {"indexed": false, "internalType": "uint16", "name": "observationCardinalityNextNew", "type": "uint16"}
+    ],
+    "name": "IncreaseObservationCardinalityNext",
+    "type": "event"
+  },
   {
     "inputs": [],
     "name": "token0",
@@ -114,10 +168,71 @@ const v3PoolABIJSON = `[
   }
 ]`
 
+// pancakeV3PoolABIJSON holds the PancakeSwap V3 pool events and getters whose
+// layout differs from Uniswap V3. Its Swap also reports the protocol fees taken
+// and its fee protocol is a uint32, so both have their own topic0, and slot0
+// returns the uint32 fee protocol. lmPool exists only on PancakeSwap V3 pools,
+// which tells them apart.
+const pancakeV3PoolABIJSON = `[
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": true, "internalType": "address", "name": "sender", "type": "address"},
+      {"indexed": true, "internalType": "address", "name": "recipient", "type": "address"},
+      {"indexed": false, "internalType": "int256", "name": "amount0", "type": "int256"},
+      {"indexed": false, "internalType": "int256", "name": "amount1", "type": "int256"},
+      {"indexed": false, "internalType": "uint160", "name": "sqrtPriceX96", "type": "uint160"},
+      {"indexed": false, "internalType": "uint128", "name": "liquidity", "type": "uint128"},
+      {"indexed": false, "internalType": "int24", "name": "tick", "type": "int24"},
+      {"indexed": false, "internalType": "uint128", "name": "protocolFeesToken0", "type": "uint128"},
+      {"indexed": false, "internalType": "uint128", "name": "protocolFeesToken1", "type": "uint128"}
+    ],
+    "name": "Swap",
+    "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": false, "internalType": "uint32", "name": "feeProtocol0Old", "type": "uint32"},
+      {"indexed": false, "internalType": "uint32", "name": "feeProtocol1Old", "type": "uint32"},
+      {"indexed": false, "internalType": "uint32", "name": "feeProtocol0New", "type": "uint32"},
+      {"indexed": false, "internalType": "uint32", "name": "feeProtocol1New", "type": "uint32"}
+    ],
+    "name": "SetFeeProtocol",
+    "type": "event"
+  },
+  {
+    "inputs": [],
+    "name": "slot0",
+    "outputs": [
+      {"internalType": "uint160", "name": "sqrtPriceX96", "type": "uint160"},
+      {"internalType": "int24", "name": "tick", "type": "int24"},
+      {"internalType": "uint16", "name": "observationIndex", "type": "uint16"},
+      {"internalType": "uint16", "name": "observationCardinality", "type": "uint16"},
+      {"internalType": "uint16", "name": "observationCardinalityNext", "type": "uint16"},
+      {"internalType": "uint32", "name": "feeProtocol", "type": "uint32"},
+      {"internalType": "bool", "name": "unlocked", "type": "bool"}
+    ],
+    "stateMutability": "view",
+    "type": "function"
+  },
+  {
+    "inputs": [],
+    "name": "lmPool",
+    "outputs": [{"internalType": "address", "name": "", "type": "address"}],
+    "stateMutability": "view",
+    "type": "function"
+  }
+]`
+
 var (
 	v3PoolABI     abi.ABI
 	v3PoolABIOnce sync.Once
 	v3PoolABIErr  error
+
+	pancakeV3PoolABI     abi.ABI
+	pancakeV3PoolABIOnce sync.Once
+	pancakeV3PoolABIErr  error
 )
 
 // V3PoolABI returns the parsed V3 pool ABI.
@@ -127,3 +242,48 @@ func V3PoolABI() (abi.ABI, error) {
 	})
 	return v3PoolABI, v3PoolABIErr
 }
+
+// PancakeV3PoolABI returns the parsed PancakeSwap V3 event variants.
+func PancakeV3PoolABI() (abi.ABI, error) {
+	pancakeV3PoolABIOnce.Do(func() {
+		pancakeV3PoolABI, pancakeV3PoolABIErr = abi.JSON(strings.NewReader(pancakeV3PoolABIJSON))
+	})
+	return pancakeV3PoolABI, pancakeV3PoolABIErr
+}
+
+// V3PoolEventNames lists the V3 pool events, in decoding order.
+var V3PoolEventNames = []string{
+	"Swap",
+	"Mint",
+	"Burn",
+	"Collect",
+	"Initialize",
+	"Flash",
+	"CollectProtocol",
+	"SetFeeProtocol",
+	"IncreaseObservationCardinalityNext",
+}
+
+// pancakeV3EventNames lists the PancakeSwap V3 variants of V3 pool events.
+var pancakeV3EventNames = []string{"Swap", "SetFeeProtocol"}
+
+// V3PoolEventTopics returns the topic0 of every V3 pool event, including the
+// PancakeSwap V3 variants.
+func V3PoolEventTopics() ([]common.Hash, error) {
+	poolABI, err := V3PoolABI()
+	if err != nil {
+		return nil, err
+	}
+	pancakeABI, err := PancakeV3PoolABI()
+	if err != nil {
+		return nil, err
+	}
+	topics := make([]common.Hash, 0, len(V3PoolEventNames)+len(pancakeV3EventNames))
+	for _, name := range V3PoolEventNames {
+		topics = append(topics, poolABI.Events[name].ID)
+	}
+	for _, name := range pancakeV3EventNames {
+		topics = append(topics, pancakeABI.Events[name].ID)
+	}
+	return topics, nil
+}
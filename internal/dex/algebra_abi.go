@@ -0,0 +1,113 @@
+package dex
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// algebraPoolABIJSON covers what Algebra pools (THENA Fusion, QuickSwap V3)
+// add to the V3 layout. Their Swap, Mint, Burn and Collect have the V3
+// signatures and are decoded by V3PoolDecoder, but their fee changes and
+// globalState differ: see algebraDirectionalPoolABIJSON. globalState lists
+// only its leading fields.
+const algebraPoolABIJSON = `[
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": false, "internalType": "uint16", "name": "fee", "type": "uint16"}
+    ],
+    "name": "Fee",
+    "type": "event"
+  },
+  {
+    "inputs": [],
+    "name": "globalState",
+    "outputs": [
+      {"internalType": "uint160", "name": "price", "type": "uint160"},
+      {"internalType": "int24", "name": "tick", "type": "int24"},
+      {"internalType": "uint16", "name": "fee", "type": "uint16"}
+    ],
+    "stateMutability": "view",
+    "type": "function"
+  }
+]`
+
+// algebraDirectionalPoolABIJSON covers the Algebra 1.9 pools with
+// directional fees (Camelot V3), which charge feeZto on zero-for-one swaps and
+// feeOtz on one-for-zero swaps. Their Fee event has its own topic0 and their
+// globalState is one word longer, which tells the layouts apart.
+const algebraDirectionalPoolABIJSON = `[
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": false, "internalType": "uint16", "name": "feeZto", "type": "uint16"},
+      {"indexed": false, "internalType": "uint16", "name": "feeOtz", "type": "uint16"}
+    ],
+    "name": "Fee",
+    "type": "event"
+  },
+  {
+    "inputs": [],
+    "name": "globalState",
+    "outputs": [
+      {"internalType": "uint160", "name": "price", "type": "uint160"},
+      {"internalType": "int24", "name": "tick", "type": "int24"},
+      {"internalType": "uint16", "name": "feeZto", "type": "uint16"},
+      {"internalType": "uint16", "name": "feeOtz", "type": "uint16"},
+      {"internalType": "uint16", "name": "timepointIndex", "type": "uint16"},
+      {"internalType": "uint8", "name": "communityFeeToken0", "type": "uint8"},
+      {"internalType": "uint8", "name": "communityFeeToken1", "type": "uint8"},
+      {"internalType": "bool", "name": "unlocked", "type": "bool"}
+    ],
+    "stateMutability": "view",
+    "type": "function"
+  }
+]`
+
+// algebraDirectionalStateWords is the length in words of the globalState of
+// directional-fee pools. Algebra 1.0 returns 7 words.
+const algebraDirectionalStateWords = 8
+
+var (
+	algebraPoolABI     abi.ABI
+	algebraPoolABIOnce sync.Once
+	algebraPoolABIErr  error
+
+	algebraDirectionalPoolABI     abi.ABI
+	algebraDirectionalPoolABIOnce sync.Once
+	algebraDirectionalPoolABIErr  error
+)
+
+// AlgebraPoolABI returns the parsed Algebra pool ABI.
+func AlgebraPoolABI() (abi.ABI, error) {
+	algebraPoolABIOnce.Do(func() {
+		algebraPoolABI, algebraPoolABIErr = abi.JSON(strings.NewReader(algebraPoolABIJSON))
+	})
+	return algebraPoolABI, algebraPoolABIErr
+}
+
+// AlgebraDirectionalPoolABI returns the parsed ABI of directional-fee Algebra
+// pools.
+func AlgebraDirectionalPoolABI() (abi.ABI, error) {
+	algebraDirectionalPoolABIOnce.Do(func() {
+		algebraDirectionalPoolABI, algebraDirectionalPoolABIErr = abi.JSON(strings.NewReader(algebraDirectionalPoolABIJSON))
+	})
+	return algebraDirectionalPoolABI, algebraDirectionalPoolABIErr
+}
+
+// AlgebraFeeTopics returns the topic0s of the Algebra Fee events, plain and
+// directional.
+func AlgebraFeeTopics() ([]common.Hash, error) {
+	poolABI, err := AlgebraPoolABI()
+	if err != nil {
+		return nil, err
+	}
+	directionalABI, err := AlgebraDirectionalPoolABI()
+	if err != nil {
+		return nil, err
+	}
+	return []common.Hash{poolABI.Events["Fee"].ID, directionalABI.Events["Fee"].ID}, nil
+}
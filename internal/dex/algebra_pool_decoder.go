@@ -0,0 +1,100 @@
+package dex
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+
+	"liquidityScope/internal/model"
+)
+
+// AlgebraPoolDecoder decodes the fee changes of Algebra pools. Their other
+// events share the V3 topic0s and are left to V3PoolDecoder.
+type AlgebraPoolDecoder struct {
+	events map[string]poolEvent
+}
+
+// NewAlgebraPoolDecoder builds an Algebra pool decoder.
+func NewAlgebraPoolDecoder() (*AlgebraPoolDecoder, error) {
+	poolABI, err := AlgebraPoolABI()
+	if err != nil {
+		return nil, err
+	}
+	directionalABI, err := AlgebraDirectionalPoolABI()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(map[string]poolEvent)
+	for _, event := range []abi.Event{poolABI.Events["Fee"], directionalABI.Events["Fee"]} {
+		events[strings.ToLower(event.ID.Hex())] = poolEvent{name: "Fee", event: event}
+	}
+	return &AlgebraPoolDecoder{events: events}, nil
+}
+
+// CanDecode checks if the topic0 is supported.
+func (d *AlgebraPoolDecoder) CanDecode(topic0 string) bool {
+	if topic0 == "" {
+		return false
+	}
+	_, ok := d.events[strings.ToLower(topic0)]
+	return ok
+}
+
+// Decode converts a LogRecord into a TypedEvent.
+func (d *AlgebraPoolDecoder) Decode(log model.LogRecord, ctx DecodeContext) (*model.TypedEvent, error) {
+	if len(log.Topics) == 0 {
+		return nil, fmt.Errorf("missing topics")
+	}
+	variant, ok := d.events[strings.ToLower(log.Topics[0])]
+	if !ok {
+		return nil, fmt.Errorf("unsupported topic0: %s", log.Topics[0])
+	}
+
+	if !common.IsHexAddress(log.Address) {
+		return nil, fmt.Errorf("invalid pool address: %s", log.Address)
+	}
+	pool := common.HexToAddress(log.Address)
+
+	poolMeta, err := cachedPoolMeta(ctx, pool, poolMetaAt(log.BlockNumber))
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := decodeAlgebraFee(variant.event, log)
+	if err != nil {
+		return nil, err
+	}
+	return buildTypedEvent(log, variant.name, decoded, poolMeta), nil
+}
+
+// decodeAlgebraFee decodes the Algebra Fee(fee) event and the directional
+// Fee(feeZto, feeOtz).
+func decodeAlgebraFee(event abi.Event, log model.LogRecord) (model.AlgebraFeeEventData, error) {
+	if len(log.Topics) != 1 {
+		return model.AlgebraFeeEventData{}, fmt.Errorf("expected 1 topics, got %d", len(log.Topics))
+	}
+	values, err := unpackNonIndexed(event, log.Data)
+	if err != nil {
+		return model.AlgebraFeeEventData{}, err
+	}
+	if len(values) != 1 && len(values) != 2 {
+		return model.AlgebraFeeEventData{}, fmt.Errorf("unexpected fee values: %d", len(values))
+	}
+	fees := make([]uint32, 0, len(values))
+	for _, value := range values {
+		fee, err := asBigInt(value)
+		if err != nil {
+			return model.AlgebraFeeEventData{}, err
+		}
+		fees = append(fees, uint32(fee.Uint64()))
+	}
+
+	data := model.AlgebraFeeEventData{Fee: fees[0]}
+	if len(fees) == 2 {
+		data.FeeOtz = fees[1]
+	}
+	return data, nil
+}
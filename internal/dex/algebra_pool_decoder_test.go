@@ -0,0 +1,157 @@
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go.uber.org/zap"
+
+	"liquidityScope/internal/model"
+)
+
+func TestAlgebraPoolDecoderFee(t *testing.T) {
+	poolABI, err := AlgebraPoolABI()
+	if err != nil {
+		t.Fatalf("abi parse: %v", err)
+	}
+
+	pool := common.HexToAddress("0x7777777777777777777777777777777777777777")
+	poolMetaCache := NewPoolMetaCache()
+	poolMetaCache.Set(pool, model.PoolMeta{
+		Token0:      "0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		Token1:      "0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+		Fee:         500,
+		TickSpacing: 60,
+		Protocol:    model.ProtocolAlgebra,
+		DynamicFee:  true,
+	})
+
+	v3Decoder, err := NewV3PoolDecoder(DecoderConfig{})
+	if err != nil {
+		t.Fatalf("v3 decoder: %v", err)
+	}
+	algebraDecoder, err := NewAlgebraPoolDecoder()
+	if err != nil {
+		t.Fatalf("algebra decoder: %v", err)
+	}
+	decoder := NewMultiDecoder(v3Decoder, algebraDecoder)
+
+	ctx := DecodeContext{
+		PoolMetaCache: poolMetaCache,
+		Logger:        zap.NewNop(),
+	}
+
+	data, err := poolABI.Events["Fee"].Inputs.NonIndexed().Pack(uint16(2800))
+	if err != nil {
+		t.Fatalf("pack fee: %v", err)
+	}
+	event, err := decoder.Decode(buildLogRecord(pool, poolABI.Events["Fee"].ID, data, nil), ctx)
+	if err != nil {
+		t.Fatalf("decode fee: %v", err)
+	}
+	fee, ok := event.Decoded.(model.AlgebraFeeEventData)
+	if !ok {
+		t.Fatalf("fee type mismatch: %T", event.Decoded)
+	}
+	if event.EventName != "Fee" || fee.Fee != 2800 {
+		t.Fatalf("fee mismatch: %s %+v", event.EventName, fee)
+	}
+	if event.PoolMeta.Protocol != model.ProtocolAlgebra || !event.PoolMeta.DynamicFee {
+		t.Fatalf("pool meta mismatch: %+v", event.PoolMeta)
+	}
+
+	// Algebra swaps share the V3 topic0 and decode with the V3 layout.
+	v3ABI, err := V3PoolABI()
+	if err != nil {
+		t.Fatalf("v3 abi parse: %v", err)
+	}
+	swapData, err := v3ABI.Events["Swap"].Inputs.NonIndexed().Pack(
+		big.NewInt(-100),
+		big.NewInt(200),
+		new(big.Int).Lsh(big.NewInt(1), 96),
+		big.NewInt(1000),
+		big.NewInt(3),
+	)
+	if err != nil {
+		t.Fatalf("pack swap: %v", err)
+	}
+	sender := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	swapEvent, err := decoder.Decode(buildLogRecord(pool, v3ABI.Events["Swap"].ID, swapData, []common.Hash{
+		topicFromAddress(sender),
+		topicFromAddress(sender),
+	}), ctx)
+	if err != nil {
+		t.Fatalf("decode swap: %v", err)
+	}
+	if _, ok := swapEvent.Decoded.(model.SwapEventData); !ok || swapEvent.PoolMeta.Protocol != model.ProtocolAlgebra {
+		t.Fatalf("swap mismatch: %T %+v", swapEvent.Decoded, swapEvent.PoolMeta)
+	}
+}
+
+func TestAlgebraPoolDecoderDirectionalFee(t *testing.T) {
+	decoder, err := NewAlgebraPoolDecoder()
+	if err != nil {
+		t.Fatalf("algebra decoder: %v", err)
+	}
+
+	pool := common.HexToAddress("0x7777777777777777777777777777777777777777")
+	poolMetaCache := NewPoolMetaCache()
+	poolMetaCache.Set(pool, model.PoolMeta{Fee: 400, FeeOtz: 500, Protocol: model.ProtocolAlgebra, DynamicFee: true})
+
+	// A Camelot V3 Fee(uint16 feeZto, uint16 feeOtz) log as returned by
+	// eth_getLogs.
+	record := model.LogRecord{
+		ChainID:     42161,
+		BlockNumber: 150000000,
+		LogIndex:    7,
+		Address:     pool.Hex(),
+		Topics:      []string{crypto.Keccak256Hash([]byte("Fee(uint16,uint16)")).Hex()},
+		Data:        "0x00000000000000000000000000000000000000000000000000000000000001c200000000000000000000000000000000000000000000000000000000000000fa",
+	}
+	if !decoder.CanDecode(record.Topics[0]) {
+		t.Fatalf("directional fee topic not registered")
+	}
+	event, err := decoder.Decode(record, DecodeContext{PoolMetaCache: poolMetaCache})
+	if err != nil {
+		t.Fatalf("decode fee: %v", err)
+	}
+	fee, ok := event.Decoded.(model.AlgebraFeeEventData)
+	if !ok || event.EventName != "Fee" || fee.Fee != 450 || fee.FeeOtz != 250 {
+		t.Fatalf("directional fee mismatch: %s %+v", event.EventName, event.Decoded)
+	}
+}
+
+func TestAlgebraFeesFromOutput(t *testing.T) {
+	word := func(value uint64) []byte {
+		return common.LeftPadBytes(new(big.Int).SetUint64(value).Bytes(), 32)
+	}
+	state := func(words ...uint64) callOutput {
+		var data []byte
+		for _, value := range words {
+			data = append(data, word(value)...)
+		}
+		return callOutput{data: data}
+	}
+
+	// Algebra 1.0: price, tick, fee, timepointIndex, communityFeeToken0,
+	// communityFeeToken1, unlocked.
+	fees, err := algebraFeesFromOutput(state(1<<40, 10, 3000, 5, 0, 0, 1))
+	if err != nil {
+		t.Fatalf("plain global state: %v", err)
+	}
+	if fees != (algebraFees{fee: 3000}) {
+		t.Fatalf("plain fees mismatch: %+v", fees)
+	}
+
+	// Directional: price, tick, feeZto, feeOtz, timepointIndex,
+	// communityFeeToken0, communityFeeToken1, unlocked.
+	fees, err = algebraFeesFromOutput(state(1<<40, 10, 450, 250, 5, 0, 0, 1))
+	if err != nil {
+		t.Fatalf("directional global state: %v", err)
+	}
+	if fees != (algebraFees{fee: 450, feeOtz: 250}) {
+		t.Fatalf("directional fees mismatch: %+v", fees)
+	}
+}
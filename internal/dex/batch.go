@@ -0,0 +1,77 @@
+package dex
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+
+	"liquidityScope/internal/chain"
+)
+
+// contractCall is one ABI method call collected into a batch.
+type contractCall struct {
+	to     common.Address
+	abi    abi.ABI
+	method string
+	args   []interface{}
+}
+
+// callOutput is the raw return data of a batched call.
+type callOutput struct {
+	data []byte
+	err  error
+}
+
+// unpack decodes the call output with the given ABI, which may differ from
+// the one used to pack the call as long as the method selector matches.
+func (o callOutput) unpack(parsed abi.ABI, method string) ([]interface{}, error) {
+	if o.err != nil {
+		return nil, fmt.Errorf("call %s: %w", method, o.err)
+	}
+	values, err := parsed.Unpack(method, o.data)
+	if err != nil {
+		return nil, fmt.Errorf("unpack %s: %w", method, err)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("unpack %s: empty result", method)
+	}
+	return values, nil
+}
+
+// callBatch packs all calls at the same block into Multicall3 aggregate3
+// calls. Calls that fail individually are reported in their output; the
+// returned error is for failures of the whole batch, which are retried when
+// their class is transient.
+func callBatch(ctx context.Context, chainClient *chain.Client, calls []contractCall, block *big.Int) ([]callOutput, error) {
+	if chainClient == nil {
+		return nil, fmt.Errorf("chain client is nil")
+	}
+
+	requests := make([]chain.CallRequest, len(calls))
+	for i, call := range calls {
+		data, err := call.abi.Pack(call.method, call.args...)
+		if err != nil {
+			return nil, fmt.Errorf("pack %s: %w", call.method, err)
+		}
+		requests[i] = chain.CallRequest{To: call.to, Data: data}
+	}
+
+	var results []chain.CallResult
+	err := chain.Retry(ctx, chain.DefaultRetryPolicy, func(ctx context.Context) error {
+		var err error
+		results, err = chainClient.Aggregate3(ctx, requests, block)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]callOutput, len(results))
+	for i, result := range results {
+		outputs[i] = callOutput{data: result.Data, err: result.Err}
+	}
+	return outputs, nil
+}
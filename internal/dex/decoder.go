@@ -2,6 +2,7 @@ package dex
 
 import (
 	"context"
+	"fmt"
 
 	"go.uber.org/zap"
 
@@ -24,3 +25,40 @@ type DecodeContext struct {
 	Logger          *zap.Logger
 	IncludeLiveMeta bool
 }
+
+// MultiDecoder dispatches each log to the first decoder that supports its
+// topic0.
+type MultiDecoder struct {
+	decoders []Decoder
+}
+
+// NewMultiDecoder combines decoders whose topic0 sets do not overlap.
+func NewMultiDecoder(decoders ...Decoder) *MultiDecoder {
+	return &MultiDecoder{decoders: decoders}
+}
+
+// CanDecode checks if any decoder supports the topic0.
+func (d *MultiDecoder) CanDecode(topic0 string) bool {
+	return d.decoderFor(topic0) != nil
+}
+
+// Decode converts a LogRecord into a TypedEvent with the matching decoder.
+func (d *MultiDecoder) Decode(log model.LogRecord, ctx DecodeContext) (*model.TypedEvent, error) {
+	if len(log.Topics) == 0 {
+		return nil, fmt.Errorf("missing topics")
+	}
+	decoder := d.decoderFor(log.Topics[0])
+	if decoder == nil {
+		return nil, fmt.Errorf("unsupported topic0: %s", log.Topics[0])
+	}
+	return decoder.Decode(log, ctx)
+}
+
+func (d *MultiDecoder) decoderFor(topic0 string) Decoder {
+	for _, decoder := range d.decoders {
+		if decoder.CanDecode(topic0) {
+			return decoder
+		}
+	}
+	return nil
+}
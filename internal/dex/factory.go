@@ -0,0 +1,110 @@
+package dex
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+
+	"liquidityScope/internal/model"
+)
+
+// Known V3 factories on BSC.
+var (
+	PancakeV3Factory = common.HexToAddress("0x0BFbCF9fa4f9C56B0F40a671Ad40E0805A091865")
+	UniswapV3Factory = common.HexToAddress("0xdB1d10011AD0Ff90774D0C6Bb92e5C5c8b4461F7")
+)
+
+const v3FactoryABIJSON = `[
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": true, "internalType": "address", "name": "token0", "type": "address"},
+      {"indexed": true, "internalType": "address", "name": "token1", "type": "address"},
+      {"indexed": true, "internalType": "uint24", "name": "fee", "type": "uint24"},
+      {"indexed": false, "internalType": "int24", "name": "tickSpacing", "type": "int24"},
+      {"indexed": false, "internalType": "address", "name": "pool", "type": "address"}
+    ],
+    "name": "PoolCreated",
+    "type": "event"
+  }
+]`
+
+var (
+	v3FactoryABI     abi.ABI
+	v3FactoryABIOnce sync.Once
+	v3FactoryABIErr  error
+)
+
+// V3FactoryABI returns the parsed V3 factory ABI.
+func V3FactoryABI() (abi.ABI, error) {
+	v3FactoryABIOnce.Do(func() {
+		v3FactoryABI, v3FactoryABIErr = abi.JSON(strings.NewReader(v3FactoryABIJSON))
+	})
+	return v3FactoryABI, v3FactoryABIErr
+}
+
+// PoolCreatedTopic returns the topic0 of the V3 factory PoolCreated event.
+func PoolCreatedTopic() (common.Hash, error) {
+	factoryABI, err := V3FactoryABI()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return factoryABI.Events["PoolCreated"].ID, nil
+}
+
+// DecodePoolCreated decodes a factory PoolCreated log into a registry entry.
+func DecodePoolCreated(log model.LogRecord) (model.RegisteredPool, error) {
+	factoryABI, err := V3FactoryABI()
+	if err != nil {
+		return model.RegisteredPool{}, err
+	}
+	event := factoryABI.Events["PoolCreated"]
+
+	if len(log.Topics) == 0 || !strings.EqualFold(log.Topics[0], event.ID.Hex()) {
+		return model.RegisteredPool{}, fmt.Errorf("not a PoolCreated log")
+	}
+	topics, err := parseIndexedTopics(event, log.Topics)
+	if err != nil {
+		return model.RegisteredPool{}, err
+	}
+
+	values, err := unpackNonIndexed(event, log.Data)
+	if err != nil {
+		return model.RegisteredPool{}, err
+	}
+	if len(values) != 2 {
+		return model.RegisteredPool{}, fmt.Errorf("unexpected PoolCreated data size %d", len(values))
+	}
+	tickSpacing, err := asBigInt(values[0])
+	if err != nil {
+		return model.RegisteredPool{}, err
+	}
+	spacing, err := int24FromBig(tickSpacing)
+	if err != nil {
+		return model.RegisteredPool{}, err
+	}
+	pool, err := asAddress(values[1])
+	if err != nil {
+		return model.RegisteredPool{}, err
+	}
+
+	fee := new(big.Int).SetBytes(topics[2].Bytes())
+	if !fee.IsUint64() || fee.Uint64() > 1<<24-1 {
+		return model.RegisteredPool{}, fmt.Errorf("fee out of range: %s", fee)
+	}
+
+	return model.RegisteredPool{
+		Address:      pool.Hex(),
+		Factory:      common.HexToAddress(log.Address).Hex(),
+		Token0:       common.BytesToAddress(topics[0].Bytes()).Hex(),
+		Token1:       common.BytesToAddress(topics[1].Bytes()).Hex(),
+		Fee:          uint32(fee.Uint64()),
+		TickSpacing:  spacing,
+		CreatedBlock: log.BlockNumber,
+		TxHash:       log.TxHash,
+	}, nil
+}
@@ -7,7 +7,6 @@ import (
 	"math/big"
 	"sync"
 
-	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"go.uber.org/zap"
@@ -20,10 +19,13 @@ import (
 type PoolMetaCache struct {
 	mu   sync.RWMutex
 	data map[common.Address]model.PoolMeta
+	// seeded holds the entries taken from the pool registry, which lack the
+	// fee protocol read from chain.
+	seeded map[common.Address]struct{}
 }
 
 func NewPoolMetaCache() *PoolMetaCache {
-	return &PoolMetaCache{data: make(map[common.Address]model.PoolMeta)}
+	return &PoolMetaCache{data: make(map[common.Address]model.PoolMeta), seeded: make(map[common.Address]struct{})}
 }
 
 func (c *PoolMetaCache) Get(address common.Address) (model.PoolMeta, bool) {
@@ -36,9 +38,27 @@ func (c *PoolMetaCache) Get(address common.Address) (model.PoolMeta, bool) {
 func (c *PoolMetaCache) Set(address common.Address, meta model.PoolMeta) {
 	c.mu.Lock()
 	c.data[address] = meta
+	delete(c.seeded, address)
 	c.mu.Unlock()
 }
 
+// Seed caches registry metadata, which is completed on first use.
+func (c *PoolMetaCache) Seed(address common.Address, meta model.PoolMeta) {
+	c.mu.Lock()
+	c.data[address] = meta
+	c.seeded[address] = struct{}{}
+	c.mu.Unlock()
+}
+
+// Seeded reports whether the entry of a pool is registry metadata not yet
+// completed.
+func (c *PoolMetaCache) Seeded(address common.Address) bool {
+	c.mu.RLock()
+	_, ok := c.seeded[address]
+	c.mu.RUnlock()
+	return ok
+}
+
 // TokenMetaCache caches token metadata by address.
 type TokenMetaCache struct {
 	mu   sync.RWMutex
@@ -63,7 +83,13 @@ func (c *TokenMetaCache) Set(address common.Address, meta model.TokenMeta) {
 }
 
 // FetchPoolMeta loads immutable pool metadata from chain and token caches.
-func FetchPoolMeta(ctx context.Context, chainClient *chain.Client, pool common.Address, tokenCache *TokenMetaCache, logger *zap.Logger) (model.PoolMeta, error) {
+// The four pool getters are sent as one batch, followed by one batch for
+// whichever token metadata is not cached yet. Algebra pools are recognized by
+// a failing fee() and take the fee in force before block as Fee, read from
+// globalState at block-1 (an archive read for old blocks). When that state
+// cannot be read, their Fee is left 0: unknown. V3 pools likewise take the fee
+// protocol of slot0 at block-1, in the layout of their fork.
+func FetchPoolMeta(ctx context.Context, chainClient *chain.Client, pool common.Address, block uint64, tokenCache *TokenMetaCache, logger *zap.Logger) (model.PoolMeta, error) {
 	if chainClient == nil {
 		return model.PoolMeta{}, fmt.Errorf("chain client is nil")
 	}
@@ -73,7 +99,212 @@ func FetchPoolMeta(ctx context.Context, chainClient *chain.Client, pool common.A
 		return model.PoolMeta{}, fmt.Errorf("parse pool abi: %w", err)
 	}
 
-	values, err := callPoolMethod(ctx, chainClient, pool, poolABI, "token0", nil)
+	methods := []string{"token0", "token1", "fee", "tickSpacing"}
+	calls := make([]contractCall, 0, len(methods))
+	for _, method := range methods {
+		calls = append(calls, contractCall{to: pool, abi: poolABI, method: method})
+	}
+	outputs, err := callBatch(ctx, chainClient, calls, nil)
+	if err != nil {
+		return model.PoolMeta{}, err
+	}
+
+	feeOut := outputs[2]
+	var algebra *algebraFees
+	if feeOut.err != nil {
+		// Algebra pools have no fee(); their fee is in globalState.
+		var before *big.Int
+		if block > 0 {
+			before = new(big.Int).SetUint64(block - 1)
+		}
+		if fees, ok := fetchAlgebraFees(ctx, chainClient, pool, before); ok {
+			algebra = &fees
+		} else if before != nil {
+			// No endpoint serves the state before block, or the pool was
+			// created in it. The current fee would misprice older swaps.
+			if _, ok := fetchAlgebraFees(ctx, chainClient, pool, nil); ok {
+				if logger != nil {
+					logger.Warn("algebra fee before first event unavailable", zap.String("pool", pool.Hex()), zap.Uint64("block_number", block))
+				}
+				algebra = &algebraFees{}
+			}
+		}
+		if algebra != nil {
+			feeOut = feeOutput(poolABI, new(big.Int).SetUint64(uint64(algebra.fee)))
+		}
+	}
+
+	meta, err := poolMetaFromOutputs(poolABI, outputs[0], outputs[1], feeOut, outputs[3])
+	if err != nil {
+		return model.PoolMeta{}, err
+	}
+	if algebra != nil {
+		meta.FeeOtz = algebra.feeOtz
+		meta.Protocol = model.ProtocolAlgebra
+		meta.DynamicFee = true
+	} else {
+		fillFeeProtocol(ctx, chainClient, pool, block, &meta, logger)
+	}
+
+	fillTokenCache(ctx, chainClient, tokenCache, []common.Address{common.HexToAddress(meta.Token0), common.HexToAddress(meta.Token1)}, logger)
+
+	return meta, nil
+}
+
+// feeProtocol is the protocol's cut of the fees of a V3 pool. Uniswap V3 pools
+// take 1/token of the fees and leave denominator 0.
+type feeProtocol struct {
+	token0      uint32
+	token1      uint32
+	denominator uint32
+}
+
+// fillFeeProtocol sets the fee protocol of a V3 pool in force before block on
+// meta. It is left 0 when it cannot be read, e.g. without an archive endpoint.
+func fillFeeProtocol(ctx context.Context, chainClient *chain.Client, pool common.Address, block uint64, meta *model.PoolMeta, logger *zap.Logger) {
+	protocol, err := fetchFeeProtocol(ctx, chainClient, pool, block)
+	if err != nil {
+		if logger != nil {
+			logger.Warn("fee protocol before first event unavailable", zap.String("pool", pool.Hex()), zap.Uint64("block_number", block), zap.Error(err))
+		}
+		return
+	}
+	meta.FeeProtocol0 = protocol.token0
+	meta.FeeProtocol1 = protocol.token1
+	meta.FeeProtocolDenominator = protocol.denominator
+}
+
+// fetchFeeProtocol reads the fee protocol of a V3 pool from slot0 at block-1,
+// or the latest block when block is 0. PancakeSwap V3 pools are recognized by
+// their lmPool getter and decoded with their own slot0 layout.
+func fetchFeeProtocol(ctx context.Context, chainClient *chain.Client, pool common.Address, block uint64) (feeProtocol, error) {
+	poolABI, err := V3PoolABI()
+	if err != nil {
+		return feeProtocol{}, err
+	}
+	pancakeABI, err := PancakeV3PoolABI()
+	if err != nil {
+		return feeProtocol{}, err
+	}
+
+	outputs, err := callBatch(ctx, chainClient, []contractCall{{to: pool, abi: pancakeABI, method: "lmPool"}}, nil)
+	if err != nil {
+		return feeProtocol{}, err
+	}
+	pancake := outputs[0].err == nil
+	if pancake {
+		poolABI = pancakeABI
+	}
+
+	var before *big.Int
+	if block > 0 {
+		before = new(big.Int).SetUint64(block - 1)
+	}
+	outputs, err = callBatch(ctx, chainClient, []contractCall{{to: pool, abi: poolABI, method: "slot0"}}, before)
+	if err != nil {
+		return feeProtocol{}, err
+	}
+	return feeProtocolFromSlot0(poolABI, outputs[0], pancake)
+}
+
+// feeProtocolFromSlot0 decodes the fee protocol of slot0. Uniswap V3 packs
+// the two tokens' values into the nibbles of a uint8, PancakeSwap V3 into the
+// 16-bit halves of a uint32 in units of pancakeProtocolFeeDenominator.
+func feeProtocolFromSlot0(poolABI abi.ABI, output callOutput, pancake bool) (feeProtocol, error) {
+	values, err := output.unpack(poolABI, "slot0")
+	if err != nil {
+		return feeProtocol{}, err
+	}
+	if len(values) < 6 {
+		return feeProtocol{}, fmt.Errorf("unexpected slot0 values: %d", len(values))
+	}
+	packed, err := asBigInt(values[5])
+	if err != nil {
+		return feeProtocol{}, fmt.Errorf("fee protocol: %w", err)
+	}
+	value := uint32(packed.Uint64())
+	if pancake {
+		return feeProtocol{token0: value % 65536, token1: value >> 16, denominator: pancakeProtocolFeeDenominator}, nil
+	}
+	return feeProtocol{token0: value % 16, token1: value >> 4}, nil
+}
+
+// algebraFees is the fee of an Algebra pool, or the zero-for-one and
+// one-for-zero fees of a directional-fee pool.
+type algebraFees struct {
+	fee    uint32
+	feeOtz uint32
+}
+
+// fetchAlgebraFees reads the fees of an Algebra pool from globalState at
+// block, or the latest block when nil. It reports false when the pool has no
+// globalState at that block.
+func fetchAlgebraFees(ctx context.Context, chainClient *chain.Client, pool common.Address, block *big.Int) (algebraFees, bool) {
+	algebraABI, err := AlgebraPoolABI()
+	if err != nil {
+		return algebraFees{}, false
+	}
+	outputs, err := callBatch(ctx, chainClient, []contractCall{{to: pool, abi: algebraABI, method: "globalState"}}, block)
+	if err != nil {
+		return algebraFees{}, false
+	}
+	fees, err := algebraFeesFromOutput(outputs[0])
+	if err != nil {
+		return algebraFees{}, false
+	}
+	return fees, true
+}
+
+// algebraFeesFromOutput decodes globalState with the layout its length
+// selects.
+func algebraFeesFromOutput(output callOutput) (algebraFees, error) {
+	if output.err == nil && len(output.data) == algebraDirectionalStateWords*32 {
+		directionalABI, err := AlgebraDirectionalPoolABI()
+		if err != nil {
+			return algebraFees{}, err
+		}
+		values, err := output.unpack(directionalABI, "globalState")
+		if err != nil {
+			return algebraFees{}, err
+		}
+		feeZto, err := asBigInt(values[2])
+		if err != nil {
+			return algebraFees{}, err
+		}
+		feeOtz, err := asBigInt(values[3])
+		if err != nil {
+			return algebraFees{}, err
+		}
+		return algebraFees{fee: uint32(feeZto.Uint64()), feeOtz: uint32(feeOtz.Uint64())}, nil
+	}
+
+	algebraABI, err := AlgebraPoolABI()
+	if err != nil {
+		return algebraFees{}, err
+	}
+	values, err := output.unpack(algebraABI, "globalState")
+	if err != nil {
+		return algebraFees{}, err
+	}
+	if len(values) < 3 {
+		return algebraFees{}, fmt.Errorf("unexpected globalState values: %d", len(values))
+	}
+	fee, err := asBigInt(values[2])
+	if err != nil {
+		return algebraFees{}, err
+	}
+	return algebraFees{fee: uint32(fee.Uint64())}, nil
+}
+
+// feeOutput encodes fee like the output of fee().
+func feeOutput(poolABI abi.ABI, fee *big.Int) callOutput {
+	data, err := poolABI.Methods["fee"].Outputs.Pack(fee)
+	return callOutput{data: data, err: err}
+}
+
+// poolMetaFromOutputs decodes the token0, token1, fee and tickSpacing getters.
+func poolMetaFromOutputs(poolABI abi.ABI, token0Out, token1Out, feeOut, tickSpacingOut callOutput) (model.PoolMeta, error) {
+	values, err := token0Out.unpack(poolABI, "token0")
 	if err != nil {
 		return model.PoolMeta{}, err
 	}
@@ -82,7 +313,7 @@ func FetchPoolMeta(ctx context.Context, chainClient *chain.Client, pool common.A
 		return model.PoolMeta{}, fmt.Errorf("token0: %w", err)
 	}
 
-	values, err = callPoolMethod(ctx, chainClient, pool, poolABI, "token1", nil)
+	values, err = token1Out.unpack(poolABI, "token1")
 	if err != nil {
 		return model.PoolMeta{}, err
 	}
@@ -91,7 +322,7 @@ func FetchPoolMeta(ctx context.Context, chainClient *chain.Client, pool common.A
 		return model.PoolMeta{}, fmt.Errorf("token1: %w", err)
 	}
 
-	values, err = callPoolMethod(ctx, chainClient, pool, poolABI, "fee", nil)
+	values, err = feeOut.unpack(poolABI, "fee")
 	if err != nil {
 		return model.PoolMeta{}, err
 	}
@@ -101,7 +332,7 @@ func FetchPoolMeta(ctx context.Context, chainClient *chain.Client, pool common.A
 	}
 	fee := uint32(feeInt.Uint64())
 
-	values, err = callPoolMethod(ctx, chainClient, pool, poolABI, "tickSpacing", nil)
+	values, err = tickSpacingOut.unpack(poolABI, "tickSpacing")
 	if err != nil {
 		return model.PoolMeta{}, err
 	}
@@ -114,37 +345,97 @@ func FetchPoolMeta(ctx context.Context, chainClient *chain.Client, pool common.A
 		return model.PoolMeta{}, fmt.Errorf("tick spacing: %w", err)
 	}
 
-	meta := model.PoolMeta{
+	return model.PoolMeta{
 		Token0:      token0.Hex(),
 		Token1:      token1.Hex(),
 		Fee:         fee,
 		TickSpacing: tickSpacing,
+	}, nil
+}
+
+// FetchV2PairMeta loads V2 pair metadata: token0 and token1 from the pair and
+// the fixed fee of the factory that created it.
+func FetchV2PairMeta(ctx context.Context, chainClient *chain.Client, pair common.Address, tokenCache *TokenMetaCache, logger *zap.Logger) (model.PoolMeta, error) {
+	if chainClient == nil {
+		return model.PoolMeta{}, fmt.Errorf("chain client is nil")
 	}
 
-	if tokenCache != nil {
-		log := logger
-		if log == nil {
-			log = zap.NewNop()
+	pairABI, err := V2PairABI()
+	if err != nil {
+		return model.PoolMeta{}, fmt.Errorf("parse pair abi: %w", err)
+	}
+
+	methods := []string{"token0", "token1", "factory"}
+	calls := make([]contractCall, 0, len(methods))
+	for _, method := range methods {
+		calls = append(calls, contractCall{to: pair, abi: pairABI, method: method})
+	}
+	outputs, err := callBatch(ctx, chainClient, calls, nil)
+	if err != nil {
+		return model.PoolMeta{}, err
+	}
+
+	addresses := make([]common.Address, len(methods))
+	for i, method := range methods {
+		values, err := outputs[i].unpack(pairABI, method)
+		if err != nil {
+			return model.PoolMeta{}, err
 		}
-		if _, ok := tokenCache.Get(token0); !ok {
-			if tokenMeta, err := FetchTokenMeta(ctx, chainClient, token0, log); err == nil {
-				tokenCache.Set(token0, tokenMeta)
-			} else {
-				log.Warn("token0 metadata fetch failed", zap.String("token", token0.Hex()), zap.Error(err))
-				tokenCache.Set(token0, tokenMeta)
-			}
+		addresses[i], err = asAddress(values[0])
+		if err != nil {
+			return model.PoolMeta{}, fmt.Errorf("%s: %w", method, err)
 		}
-		if _, ok := tokenCache.Get(token1); !ok {
-			if tokenMeta, err := FetchTokenMeta(ctx, chainClient, token1, log); err == nil {
-				tokenCache.Set(token1, tokenMeta)
-			} else {
-				log.Warn("token1 metadata fetch failed", zap.String("token", token1.Hex()), zap.Error(err))
-				tokenCache.Set(token1, tokenMeta)
-			}
+	}
+
+	fillTokenCache(ctx, chainClient, tokenCache, addresses[:2], logger)
+
+	return model.PoolMeta{
+		Token0:   addresses[0].Hex(),
+		Token1:   addresses[1].Hex(),
+		Fee:      V2PairFee(addresses[2]),
+		Protocol: model.ProtocolV2,
+	}, nil
+}
+
+// NativeCurrency is the V4 currency of the chain's native coin, which has no
+// ERC20 contract to read metadata from.
+var NativeCurrency = common.Address{}
+
+// NativeDecimals is the decimals of the native coin.
+const NativeDecimals uint8 = 18
+
+// fillTokenCache loads metadata for uncached tokens in one batch. Tokens whose
+// metadata cannot be read are cached with whatever fields were recovered.
+func fillTokenCache(ctx context.Context, chainClient *chain.Client, tokenCache *TokenMetaCache, tokens []common.Address, logger *zap.Logger) {
+	if tokenCache == nil {
+		return
+	}
+	log := logger
+	if log == nil {
+		log = zap.NewNop()
+	}
+
+	missing := make([]common.Address, 0, len(tokens))
+	for _, token := range tokens {
+		if token == NativeCurrency {
+			tokenCache.Set(token, model.TokenMeta{Address: token.Hex(), Decimals: NativeDecimals})
+			continue
 		}
+		if _, ok := tokenCache.Get(token); !ok {
+			missing = append(missing, token)
+		}
+	}
+	if len(missing) == 0 {
+		return
 	}
 
-	return meta, nil
+	metas, errs := FetchTokenMetas(ctx, chainClient, missing, log)
+	for i, token := range missing {
+		if errs[i] != nil {
+			log.Warn("token metadata fetch failed", zap.String("token", token.Hex()), zap.Error(errs[i]))
+		}
+		tokenCache.Set(token, metas[i])
+	}
 }
 
 // FetchPoolOptionalMeta loads optional pool fields (slot0/liquidity) at a block height.
@@ -163,9 +454,17 @@ func FetchPoolOptionalMeta(ctx context.Context, chainClient *chain.Client, pool
 		blockPtr = new(big.Int).SetUint64(blockNumber)
 	}
 
+	outputs, err := callBatch(ctx, chainClient, []contractCall{
+		{to: pool, abi: poolABI, method: "liquidity"},
+		{to: pool, abi: poolABI, method: "slot0"},
+	}, blockPtr)
+	if err != nil {
+		return model.PoolMeta{}, err
+	}
+
 	meta := model.PoolMeta{}
 
-	if values, err := callPoolMethod(ctx, chainClient, pool, poolABI, "liquidity", blockPtr); err == nil {
+	if values, err := outputs[0].unpack(poolABI, "liquidity"); err == nil {
 		if liq, err := asBigInt(values[0]); err == nil {
 			meta.Liquidity = liq.String()
 		}
@@ -173,7 +472,7 @@ func FetchPoolOptionalMeta(ctx context.Context, chainClient *chain.Client, pool
 		logger.Debug("liquidity call failed", zap.String("pool", pool.Hex()), zap.Error(err))
 	}
 
-	if values, err := callPoolMethod(ctx, chainClient, pool, poolABI, "slot0", blockPtr); err == nil && len(values) >= 2 {
+	if values, err := outputs[1].unpack(poolABI, "slot0"); err == nil && len(values) >= 2 {
 		sqrt, errSqrt := asBigInt(values[0])
 		tickInt, errTick := asBigInt(values[1])
 		if errSqrt == nil && errTick == nil {
@@ -191,57 +490,67 @@ func FetchPoolOptionalMeta(ctx context.Context, chainClient *chain.Client, pool
 	return meta, nil
 }
 
-func callPoolMethod(ctx context.Context, chainClient *chain.Client, pool common.Address, poolABI abi.ABI, method string, block *big.Int) ([]interface{}, error) {
-	data, err := poolABI.Pack(method)
-	if err != nil {
-		return nil, fmt.Errorf("pack %s: %w", method, err)
-	}
-	msg := ethereum.CallMsg{To: &pool, Data: data}
-	resp, err := chainClient.CallContract(ctx, msg, block)
-	if err != nil {
-		return nil, fmt.Errorf("call %s: %w", method, err)
+// FetchTokenMeta loads token metadata via ERC20 calls.
+func FetchTokenMeta(ctx context.Context, chainClient *chain.Client, token common.Address, logger *zap.Logger) (model.TokenMeta, error) {
+	metas, errs := FetchTokenMetas(ctx, chainClient, []common.Address{token}, logger)
+	return metas[0], errs[0]
+}
+
+// FetchTokenMetas loads metadata for several tokens with a single batch of
+// decimals/symbol/name calls. Results and errors are indexed like tokens.
+func FetchTokenMetas(ctx context.Context, chainClient *chain.Client, tokens []common.Address, logger *zap.Logger) ([]model.TokenMeta, []error) {
+	metas := make([]model.TokenMeta, len(tokens))
+	errs := make([]error, len(tokens))
+	for i, token := range tokens {
+		metas[i] = model.TokenMeta{Address: token.Hex()}
 	}
-	values, err := poolABI.Unpack(method, resp)
-	if err != nil {
-		return nil, fmt.Errorf("unpack %s: %w", method, err)
+
+	fail := func(err error) ([]model.TokenMeta, []error) {
+		for i := range errs {
+			errs[i] = err
+		}
+		return metas, errs
 	}
-	return values, nil
-}
 
-// FetchTokenMeta loads token metadata via ERC20 calls.
-func FetchTokenMeta(ctx context.Context, chainClient *chain.Client, token common.Address, logger *zap.Logger) (model.TokenMeta, error) {
-	meta := model.TokenMeta{Address: token.Hex()}
 	if chainClient == nil {
-		return meta, fmt.Errorf("chain client is nil")
+		return fail(fmt.Errorf("chain client is nil"))
 	}
 
 	stringABI, err := erc20ABIStringInstance()
 	if err != nil {
-		return meta, fmt.Errorf("parse erc20 string abi: %w", err)
+		return fail(fmt.Errorf("parse erc20 string abi: %w", err))
 	}
 	bytes32ABI, err := erc20ABIBytes32Instance()
 	if err != nil {
-		return meta, fmt.Errorf("parse erc20 bytes32 abi: %w", err)
+		return fail(fmt.Errorf("parse erc20 bytes32 abi: %w", err))
 	}
 
-	call := func(method string, parsed abi.ABI) ([]interface{}, error) {
-		data, err := parsed.Pack(method)
-		if err != nil {
-			return nil, fmt.Errorf("pack %s: %w", method, err)
-		}
-		msg := ethereum.CallMsg{To: &token, Data: data}
-		resp, err := chainClient.CallContract(ctx, msg, nil)
-		if err != nil {
-			return nil, fmt.Errorf("call %s: %w", method, err)
+	methods := []string{"decimals", "symbol", "name"}
+	calls := make([]contractCall, 0, len(tokens)*len(methods))
+	for _, token := range tokens {
+		for _, method := range methods {
+			calls = append(calls, contractCall{to: token, abi: stringABI, method: method})
 		}
-		values, err := parsed.Unpack(method, resp)
-		if err != nil {
-			return nil, fmt.Errorf("unpack %s: %w", method, err)
-		}
-		return values, nil
 	}
+	outputs, err := callBatch(ctx, chainClient, calls, nil)
+	if err != nil {
+		return fail(err)
+	}
+
+	for i, token := range tokens {
+		base := i * len(methods)
+		metas[i], errs[i] = tokenMetaFromOutputs(token, outputs[base], outputs[base+1], outputs[base+2], stringABI, bytes32ABI, logger)
+	}
+	return metas, errs
+}
+
+// tokenMetaFromOutputs decodes ERC20 metadata. symbol/name are decoded as
+// strings first and as bytes32 for legacy tokens; the selectors are shared,
+// so no extra call is needed for the fallback.
+func tokenMetaFromOutputs(token common.Address, decimalsOut, symbolOut, nameOut callOutput, stringABI, bytes32ABI abi.ABI, logger *zap.Logger) (model.TokenMeta, error) {
+	meta := model.TokenMeta{Address: token.Hex()}
 
-	values, err := call("decimals", stringABI)
+	values, err := decimalsOut.unpack(stringABI, "decimals")
 	if err != nil {
 		return meta, err
 	}
@@ -251,11 +560,11 @@ func FetchTokenMeta(ctx context.Context, chainClient *chain.Client, token common
 	}
 	meta.Decimals = decimals
 
-	if values, err := call("symbol", stringABI); err == nil {
+	if values, err := symbolOut.unpack(stringABI, "symbol"); err == nil {
 		if symbol, ok := values[0].(string); ok {
 			meta.Symbol = symbol
 		}
-	} else if values, err := call("symbol", bytes32ABI); err == nil {
+	} else if values, err := symbolOut.unpack(bytes32ABI, "symbol"); err == nil {
 		if symbol, ok := bytes32ToString(values[0]); ok {
 			meta.Symbol = symbol
 		}
@@ -263,11 +572,11 @@ func FetchTokenMeta(ctx context.Context, chainClient *chain.Client, token common
 		logger.Debug("symbol call failed", zap.String("token", token.Hex()), zap.Error(err))
 	}
 
-	if values, err := call("name", stringABI); err == nil {
+	if values, err := nameOut.unpack(stringABI, "name"); err == nil {
 		if name, ok := values[0].(string); ok {
 			meta.Name = name
 		}
-	} else if values, err := call("name", bytes32ABI); err == nil {
+	} else if values, err := nameOut.unpack(bytes32ABI, "name"); err == nil {
 		if name, ok := bytes32ToString(values[0]); ok {
 			meta.Name = name
 		}
@@ -0,0 +1,102 @@
+package dex
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestTokenMetaFromOutputs(t *testing.T) {
+	stringABI, err := erc20ABIStringInstance()
+	if err != nil {
+		t.Fatalf("string abi: %v", err)
+	}
+	bytes32ABI, err := erc20ABIBytes32Instance()
+	if err != nil {
+		t.Fatalf("bytes32 abi: %v", err)
+	}
+
+	decimals, err := stringABI.Methods["decimals"].Outputs.Pack(uint8(18))
+	if err != nil {
+		t.Fatalf("pack decimals: %v", err)
+	}
+	symbol, err := stringABI.Methods["symbol"].Outputs.Pack("CAKE")
+	if err != nil {
+		t.Fatalf("pack symbol: %v", err)
+	}
+	var legacyName [32]byte
+	copy(legacyName[:], "Maker")
+	name, err := bytes32ABI.Methods["name"].Outputs.Pack(legacyName)
+	if err != nil {
+		t.Fatalf("pack name: %v", err)
+	}
+
+	token := common.HexToAddress("0x0e09fabb73bd3ade0a17ecc321fd13a19e81ce82")
+	meta, err := tokenMetaFromOutputs(token,
+		callOutput{data: decimals},
+		callOutput{data: symbol},
+		callOutput{data: name},
+		stringABI, bytes32ABI, nil,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.Decimals != 18 || meta.Symbol != "CAKE" || meta.Name != "Maker" {
+		t.Fatalf("meta mismatch: %+v", meta)
+	}
+}
+
+func TestTokenMetaFromOutputsMissingDecimals(t *testing.T) {
+	stringABI, _ := erc20ABIStringInstance()
+	bytes32ABI, _ := erc20ABIBytes32Instance()
+
+	_, err := tokenMetaFromOutputs(common.Address{},
+		callOutput{err: errors.New("execution reverted")},
+		callOutput{},
+		callOutput{},
+		stringABI, bytes32ABI, nil,
+	)
+	if err == nil {
+		t.Fatalf("expected error when decimals call fails")
+	}
+}
+
+func TestFeeProtocolFromSlot0(t *testing.T) {
+	poolABI, err := V3PoolABI()
+	if err != nil {
+		t.Fatalf("abi parse: %v", err)
+	}
+	pancakeABI, err := PancakeV3PoolABI()
+	if err != nil {
+		t.Fatalf("pancake abi parse: %v", err)
+	}
+	price := new(big.Int).Lsh(big.NewInt(1), 96)
+
+	// Uniswap V3 packs 1/4 for token0 and 1/6 for token1 into nibbles.
+	data, err := poolABI.Methods["slot0"].Outputs.Pack(price, big.NewInt(10), uint16(1), uint16(1), uint16(1), uint8(4|6<<4), true)
+	if err != nil {
+		t.Fatalf("pack slot0: %v", err)
+	}
+	protocol, err := feeProtocolFromSlot0(poolABI, callOutput{data: data}, false)
+	if err != nil {
+		t.Fatalf("uniswap slot0: %v", err)
+	}
+	if protocol != (feeProtocol{token0: 4, token1: 6}) {
+		t.Fatalf("uniswap fee protocol mismatch: %+v", protocol)
+	}
+
+	// PancakeSwap V3 packs 3200 and 2500 per 10000 into 16-bit halves.
+	data, err = pancakeABI.Methods["slot0"].Outputs.Pack(price, big.NewInt(10), uint16(1), uint16(1), uint16(1), uint32(3200|2500<<16), true)
+	if err != nil {
+		t.Fatalf("pack pancake slot0: %v", err)
+	}
+	protocol, err = feeProtocolFromSlot0(pancakeABI, callOutput{data: data}, true)
+	if err != nil {
+		t.Fatalf("pancake slot0: %v", err)
+	}
+	if protocol != (feeProtocol{token0: 3200, token1: 2500, denominator: 10000}) {
+		t.Fatalf("pancake fee protocol mismatch: %+v", protocol)
+	}
+}
@@ -0,0 +1,206 @@
+package dex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+
+	"liquidityScope/internal/model"
+)
+
+// PoolRegistry is the set of pools discovered from factory events, persisted
+// as a JSON file.
+type PoolRegistry struct {
+	path  string
+	mu    sync.RWMutex
+	pools map[common.Address]model.RegisteredPool
+}
+
+type registryFile struct {
+	Pools []model.RegisteredPool `json:"pools"`
+}
+
+// LoadPoolRegistry reads the registry at path. A missing file yields an
+// empty registry that will be created on the first Save.
+func LoadPoolRegistry(path string) (*PoolRegistry, error) {
+	r := &PoolRegistry{path: path, pools: make(map[common.Address]model.RegisteredPool)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read pool registry: %w", err)
+	}
+
+	var file registryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse pool registry: %w", err)
+	}
+	for _, pool := range file.Pools {
+		if !common.IsHexAddress(pool.Address) {
+			return nil, fmt.Errorf("invalid pool address in registry: %s", pool.Address)
+		}
+		r.pools[common.HexToAddress(pool.Address)] = pool
+	}
+	return r, nil
+}
+
+// Len returns the number of registered pools.
+func (r *PoolRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.pools)
+}
+
+// Get returns the registry entry of a pool.
+func (r *PoolRegistry) Get(address common.Address) (model.RegisteredPool, bool) {
+	r.mu.RLock()
+	pool, ok := r.pools[address]
+	r.mu.RUnlock()
+	return pool, ok
+}
+
+// Add registers a pool and reports whether the registry changed.
+func (r *PoolRegistry) Add(pool model.RegisteredPool) bool {
+	address := common.HexToAddress(pool.Address)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.pools[address]; ok && existing == pool {
+		return false
+	}
+	r.pools[address] = pool
+	return true
+}
+
+// Remove drops a pool, e.g. when its PoolCreated log was orphaned by a reorg.
+func (r *PoolRegistry) Remove(address common.Address) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pools[address]; !ok {
+		return false
+	}
+	delete(r.pools, address)
+	return true
+}
+
+// Pools returns all entries ordered by creation block and address.
+func (r *PoolRegistry) Pools() []model.RegisteredPool {
+	r.mu.RLock()
+	pools := make([]model.RegisteredPool, 0, len(r.pools))
+	for _, pool := range r.pools {
+		pools = append(pools, pool)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(pools, func(i, j int) bool {
+		if pools[i].CreatedBlock != pools[j].CreatedBlock {
+			return pools[i].CreatedBlock < pools[j].CreatedBlock
+		}
+		return strings.ToLower(pools[i].Address) < strings.ToLower(pools[j].Address)
+	})
+	return pools
+}
+
+// Addresses returns the registered pool addresses in registry order.
+func (r *PoolRegistry) Addresses() []common.Address {
+	pools := r.Pools()
+	out := make([]common.Address, 0, len(pools))
+	for _, pool := range pools {
+		out = append(out, common.HexToAddress(pool.Address))
+	}
+	return out
+}
+
+// SeedPoolMetaCache fills cache with the immutable metadata of every
+// registered pool, so decoding skips the token0/token1/fee/tickSpacing calls.
+func (r *PoolRegistry) SeedPoolMetaCache(cache *PoolMetaCache) {
+	if cache == nil {
+		return
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for address, pool := range r.pools {
+		cache.Seed(address, pool.Meta())
+	}
+}
+
+// Save writes the registry atomically.
+func (r *PoolRegistry) Save() error {
+	dir := filepath.Dir(r.path)
+	if dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create registry dir: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(registryFile{Pools: r.Pools()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal pool registry: %w", err)
+	}
+
+	tmpPath := r.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("write pool registry tmp: %w", err)
+	}
+	if err := os.Rename(tmpPath, r.path); err != nil {
+		return fmt.Errorf("rename pool registry: %w", err)
+	}
+	return nil
+}
+
+// RegistrySink is a log sink that records factory PoolCreated events in a
+// PoolRegistry. Logs of other events are ignored.
+type RegistrySink struct {
+	registry *PoolRegistry
+	logger   *zap.Logger
+}
+
+func NewRegistrySink(registry *PoolRegistry, logger *zap.Logger) *RegistrySink {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &RegistrySink{registry: registry, logger: logger}
+}
+
+// PutLogBatch registers the pools created in a batch and saves the registry.
+func (s *RegistrySink) PutLogBatch(logs []model.LogRecord) error {
+	changed := false
+	for _, record := range logs {
+		pool, err := DecodePoolCreated(record)
+		if err != nil {
+			s.logger.Debug("skip factory log", zap.String("tx_hash", record.TxHash), zap.Uint64("log_index", record.LogIndex), zap.Error(err))
+			continue
+		}
+		if record.Removed {
+			if s.registry.Remove(common.HexToAddress(pool.Address)) {
+				s.logger.Info("pool removed by reorg", zap.String("pool", pool.Address), zap.Uint64("block_number", record.BlockNumber))
+				changed = true
+			}
+			continue
+		}
+		if s.registry.Add(pool) {
+			s.logger.Info("pool discovered",
+				zap.String("pool", pool.Address),
+				zap.String("factory", pool.Factory),
+				zap.String("token0", pool.Token0),
+				zap.String("token1", pool.Token1),
+				zap.Uint32("fee", pool.Fee),
+				zap.Uint64("block_number", pool.CreatedBlock),
+			)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.registry.Save()
+}
@@ -0,0 +1,92 @@
+package dex
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"liquidityScope/internal/model"
+)
+
+func buildPoolCreatedLog(t *testing.T, pool common.Address, fee int64, tickSpacing int64) model.LogRecord {
+	t.Helper()
+	factoryABI, err := V3FactoryABI()
+	if err != nil {
+		t.Fatalf("abi parse: %v", err)
+	}
+	event := factoryABI.Events["PoolCreated"]
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(tickSpacing), pool)
+	if err != nil {
+		t.Fatalf("pack PoolCreated: %v", err)
+	}
+	return buildLogRecord(PancakeV3Factory, event.ID, data, []common.Hash{
+		topicFromAddress(common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")),
+		topicFromAddress(common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")),
+		common.BigToHash(big.NewInt(fee)),
+	})
+}
+
+func TestDecodePoolCreated(t *testing.T) {
+	pool := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	entry, err := DecodePoolCreated(buildPoolCreatedLog(t, pool, 2500, 50))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if entry.Address != pool.Hex() || entry.Factory != PancakeV3Factory.Hex() {
+		t.Fatalf("address mismatch: %+v", entry)
+	}
+	if entry.Token0 != common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Hex() || entry.Fee != 2500 || entry.TickSpacing != 50 {
+		t.Fatalf("meta mismatch: %+v", entry)
+	}
+	if entry.CreatedBlock != 12345 {
+		t.Fatalf("block mismatch: %d", entry.CreatedBlock)
+	}
+
+	other := buildPoolCreatedLog(t, pool, 2500, 50)
+	other.Topics[0] = common.HexToHash("0x01").Hex()
+	if _, err := DecodePoolCreated(other); err == nil {
+		t.Fatalf("expected error for foreign topic0")
+	}
+}
+
+func TestRegistrySinkPersistsPools(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pools.json")
+	registry, err := LoadPoolRegistry(path)
+	if err != nil {
+		t.Fatalf("load empty: %v", err)
+	}
+
+	poolA := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	poolB := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	sink := NewRegistrySink(registry, nil)
+	if err := sink.PutLogBatch([]model.LogRecord{
+		buildPoolCreatedLog(t, poolA, 500, 10),
+		buildPoolCreatedLog(t, poolB, 2500, 50),
+	}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	orphaned := buildPoolCreatedLog(t, poolB, 2500, 50)
+	orphaned.Removed = true
+	if err := sink.PutLogBatch([]model.LogRecord{orphaned}); err != nil {
+		t.Fatalf("put tombstone: %v", err)
+	}
+
+	reloaded, err := LoadPoolRegistry(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	addresses := reloaded.Addresses()
+	if len(addresses) != 1 || addresses[0] != poolA {
+		t.Fatalf("registry mismatch: %v", addresses)
+	}
+
+	cache := NewPoolMetaCache()
+	reloaded.SeedPoolMetaCache(cache)
+	meta, ok := cache.Get(poolA)
+	if !ok || meta.Fee != 500 || meta.TickSpacing != 10 {
+		t.Fatalf("seeded meta mismatch: %+v ok=%v", meta, ok)
+	}
+}
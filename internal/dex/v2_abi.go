@@ -0,0 +1,125 @@
+package dex
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Known V2 factories on BSC.
+var (
+	PancakeV2Factory = common.HexToAddress("0xcA143Ce32Fe78f1f7019d7d551a6402fC5350c73")
+	UniswapV2Factory = common.HexToAddress("0x8909Dc15e40173Ff4699343b6eB8132c65e18eC6")
+)
+
+// V2 pair swap fees, in hundredths of a bip like the V3 fee tiers.
+const (
+	PancakeV2Fee uint32 = 2500
+	UniswapV2Fee uint32 = 3000
+)
+
+// V2PairFee returns the fixed swap fee of pairs created by factory. Pairs of
+// unknown factories are assumed to charge the Uniswap V2 fee.
+func V2PairFee(factory common.Address) uint32 {
+	if factory == PancakeV2Factory {
+		return PancakeV2Fee
+	}
+	return UniswapV2Fee
+}
+
+const v2PairABIJSON = `[
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": true, "internalType": "address", "name": "sender", "type": "address"},
+      {"indexed": false, "internalType": "uint256", "name": "amount0In", "type": "uint256"},
+      {"indexed": false, "internalType": "uint256", "name": "amount1In", "type": "uint256"},
+      {"indexed": false, "internalType": "uint256", "name": "amount0Out", "type": "uint256"},
+      {"indexed": false, "internalType": "uint256", "name": "amount1Out", "type": "uint256"},
+      {"indexed": true, "internalType": "address", "name": "to", "type": "address"}
+    ],
+    "name": "Swap",
+    "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": false, "internalType": "uint112", "name": "reserve0", "type": "uint112"},
+      {"indexed": false, "internalType": "uint112", "name": "reserve1", "type": "uint112"}
+    ],
+    "name": "Sync",
+    "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": true, "internalType": "address", "name": "sender", "type": "address"},
+      {"indexed": false, "internalType": "uint256", "name": "amount0", "type": "uint256"},
+      {"indexed": false, "internalType": "uint256", "name": "amount1", "type": "uint256"}
+    ],
+    "name": "Mint",
+    "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": true, "internalType": "address", "name": "sender", "type": "address"},
+      {"indexed": false, "internalType": "uint256", "name": "amount0", "type": "uint256"},
+      {"indexed": false, "internalType": "uint256", "name": "amount1", "type": "uint256"},
+      {"indexed": true, "internalType": "address", "name": "to", "type": "address"}
+    ],
+    "name": "Burn",
+    "type": "event"
+  },
+  {
+    "inputs": [],
+    "name": "token0",
+    "outputs": [{"internalType": "address", "name": "", "type": "address"}],
+    "stateMutability": "view",
+    "type": "function"
+  },
+  {
+    "inputs": [],
+    "name": "token1",
+    "outputs": [{"internalType": "address", "name": "", "type": "address"}],
+    "stateMutability": "view",
+    "type": "function"
+  },
+  {
+    "inputs": [],
+    "name": "factory",
+    "outputs": [{"internalType": "address", "name": "", "type": "address"}],
+    "stateMutability": "view",
+    "type": "function"
+  }
+]`
+
+var (
+	v2PairABI     abi.ABI
+	v2PairABIOnce sync.Once
+	v2PairABIErr  error
+)
+
+// V2PairABI returns the parsed V2 pair ABI.
+func V2PairABI() (abi.ABI, error) {
+	v2PairABIOnce.Do(func() {
+		v2PairABI, v2PairABIErr = abi.JSON(strings.NewReader(v2PairABIJSON))
+	})
+	return v2PairABI, v2PairABIErr
+}
+
+// V2PairEventTopics returns the topic0 of the Swap, Sync, Mint and Burn events.
+func V2PairEventTopics() ([]common.Hash, error) {
+	pairABI, err := V2PairABI()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{"Swap", "Sync", "Mint", "Burn"}
+	topics := make([]common.Hash, 0, len(names))
+	for _, name := range names {
+		topics = append(topics, pairABI.Events[name].ID)
+	}
+	return topics, nil
+}
@@ -0,0 +1,192 @@
+package dex
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+
+	"liquidityScope/internal/model"
+)
+
+// V2PairDecoder decodes PancakeSwap V2 / Uniswap V2 pair events.
+type V2PairDecoder struct {
+	events map[string]poolEvent
+}
+
+// NewV2PairDecoder builds a V2 pair decoder.
+func NewV2PairDecoder() (*V2PairDecoder, error) {
+	pairABI, err := V2PairABI()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(map[string]poolEvent)
+	for _, name := range []string{"Swap", "Sync", "Mint", "Burn"} {
+		event := pairABI.Events[name]
+		events[strings.ToLower(event.ID.Hex())] = poolEvent{name: name, event: event}
+	}
+	return &V2PairDecoder{events: events}, nil
+}
+
+// CanDecode checks if the topic0 is supported.
+func (d *V2PairDecoder) CanDecode(topic0 string) bool {
+	if topic0 == "" {
+		return false
+	}
+	_, ok := d.events[strings.ToLower(topic0)]
+	return ok
+}
+
+// Decode converts a LogRecord into a TypedEvent.
+func (d *V2PairDecoder) Decode(log model.LogRecord, ctx DecodeContext) (*model.TypedEvent, error) {
+	if len(log.Topics) == 0 {
+		return nil, fmt.Errorf("missing topics")
+	}
+	variant, ok := d.events[strings.ToLower(log.Topics[0])]
+	if !ok {
+		return nil, fmt.Errorf("unsupported topic0: %s", log.Topics[0])
+	}
+
+	if !common.IsHexAddress(log.Address) {
+		return nil, fmt.Errorf("invalid pair address: %s", log.Address)
+	}
+	pair := common.HexToAddress(log.Address)
+
+	pairMeta, err := cachedPoolMeta(ctx, pair, FetchV2PairMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded interface{}
+	switch variant.name {
+	case "Swap":
+		decoded, err = decodeV2Swap(variant.event, log)
+	case "Sync":
+		decoded, err = decodeSync(variant.event, log)
+	case "Mint":
+		decoded, err = decodeV2Mint(variant.event, log)
+	case "Burn":
+		decoded, err = decodeV2Burn(variant.event, log)
+	default:
+		return nil, fmt.Errorf("unsupported event name: %s", variant.name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buildTypedEvent(log, variant.name, decoded, pairMeta), nil
+}
+
+func decodeV2Swap(event abi.Event, log model.LogRecord) (model.V2SwapEventData, error) {
+	indexedTopics, err := parseIndexedTopics(event, log.Topics)
+	if err != nil {
+		return model.V2SwapEventData{}, err
+	}
+
+	var indexed struct {
+		Sender common.Address
+		To     common.Address
+	}
+	if err := abi.ParseTopics(&indexed, indexedArguments(event.Inputs), indexedTopics); err != nil {
+		return model.V2SwapEventData{}, fmt.Errorf("parse topics: %w", err)
+	}
+
+	amounts, err := unpackAmounts(event, log.Data, 4)
+	if err != nil {
+		return model.V2SwapEventData{}, err
+	}
+
+	return model.V2SwapEventData{
+		Sender:     indexed.Sender.Hex(),
+		To:         indexed.To.Hex(),
+		Amount0In:  amounts[0],
+		Amount1In:  amounts[1],
+		Amount0Out: amounts[2],
+		Amount1Out: amounts[3],
+	}, nil
+}
+
+func decodeSync(event abi.Event, log model.LogRecord) (model.SyncEventData, error) {
+	if len(log.Topics) != 1 {
+		return model.SyncEventData{}, fmt.Errorf("expected 1 topics, got %d", len(log.Topics))
+	}
+	reserves, err := unpackAmounts(event, log.Data, 2)
+	if err != nil {
+		return model.SyncEventData{}, err
+	}
+	return model.SyncEventData{Reserve0: reserves[0], Reserve1: reserves[1]}, nil
+}
+
+func decodeV2Mint(event abi.Event, log model.LogRecord) (model.V2MintEventData, error) {
+	indexedTopics, err := parseIndexedTopics(event, log.Topics)
+	if err != nil {
+		return model.V2MintEventData{}, err
+	}
+
+	var indexed struct {
+		Sender common.Address
+	}
+	if err := abi.ParseTopics(&indexed, indexedArguments(event.Inputs), indexedTopics); err != nil {
+		return model.V2MintEventData{}, fmt.Errorf("parse topics: %w", err)
+	}
+
+	amounts, err := unpackAmounts(event, log.Data, 2)
+	if err != nil {
+		return model.V2MintEventData{}, err
+	}
+
+	return model.V2MintEventData{
+		Sender:  indexed.Sender.Hex(),
+		Amount0: amounts[0],
+		Amount1: amounts[1],
+	}, nil
+}
+
+func decodeV2Burn(event abi.Event, log model.LogRecord) (model.V2BurnEventData, error) {
+	indexedTopics, err := parseIndexedTopics(event, log.Topics)
+	if err != nil {
+		return model.V2BurnEventData{}, err
+	}
+
+	var indexed struct {
+		Sender common.Address
+		To     common.Address
+	}
+	if err := abi.ParseTopics(&indexed, indexedArguments(event.Inputs), indexedTopics); err != nil {
+		return model.V2BurnEventData{}, fmt.Errorf("parse topics: %w", err)
+	}
+
+	amounts, err := unpackAmounts(event, log.Data, 2)
+	if err != nil {
+		return model.V2BurnEventData{}, err
+	}
+
+	return model.V2BurnEventData{
+		Sender:  indexed.Sender.Hex(),
+		To:      indexed.To.Hex(),
+		Amount0: amounts[0],
+		Amount1: amounts[1],
+	}, nil
+}
+
+// unpackAmounts unpacks want unsigned integers from the event data as decimal
+// strings.
+func unpackAmounts(event abi.Event, dataHex string, want int) ([]string, error) {
+	values, err := unpackNonIndexed(event, dataHex)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != want {
+		return nil, fmt.Errorf("unexpected %s values: %d", strings.ToLower(event.Name), len(values))
+	}
+	amounts := make([]string, 0, len(values))
+	for _, value := range values {
+		amount, err := asBigInt(value)
+		if err != nil {
+			return nil, err
+		}
+		amounts = append(amounts, amount.String())
+	}
+	return amounts, nil
+}
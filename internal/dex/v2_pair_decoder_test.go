@@ -0,0 +1,93 @@
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+
+	"liquidityScope/internal/model"
+)
+
+func TestV2PairDecoderSwapSync(t *testing.T) {
+	pairABI, err := V2PairABI()
+	if err != nil {
+		t.Fatalf("abi parse: %v", err)
+	}
+
+	pair := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	poolMetaCache := NewPoolMetaCache()
+	poolMetaCache.Set(pair, model.PoolMeta{
+		Token0:   "0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		Token1:   "0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+		Fee:      PancakeV2Fee,
+		Protocol: model.ProtocolV2,
+	})
+
+	v3Decoder, err := NewV3PoolDecoder(DecoderConfig{})
+	if err != nil {
+		t.Fatalf("v3 decoder: %v", err)
+	}
+	v2Decoder, err := NewV2PairDecoder()
+	if err != nil {
+		t.Fatalf("v2 decoder: %v", err)
+	}
+	decoder := NewMultiDecoder(v3Decoder, v2Decoder)
+
+	ctx := DecodeContext{
+		PoolMetaCache: poolMetaCache,
+		Logger:        zap.NewNop(),
+	}
+
+	sender := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	to := common.HexToAddress("0x3333333333333333333333333333333333333333")
+
+	swapData, err := pairABI.Events["Swap"].Inputs.NonIndexed().Pack(
+		big.NewInt(1000),
+		big.NewInt(0),
+		big.NewInt(0),
+		big.NewInt(1990),
+	)
+	if err != nil {
+		t.Fatalf("pack swap: %v", err)
+	}
+	swapLog := buildLogRecord(pair, pairABI.Events["Swap"].ID, swapData, []common.Hash{
+		topicFromAddress(sender),
+		topicFromAddress(to),
+	})
+
+	swapEvent, err := decoder.Decode(swapLog, ctx)
+	if err != nil {
+		t.Fatalf("decode swap: %v", err)
+	}
+	swap, ok := swapEvent.Decoded.(model.V2SwapEventData)
+	if !ok {
+		t.Fatalf("swap type mismatch: %T", swapEvent.Decoded)
+	}
+	if swap.Amount0In != "1000" || swap.Amount1Out != "1990" || swap.Amount1In != "0" {
+		t.Fatalf("swap amounts mismatch: %+v", swap)
+	}
+	if swap.Sender != sender.Hex() || swap.To != to.Hex() {
+		t.Fatalf("swap address mismatch: %+v", swap)
+	}
+	if swapEvent.PoolMeta.Protocol != model.ProtocolV2 || swapEvent.PoolMeta.Fee != PancakeV2Fee {
+		t.Fatalf("pair meta mismatch: %+v", swapEvent.PoolMeta)
+	}
+
+	syncData, err := pairABI.Events["Sync"].Inputs.NonIndexed().Pack(big.NewInt(51000), big.NewInt(98010))
+	if err != nil {
+		t.Fatalf("pack sync: %v", err)
+	}
+	syncEvent, err := decoder.Decode(buildLogRecord(pair, pairABI.Events["Sync"].ID, syncData, nil), ctx)
+	if err != nil {
+		t.Fatalf("decode sync: %v", err)
+	}
+	sync, ok := syncEvent.Decoded.(model.SyncEventData)
+	if !ok {
+		t.Fatalf("sync type mismatch: %T", syncEvent.Decoded)
+	}
+	if syncEvent.EventName != "Sync" || sync.Reserve0 != "51000" || sync.Reserve1 != "98010" {
+		t.Fatalf("sync mismatch: %+v", sync)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"go.uber.org/zap"
 
+	"liquidityScope/internal/chain"
 	"liquidityScope/internal/model"
 )
 
@@ -20,22 +22,35 @@ type DecoderConfig struct {
 
 // V3PoolDecoder decodes PancakeSwap V3 / Uniswap V3 pool events.
 type V3PoolDecoder struct {
-	poolABI     abi.ABI
-	topicToName map[string]string
+	events map[string]poolEvent
 }
 
-// NewV3PoolDecoder builds a V3 pool decoder.
+// poolEvent is the ABI variant of an event, selected by its topic0.
+type poolEvent struct {
+	name  string
+	event abi.Event
+}
+
+// NewV3PoolDecoder builds a V3 pool decoder. Topics in cfg.Topic0Map that are
+// not a known variant are decoded with the Uniswap V3 layout of the event.
 func NewV3PoolDecoder(cfg DecoderConfig) (*V3PoolDecoder, error) {
 	poolABI, err := V3PoolABI()
 	if err != nil {
 		return nil, err
 	}
+	pancakeABI, err := PancakeV3PoolABI()
+	if err != nil {
+		return nil, err
+	}
 
-	topicToName := map[string]string{
-		strings.ToLower(poolABI.Events["Swap"].ID.Hex()):    "Swap",
-		strings.ToLower(poolABI.Events["Mint"].ID.Hex()):    "Mint",
-		strings.ToLower(poolABI.Events["Burn"].ID.Hex()):    "Burn",
-		strings.ToLower(poolABI.Events["Collect"].ID.Hex()): "Collect",
+	events := make(map[string]poolEvent)
+	for _, name := range V3PoolEventNames {
+		event := poolABI.Events[name]
+		events[strings.ToLower(event.ID.Hex())] = poolEvent{name: name, event: event}
+	}
+	for _, name := range pancakeV3EventNames {
+		event := pancakeABI.Events[name]
+		events[strings.ToLower(event.ID.Hex())] = poolEvent{name: name, event: event}
 	}
 
 	for topic0, name := range cfg.Topic0Map {
@@ -47,13 +62,14 @@ func NewV3PoolDecoder(cfg DecoderConfig) (*V3PoolDecoder, error) {
 		if topic0 == "" {
 			continue
 		}
-		topicToName[strings.ToLower(topic0)] = name
+		topic0 = strings.ToLower(topic0)
+		if known, ok := events[topic0]; ok && known.name == name {
+			continue
+		}
+		events[topic0] = poolEvent{name: name, event: poolABI.Events[name]}
 	}
 
-	return &V3PoolDecoder{
-		poolABI:     poolABI,
-		topicToName: topicToName,
-	}, nil
+	return &V3PoolDecoder{events: events}, nil
 }
 
 // CanDecode checks if the topic0 is supported.
@@ -61,7 +77,7 @@ func (d *V3PoolDecoder) CanDecode(topic0 string) bool {
 	if topic0 == "" {
 		return false
 	}
-	_, ok := d.topicToName[strings.ToLower(topic0)]
+	_, ok := d.events[strings.ToLower(topic0)]
 	return ok
 }
 
@@ -70,7 +86,7 @@ func (d *V3PoolDecoder) Decode(log model.LogRecord, ctx DecodeContext) (*model.T
 	if len(log.Topics) == 0 {
 		return nil, fmt.Errorf("missing topics")
 	}
-	name, ok := d.topicToName[strings.ToLower(log.Topics[0])]
+	variant, ok := d.events[strings.ToLower(log.Topics[0])]
 	if !ok {
 		return nil, fmt.Errorf("unsupported topic0: %s", log.Topics[0])
 	}
@@ -85,34 +101,33 @@ func (d *V3PoolDecoder) Decode(log model.LogRecord, ctx DecodeContext) (*model.T
 		return nil, err
 	}
 
-	switch name {
+	var decoded interface{}
+	switch variant.name {
 	case "Swap":
-		decoded, err := d.decodeSwap(log)
-		if err != nil {
-			return nil, err
-		}
-		return buildTypedEvent(log, name, decoded, poolMeta), nil
+		decoded, err = decodeSwap(variant.event, log)
 	case "Mint":
-		decoded, err := d.decodeMint(log)
-		if err != nil {
-			return nil, err
-		}
-		return buildTypedEvent(log, name, decoded, poolMeta), nil
+		decoded, err = decodeMint(variant.event, log)
 	case "Burn":
-		decoded, err := d.decodeBurn(log)
-		if err != nil {
-			return nil, err
-		}
-		return buildTypedEvent(log, name, decoded, poolMeta), nil
+		decoded, err = decodeBurn(variant.event, log)
 	case "Collect":
-		decoded, err := d.decodeCollect(log)
-		if err != nil {
-			return nil, err
-		}
-		return buildTypedEvent(log, name, decoded, poolMeta), nil
+		decoded, err = decodeCollect(variant.event, log)
+	case "Initialize":
+		decoded, err = decodeInitialize(variant.event, log)
+	case "Flash":
+		decoded, err = decodeFlash(variant.event, log)
+	case "CollectProtocol":
+		decoded, err = decodeCollectProtocol(variant.event, log)
+	case "SetFeeProtocol":
+		decoded, err = decodeSetFeeProtocol(variant.event, log)
+	case "IncreaseObservationCardinalityNext":
+		decoded, err = decodeIncreaseObservationCardinalityNext(variant.event, log)
 	default:
-		return nil, fmt.Errorf("unsupported event name: %s", name)
+		return nil, fmt.Errorf("unsupported event name: %s", variant.name)
+	}
+	if err != nil {
+		return nil, err
 	}
+	return buildTypedEvent(log, variant.name, decoded, poolMeta), nil
 }
 
 func normalizeEventName(name string) string {
@@ -125,35 +140,50 @@ func normalizeEventName(name string) string {
 		return "Burn"
 	case "collect":
 		return "Collect"
+	case "initialize":
+		return "Initialize"
+	case "flash":
+		return "Flash"
+	case "collectprotocol":
+		return "CollectProtocol"
+	case "setfeeprotocol":
+		return "SetFeeProtocol"
+	case "increaseobservationcardinalitynext":
+		return "IncreaseObservationCardinalityNext"
 	default:
 		return ""
 	}
 }
 
-func getPoolMeta(ctx DecodeContext, pool common.Address, blockNumber uint64) (model.PoolMeta, error) {
-	var meta model.PoolMeta
-	var ok bool
-	if ctx.PoolMetaCache != nil {
-		meta, ok = ctx.PoolMetaCache.Get(pool)
+// poolMetaFetcher loads the immutable metadata of a pool from chain.
+type poolMetaFetcher func(ctx context.Context, chainClient *chain.Client, pool common.Address, tokenCache *TokenMetaCache, logger *zap.Logger) (model.PoolMeta, error)
+
+// poolMetaAt returns a fetcher of V3 and Algebra pool metadata that takes the
+// fee of Algebra pools in force before block, the first one the pool is seen at.
+func poolMetaAt(block uint64) poolMetaFetcher {
+	return func(ctx context.Context, chainClient *chain.Client, pool common.Address, tokenCache *TokenMetaCache, logger *zap.Logger) (model.PoolMeta, error) {
+		return FetchPoolMeta(ctx, chainClient, pool, block, tokenCache, logger)
 	}
-	if ctx.Chain == nil {
+}
+
+func getPoolMeta(ctx DecodeContext, pool common.Address, blockNumber uint64) (model.PoolMeta, error) {
+	if ctx.Chain == nil && ctx.IncludeLiveMeta {
 		return model.PoolMeta{}, fmt.Errorf("chain client is nil")
 	}
+	meta, err := cachedPoolMeta(ctx, pool, poolMetaAt(blockNumber))
+	if err != nil {
+		return model.PoolMeta{}, err
+	}
 
 	callCtx := ctx.Context
 	if callCtx == nil {
 		callCtx = context.Background()
 	}
 
-	if !ok {
-		var err error
-		meta, err = FetchPoolMeta(callCtx, ctx.Chain, pool, ctx.TokenMetaCache, ctx.Logger)
-		if err != nil {
-			return model.PoolMeta{}, err
-		}
-		if ctx.PoolMetaCache != nil {
-			ctx.PoolMetaCache.Set(pool, meta)
-		}
+	// Registry metadata carries no fee protocol: read it once, like a fetch.
+	if ctx.PoolMetaCache != nil && ctx.PoolMetaCache.Seeded(pool) && ctx.Chain != nil {
+		fillFeeProtocol(callCtx, ctx.Chain, pool, blockNumber, &meta, ctx.Logger)
+		ctx.PoolMetaCache.Set(pool, meta)
 	}
 
 	if ctx.IncludeLiveMeta {
@@ -169,6 +199,32 @@ func getPoolMeta(ctx DecodeContext, pool common.Address, blockNumber uint64) (mo
 	return meta, nil
 }
 
+// cachedPoolMeta returns the cached metadata of pool, fetching and caching it
+// on a miss.
+func cachedPoolMeta(ctx DecodeContext, pool common.Address, fetch poolMetaFetcher) (model.PoolMeta, error) {
+	if ctx.PoolMetaCache != nil {
+		if meta, ok := ctx.PoolMetaCache.Get(pool); ok {
+			return meta, nil
+		}
+	}
+	if ctx.Chain == nil {
+		return model.PoolMeta{}, fmt.Errorf("chain client is nil")
+	}
+
+	callCtx := ctx.Context
+	if callCtx == nil {
+		callCtx = context.Background()
+	}
+	meta, err := fetch(callCtx, ctx.Chain, pool, ctx.TokenMetaCache, ctx.Logger)
+	if err != nil {
+		return model.PoolMeta{}, err
+	}
+	if ctx.PoolMetaCache != nil {
+		ctx.PoolMetaCache.Set(pool, meta)
+	}
+	return meta, nil
+}
+
 func buildTypedEvent(log model.LogRecord, name string, decoded interface{}, meta model.PoolMeta) *model.TypedEvent {
 	raw := &model.RawLogRef{Topic0: log.Topics[0], Data: log.Data}
 	return &model.TypedEvent{
@@ -183,11 +239,13 @@ func buildTypedEvent(log model.LogRecord, name string, decoded interface{}, meta
 		Decoded:     decoded,
 		PoolMeta:    meta,
 		Raw:         raw,
+		Removed:     log.Removed,
 	}
 }
 
-func (d *V3PoolDecoder) decodeSwap(log model.LogRecord) (model.SwapEventData, error) {
-	event := d.poolABI.Events["Swap"]
+// decodeSwap decodes the Uniswap V3 Swap and the PancakeSwap V3 Swap, which
+// appends protocolFeesToken0 and protocolFeesToken1.
+func decodeSwap(event abi.Event, log model.LogRecord) (model.SwapEventData, error) {
 	indexedTopics, err := parseIndexedTopics(event, log.Topics)
 	if err != nil {
 		return model.SwapEventData{}, err
@@ -205,7 +263,7 @@ func (d *V3PoolDecoder) decodeSwap(log model.LogRecord) (model.SwapEventData, er
 	if err != nil {
 		return model.SwapEventData{}, err
 	}
-	if len(values) != 5 {
+	if len(values) != 5 && len(values) != 7 {
 		return model.SwapEventData{}, fmt.Errorf("unexpected swap values: %d", len(values))
 	}
 
@@ -234,7 +292,7 @@ func (d *V3PoolDecoder) decodeSwap(log model.LogRecord) (model.SwapEventData, er
 		return model.SwapEventData{}, err
 	}
 
-	return model.SwapEventData{
+	swap := model.SwapEventData{
 		Sender:       indexed.Sender.Hex(),
 		Recipient:    indexed.Recipient.Hex(),
 		Amount0:      amount0.String(),
@@ -242,11 +300,23 @@ func (d *V3PoolDecoder) decodeSwap(log model.LogRecord) (model.SwapEventData, er
 		SqrtPriceX96: sqrtPrice.String(),
 		Liquidity:    liquidity.String(),
 		Tick:         tick,
-	}, nil
+	}
+	if len(values) == 7 {
+		protocolFees0, err := asBigInt(values[5])
+		if err != nil {
+			return model.SwapEventData{}, err
+		}
+		protocolFees1, err := asBigInt(values[6])
+		if err != nil {
+			return model.SwapEventData{}, err
+		}
+		swap.ProtocolFeesToken0 = protocolFees0.String()
+		swap.ProtocolFeesToken1 = protocolFees1.String()
+	}
+	return swap, nil
 }
 
-func (d *V3PoolDecoder) decodeMint(log model.LogRecord) (model.MintEventData, error) {
-	event := d.poolABI.Events["Mint"]
+func decodeMint(event abi.Event, log model.LogRecord) (model.MintEventData, error) {
 	indexedTopics, err := parseIndexedTopics(event, log.Topics)
 	if err != nil {
 		return model.MintEventData{}, err
@@ -306,8 +376,7 @@ func (d *V3PoolDecoder) decodeMint(log model.LogRecord) (model.MintEventData, er
 	}, nil
 }
 
-func (d *V3PoolDecoder) decodeBurn(log model.LogRecord) (model.BurnEventData, error) {
-	event := d.poolABI.Events["Burn"]
+func decodeBurn(event abi.Event, log model.LogRecord) (model.BurnEventData, error) {
 	indexedTopics, err := parseIndexedTopics(event, log.Topics)
 	if err != nil {
 		return model.BurnEventData{}, err
@@ -362,8 +431,7 @@ func (d *V3PoolDecoder) decodeBurn(log model.LogRecord) (model.BurnEventData, er
 	}, nil
 }
 
-func (d *V3PoolDecoder) decodeCollect(log model.LogRecord) (model.CollectEventData, error) {
-	event := d.poolABI.Events["Collect"]
+func decodeCollect(event abi.Event, log model.LogRecord) (model.CollectEventData, error) {
 	indexedTopics, err := parseIndexedTopics(event, log.Topics)
 	if err != nil {
 		return model.CollectEventData{}, err
@@ -418,6 +486,159 @@ func (d *V3PoolDecoder) decodeCollect(log model.LogRecord) (model.CollectEventDa
 	}, nil
 }
 
+func decodeInitialize(event abi.Event, log model.LogRecord) (model.InitializeEventData, error) {
+	if len(log.Topics) != 1 {
+		return model.InitializeEventData{}, fmt.Errorf("expected 1 topics, got %d", len(log.Topics))
+	}
+	values, err := unpackNonIndexed(event, log.Data)
+	if err != nil {
+		return model.InitializeEventData{}, err
+	}
+	if len(values) != 2 {
+		return model.InitializeEventData{}, fmt.Errorf("unexpected initialize values: %d", len(values))
+	}
+
+	sqrtPrice, err := asBigInt(values[0])
+	if err != nil {
+		return model.InitializeEventData{}, err
+	}
+	tickInt, err := asBigInt(values[1])
+	if err != nil {
+		return model.InitializeEventData{}, err
+	}
+	tick, err := int24FromBig(tickInt)
+	if err != nil {
+		return model.InitializeEventData{}, err
+	}
+
+	return model.InitializeEventData{
+		SqrtPriceX96: sqrtPrice.String(),
+		Tick:         tick,
+	}, nil
+}
+
+func decodeFlash(event abi.Event, log model.LogRecord) (model.FlashEventData, error) {
+	indexedTopics, err := parseIndexedTopics(event, log.Topics)
+	if err != nil {
+		return model.FlashEventData{}, err
+	}
+
+	var indexed struct {
+		Sender    common.Address
+		Recipient common.Address
+	}
+	if err := abi.ParseTopics(&indexed, indexedArguments(event.Inputs), indexedTopics); err != nil {
+		return model.FlashEventData{}, fmt.Errorf("parse topics: %w", err)
+	}
+
+	amounts, err := unpackAmounts(event, log.Data, 4)
+	if err != nil {
+		return model.FlashEventData{}, err
+	}
+
+	return model.FlashEventData{
+		Sender:    indexed.Sender.Hex(),
+		Recipient: indexed.Recipient.Hex(),
+		Amount0:   amounts[0],
+		Amount1:   amounts[1],
+		Paid0:     amounts[2],
+		Paid1:     amounts[3],
+	}, nil
+}
+
+func decodeCollectProtocol(event abi.Event, log model.LogRecord) (model.CollectProtocolEventData, error) {
+	indexedTopics, err := parseIndexedTopics(event, log.Topics)
+	if err != nil {
+		return model.CollectProtocolEventData{}, err
+	}
+
+	var indexed struct {
+		Sender    common.Address
+		Recipient common.Address
+	}
+	if err := abi.ParseTopics(&indexed, indexedArguments(event.Inputs), indexedTopics); err != nil {
+		return model.CollectProtocolEventData{}, fmt.Errorf("parse topics: %w", err)
+	}
+
+	amounts, err := unpackAmounts(event, log.Data, 2)
+	if err != nil {
+		return model.CollectProtocolEventData{}, err
+	}
+
+	return model.CollectProtocolEventData{
+		Sender:    indexed.Sender.Hex(),
+		Recipient: indexed.Recipient.Hex(),
+		Amount0:   amounts[0],
+		Amount1:   amounts[1],
+	}, nil
+}
+
+// pancakeProtocolFeeDenominator is the unit of PancakeSwap V3 fee protocols.
+const pancakeProtocolFeeDenominator = 10000
+
+// decodeSetFeeProtocol decodes the Uniswap V3 SetFeeProtocol and the
+// PancakeSwap V3 one, whose values are uint32 fractions of
+// pancakeProtocolFeeDenominator.
+func decodeSetFeeProtocol(event abi.Event, log model.LogRecord) (model.SetFeeProtocolEventData, error) {
+	if len(log.Topics) != 1 {
+		return model.SetFeeProtocolEventData{}, fmt.Errorf("expected 1 topics, got %d", len(log.Topics))
+	}
+	values, err := unpackNonIndexed(event, log.Data)
+	if err != nil {
+		return model.SetFeeProtocolEventData{}, err
+	}
+	if len(values) != 4 {
+		return model.SetFeeProtocolEventData{}, fmt.Errorf("unexpected setfeeprotocol values: %d", len(values))
+	}
+
+	protocols := make([]uint32, 0, len(values))
+	for _, value := range values {
+		protocol, err := asBigInt(value)
+		if err != nil {
+			return model.SetFeeProtocolEventData{}, err
+		}
+		protocols = append(protocols, uint32(protocol.Uint64()))
+	}
+
+	data := model.SetFeeProtocolEventData{
+		FeeProtocol0Old: protocols[0],
+		FeeProtocol1Old: protocols[1],
+		FeeProtocol0New: protocols[2],
+		FeeProtocol1New: protocols[3],
+	}
+	if event.Inputs[0].Type.Size == 32 {
+		data.Denominator = pancakeProtocolFeeDenominator
+	}
+	return data, nil
+}
+
+func decodeIncreaseObservationCardinalityNext(event abi.Event, log model.LogRecord) (model.IncreaseObservationCardinalityNextEventData, error) {
+	if len(log.Topics) != 1 {
+		return model.IncreaseObservationCardinalityNextEventData{}, fmt.Errorf("expected 1 topics, got %d", len(log.Topics))
+	}
+	values, err := unpackNonIndexed(event, log.Data)
+	if err != nil {
+		return model.IncreaseObservationCardinalityNextEventData{}, err
+	}
+	if len(values) != 2 {
+		return model.IncreaseObservationCardinalityNextEventData{}, fmt.Errorf("unexpected increaseobservationcardinalitynext values: %d", len(values))
+	}
+
+	cardinalityOld, err := asBigInt(values[0])
+	if err != nil {
+		return model.IncreaseObservationCardinalityNextEventData{}, err
+	}
+	cardinalityNew, err := asBigInt(values[1])
+	if err != nil {
+		return model.IncreaseObservationCardinalityNextEventData{}, err
+	}
+
+	return model.IncreaseObservationCardinalityNextEventData{
+		ObservationCardinalityNextOld: uint16(cardinalityOld.Uint64()),
+		ObservationCardinalityNextNew: uint16(cardinalityNew.Uint64()),
+	}, nil
+}
+
 func parseIndexedTopics(event abi.Event, topics []string) ([]common.Hash, error) {
 	indexedCount := len(indexedArguments(event.Inputs))
 	if len(topics) != indexedCount+1 {
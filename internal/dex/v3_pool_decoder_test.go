@@ -79,6 +79,59 @@ func TestV3PoolDecoderSwap(t *testing.T) {
 	}
 }
 
+func TestV3PoolDecoderPancakeSwap(t *testing.T) {
+	pancakeABI, err := PancakeV3PoolABI()
+	if err != nil {
+		t.Fatalf("abi parse: %v", err)
+	}
+	swapEvent := pancakeABI.Events["Swap"]
+
+	pool := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	poolMetaCache := NewPoolMetaCache()
+	poolMetaCache.Set(pool, model.PoolMeta{Fee: 2500, TickSpacing: 50})
+
+	// Mapping the native topic0 to Swap must keep the Pancake layout.
+	decoder, err := NewV3PoolDecoder(DecoderConfig{Topic0Map: map[string]string{swapEvent.ID.Hex(): "swap"}})
+	if err != nil {
+		t.Fatalf("decoder: %v", err)
+	}
+	if !decoder.CanDecode(swapEvent.ID.Hex()) {
+		t.Fatalf("pancake swap topic not supported")
+	}
+
+	data, err := swapEvent.Inputs.NonIndexed().Pack(
+		big.NewInt(-1000),
+		big.NewInt(2000),
+		big.NewInt(123456789),
+		big.NewInt(987654321),
+		big.NewInt(-15),
+		big.NewInt(0),
+		big.NewInt(3),
+	)
+	if err != nil {
+		t.Fatalf("pack swap: %v", err)
+	}
+	logRecord := buildLogRecord(pool, swapEvent.ID, data, []common.Hash{
+		topicFromAddress(common.HexToAddress("0x2222222222222222222222222222222222222222")),
+		topicFromAddress(common.HexToAddress("0x3333333333333333333333333333333333333333")),
+	})
+
+	event, err := decoder.Decode(logRecord, DecodeContext{PoolMetaCache: poolMetaCache, Logger: zap.NewNop()})
+	if err != nil {
+		t.Fatalf("decode swap: %v", err)
+	}
+	swap, ok := event.Decoded.(model.SwapEventData)
+	if !ok {
+		t.Fatalf("decoded type mismatch")
+	}
+	if event.EventName != "Swap" || swap.Amount1 != "2000" || swap.Tick != -15 {
+		t.Fatalf("swap mismatch: %+v", swap)
+	}
+	if swap.ProtocolFeesToken0 != "0" || swap.ProtocolFeesToken1 != "3" {
+		t.Fatalf("protocol fees mismatch: %+v", swap)
+	}
+}
+
 func TestV3PoolDecoderMintBurnCollect(t *testing.T) {
 	poolABI, err := V3PoolABI()
 	if err != nil {
@@ -228,3 +281,101 @@ func topicFromInt24(value int32) common.Hash {
 	}
 	return common.BigToHash(bigVal)
 }
+
+func TestV3PoolDecoderPoolEvents(t *testing.T) {
+	poolABI, err := V3PoolABI()
+	if err != nil {
+		t.Fatalf("abi parse: %v", err)
+	}
+	pancakeABI, err := PancakeV3PoolABI()
+	if err != nil {
+		t.Fatalf("pancake abi parse: %v", err)
+	}
+
+	pool := common.HexToAddress("0x9999999999999999999999999999999999999999")
+	poolMetaCache := NewPoolMetaCache()
+	poolMetaCache.Set(pool, model.PoolMeta{
+		Token0:      "0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		Token1:      "0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+		Fee:         500,
+		TickSpacing: 10,
+	})
+
+	flashTopic := common.HexToHash("0x1234")
+	decoder, err := NewV3PoolDecoder(DecoderConfig{Topic0Map: map[string]string{flashTopic.Hex(): "flash"}})
+	if err != nil {
+		t.Fatalf("decoder: %v", err)
+	}
+
+	ctx := DecodeContext{
+		PoolMetaCache: poolMetaCache,
+		Logger:        zap.NewNop(),
+	}
+
+	initData, err := poolABI.Events["Initialize"].Inputs.NonIndexed().Pack(new(big.Int).Lsh(big.NewInt(1), 96), big.NewInt(-5))
+	if err != nil {
+		t.Fatalf("pack initialize: %v", err)
+	}
+	initEvent, err := decoder.Decode(buildLogRecord(pool, poolABI.Events["Initialize"].ID, initData, nil), ctx)
+	if err != nil {
+		t.Fatalf("decode initialize: %v", err)
+	}
+	initialize, ok := initEvent.Decoded.(model.InitializeEventData)
+	if !ok || initialize.Tick != -5 || initialize.SqrtPriceX96 != "79228162514264337593543950336" {
+		t.Fatalf("initialize mismatch: %+v", initEvent.Decoded)
+	}
+
+	sender := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	recipient := common.HexToAddress("0xcccccccccccccccccccccccccccccccccccccccc")
+	flashData, err := poolABI.Events["Flash"].Inputs.NonIndexed().Pack(
+		big.NewInt(1000000),
+		big.NewInt(0),
+		big.NewInt(500),
+		big.NewInt(0),
+	)
+	if err != nil {
+		t.Fatalf("pack flash: %v", err)
+	}
+	for _, topic0 := range []common.Hash{poolABI.Events["Flash"].ID, flashTopic} {
+		flashEvent, err := decoder.Decode(buildLogRecord(pool, topic0, flashData, []common.Hash{
+			topicFromAddress(sender),
+			topicFromAddress(recipient),
+		}), ctx)
+		if err != nil {
+			t.Fatalf("decode flash: %v", err)
+		}
+		flash, ok := flashEvent.Decoded.(model.FlashEventData)
+		if !ok || flashEvent.EventName != "Flash" {
+			t.Fatalf("flash type mismatch: %s %T", flashEvent.EventName, flashEvent.Decoded)
+		}
+		if flash.Amount0 != "1000000" || flash.Paid0 != "500" || flash.Recipient != recipient.Hex() {
+			t.Fatalf("flash mismatch: %+v", flash)
+		}
+	}
+
+	feeProtocolData, err := pancakeABI.Events["SetFeeProtocol"].Inputs.NonIndexed().Pack(uint32(0), uint32(0), uint32(3200), uint32(3200))
+	if err != nil {
+		t.Fatalf("pack set fee protocol: %v", err)
+	}
+	feeProtocolEvent, err := decoder.Decode(buildLogRecord(pool, pancakeABI.Events["SetFeeProtocol"].ID, feeProtocolData, nil), ctx)
+	if err != nil {
+		t.Fatalf("decode set fee protocol: %v", err)
+	}
+	feeProtocol, ok := feeProtocolEvent.Decoded.(model.SetFeeProtocolEventData)
+	if !ok || feeProtocol.FeeProtocol0New != 3200 || feeProtocol.FeeProtocol1Old != 0 || feeProtocol.Denominator != 10000 {
+		t.Fatalf("set fee protocol mismatch: %+v", feeProtocolEvent.Decoded)
+	}
+
+	cardinalityData, err := poolABI.Events["IncreaseObservationCardinalityNext"].Inputs.NonIndexed().Pack(uint16(1), uint16(100))
+	if err != nil {
+		t.Fatalf("pack cardinality: %v", err)
+	}
+	cardinalityEvent, err := decoder.Decode(buildLogRecord(pool, poolABI.Events["IncreaseObservationCardinalityNext"].ID, cardinalityData, nil), ctx)
+	if err != nil {
+		t.Fatalf("decode cardinality: %v", err)
+	}
+	cardinality, ok := cardinalityEvent.Decoded.(model.IncreaseObservationCardinalityNextEventData)
+	if !ok || cardinality.ObservationCardinalityNextOld != 1 || cardinality.ObservationCardinalityNextNew != 100 {
+		t.Fatalf("cardinality mismatch: %+v", cardinalityEvent.Decoded)
+	}
+}
@@ -0,0 +1,84 @@
+package dex
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const v4PoolManagerABIJSON = `[
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": true, "internalType": "PoolId", "name": "id", "type": "bytes32"},
+      {"indexed": true, "internalType": "Currency", "name": "currency0", "type": "address"},
+      {"indexed": true, "internalType": "Currency", "name": "currency1", "type": "address"},
+      {"indexed": false, "internalType": "uint24", "name": "fee", "type": "uint24"},
+      {"indexed": false, "internalType": "int24", "name": "tickSpacing", "type": "int24"},
+      {"indexed": false, "internalType": "contract IHooks", "name": "hooks", "type": "address"},
+      {"indexed": false, "internalType": "uint160", "name": "sqrtPriceX96", "type": "uint160"},
+      {"indexed": false, "internalType": "int24", "name": "tick", "type": "int24"}
+    ],
+    "name": "Initialize",
+    "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": true, "internalType": "PoolId", "name": "id", "type": "bytes32"},
+      {"indexed": true, "internalType": "address", "name": "sender", "type": "address"},
+      {"indexed": false, "internalType": "int24", "name": "tickLower", "type": "int24"},
+      {"indexed": false, "internalType": "int24", "name": "tickUpper", "type": "int24"},
+      {"indexed": false, "internalType": "int256", "name": "liquidityDelta", "type": "int256"},
+      {"indexed": false, "internalType": "bytes32", "name": "salt", "type": "bytes32"}
+    ],
+    "name": "ModifyLiquidity",
+    "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": true, "internalType": "PoolId", "name": "id", "type": "bytes32"},
+      {"indexed": true, "internalType": "address", "name": "sender", "type": "address"},
+      {"indexed": false, "internalType": "int128", "name": "amount0", "type": "int128"},
+      {"indexed": false, "internalType": "int128", "name": "amount1", "type": "int128"},
+      {"indexed": false, "internalType": "uint160", "name": "sqrtPriceX96", "type": "uint160"},
+      {"indexed": false, "internalType": "uint128", "name": "liquidity", "type": "uint128"},
+      {"indexed": false, "internalType": "int24", "name": "tick", "type": "int24"},
+      {"indexed": false, "internalType": "uint24", "name": "fee", "type": "uint24"}
+    ],
+    "name": "Swap",
+    "type": "event"
+  }
+]`
+
+var (
+	v4PoolManagerABI     abi.ABI
+	v4PoolManagerABIOnce sync.Once
+	v4PoolManagerABIErr  error
+)
+
+// V4PoolManagerABI returns the parsed V4 PoolManager ABI.
+func V4PoolManagerABI() (abi.ABI, error) {
+	v4PoolManagerABIOnce.Do(func() {
+		v4PoolManagerABI, v4PoolManagerABIErr = abi.JSON(strings.NewReader(v4PoolManagerABIJSON))
+	})
+	return v4PoolManagerABI, v4PoolManagerABIErr
+}
+
+// V4PoolManagerEventTopics returns the topic0 of the Initialize,
+// ModifyLiquidity and Swap events.
+func V4PoolManagerEventTopics() ([]common.Hash, error) {
+	managerABI, err := V4PoolManagerABI()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{"Initialize", "ModifyLiquidity", "Swap"}
+	topics := make([]common.Hash, 0, len(names))
+	for _, name := range names {
+		topics = append(topics, managerABI.Events[name].ID)
+	}
+	return topics, nil
+}
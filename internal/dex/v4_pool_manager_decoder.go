@@ -0,0 +1,350 @@
+package dex
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"liquidityScope/internal/model"
+)
+
+// UniswapV4PoolManager is the Uniswap V4 PoolManager on BSC.
+var UniswapV4PoolManager = common.HexToAddress("0x28e2Ea090877bF75740558f6BFB36A5ffeE9e9dF")
+
+// V4PoolManagerDecoder decodes Uniswap V4 PoolManager events. All V4 pools
+// emit from the PoolManager, so events are keyed by poolId and the pool key is
+// looked up in a registry filled from Initialize events. Only events from the
+// configured PoolManagers are accepted, since any contract can emit the same
+// topics.
+type V4PoolManagerDecoder struct {
+	events   map[string]poolEvent
+	registry *V4PoolRegistry
+	managers map[common.Address]struct{}
+
+	// orphaned holds the keys unregistered by reorged-out Initialize logs, so
+	// the tombstones of the pool's other logs still resolve.
+	mu       sync.Mutex
+	orphaned map[v4PoolRef]model.V4PoolKey
+}
+
+// NewV4PoolManagerDecoder builds a V4 decoder for events of managers that
+// registers pools in registry.
+func NewV4PoolManagerDecoder(registry *V4PoolRegistry, managers []common.Address) (*V4PoolManagerDecoder, error) {
+	if registry == nil {
+		return nil, fmt.Errorf("v4 pool registry is nil")
+	}
+	if len(managers) == 0 {
+		return nil, fmt.Errorf("at least one v4 pool manager is required")
+	}
+	managerABI, err := V4PoolManagerABI()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(map[string]poolEvent)
+	for _, name := range []string{"Initialize", "ModifyLiquidity", "Swap"} {
+		event := managerABI.Events[name]
+		events[strings.ToLower(event.ID.Hex())] = poolEvent{name: name, event: event}
+	}
+	allowed := make(map[common.Address]struct{}, len(managers))
+	for _, manager := range managers {
+		allowed[manager] = struct{}{}
+	}
+	return &V4PoolManagerDecoder{events: events, registry: registry, managers: allowed, orphaned: make(map[v4PoolRef]model.V4PoolKey)}, nil
+}
+
+// CanDecode checks if the topic0 is supported.
+func (d *V4PoolManagerDecoder) CanDecode(topic0 string) bool {
+	if topic0 == "" {
+		return false
+	}
+	_, ok := d.events[strings.ToLower(topic0)]
+	return ok
+}
+
+// Decode converts a LogRecord into a TypedEvent keyed by poolId. Initialize
+// registers the pool key and its tombstone unregisters it; other events of a
+// pool whose Initialize was never decoded fail.
+func (d *V4PoolManagerDecoder) Decode(log model.LogRecord, ctx DecodeContext) (*model.TypedEvent, error) {
+	if len(log.Topics) < 2 {
+		return nil, fmt.Errorf("expected pool id topic, got %d topics", len(log.Topics))
+	}
+	variant, ok := d.events[strings.ToLower(log.Topics[0])]
+	if !ok {
+		return nil, fmt.Errorf("unsupported topic0: %s", log.Topics[0])
+	}
+
+	if !common.IsHexAddress(log.Address) {
+		return nil, fmt.Errorf("invalid pool manager address: %s", log.Address)
+	}
+	manager := common.HexToAddress(log.Address)
+	if _, ok := d.managers[manager]; !ok {
+		return nil, fmt.Errorf("emitter %s is not a configured v4 pool manager", manager.Hex())
+	}
+	ids, err := parseTopicHashes(log.Topics[1:2])
+	if err != nil {
+		return nil, err
+	}
+	id := ids[0]
+	ref := v4PoolRef{manager: manager, id: id}
+
+	if variant.name == "Initialize" {
+		decoded, key, err := decodeV4Initialize(variant.event, log)
+		if err != nil {
+			return nil, err
+		}
+		if v4PoolID(key) != id {
+			return nil, fmt.Errorf("v4 pool id %s does not match its key", id.Hex())
+		}
+		if log.Removed {
+			if removed, ok := d.registry.Remove(key); ok {
+				d.mu.Lock()
+				d.orphaned[ref] = removed
+				d.mu.Unlock()
+			}
+			return buildV4TypedEvent(log, variant.name, decoded, key), nil
+		}
+		// Re-initializing a pool after a reorg registers the same key: the
+		// poolId is its hash.
+		if _, err := d.registry.Add(key); err != nil {
+			return nil, err
+		}
+		d.mu.Lock()
+		delete(d.orphaned, ref)
+		d.mu.Unlock()
+		d.fillCurrencies(ctx, key)
+		return buildV4TypedEvent(log, variant.name, decoded, key), nil
+	}
+
+	key, ok := d.registry.Get(manager, id)
+	if !ok && log.Removed {
+		d.mu.Lock()
+		key, ok = d.orphaned[ref]
+		d.mu.Unlock()
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown v4 pool id %s: Initialize not decoded", id.Hex())
+	}
+
+	var decoded interface{}
+	switch variant.name {
+	case "ModifyLiquidity":
+		decoded, err = decodeV4ModifyLiquidity(variant.event, log)
+	case "Swap":
+		decoded, err = decodeV4Swap(variant.event, log)
+	default:
+		return nil, fmt.Errorf("unsupported event name: %s", variant.name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buildV4TypedEvent(log, variant.name, decoded, key), nil
+}
+
+// fillCurrencies caches the token metadata of the pool currencies.
+func (d *V4PoolManagerDecoder) fillCurrencies(ctx DecodeContext, key model.V4PoolKey) {
+	if ctx.Chain == nil {
+		return
+	}
+	callCtx := ctx.Context
+	if callCtx == nil {
+		callCtx = context.Background()
+	}
+	tokens := []common.Address{common.HexToAddress(key.Currency0), common.HexToAddress(key.Currency1)}
+	fillTokenCache(callCtx, ctx.Chain, ctx.TokenMetaCache, tokens, ctx.Logger)
+}
+
+func buildV4TypedEvent(log model.LogRecord, name string, decoded interface{}, key model.V4PoolKey) *model.TypedEvent {
+	event := buildTypedEvent(log, name, decoded, key.Meta())
+	event.PoolID = key.PoolID
+	return event
+}
+
+func decodeV4Initialize(event abi.Event, log model.LogRecord) (model.V4InitializeEventData, model.V4PoolKey, error) {
+	indexedTopics, err := parseIndexedTopics(event, log.Topics)
+	if err != nil {
+		return model.V4InitializeEventData{}, model.V4PoolKey{}, err
+	}
+
+	var indexed struct {
+		Id        [32]byte
+		Currency0 common.Address
+		Currency1 common.Address
+	}
+	if err := abi.ParseTopics(&indexed, indexedArguments(event.Inputs), indexedTopics); err != nil {
+		return model.V4InitializeEventData{}, model.V4PoolKey{}, fmt.Errorf("parse topics: %w", err)
+	}
+
+	values, err := unpackNonIndexed(event, log.Data)
+	if err != nil {
+		return model.V4InitializeEventData{}, model.V4PoolKey{}, err
+	}
+	if len(values) != 5 {
+		return model.V4InitializeEventData{}, model.V4PoolKey{}, fmt.Errorf("unexpected initialize values: %d", len(values))
+	}
+
+	fee, err := asBigInt(values[0])
+	if err != nil {
+		return model.V4InitializeEventData{}, model.V4PoolKey{}, err
+	}
+	tickSpacingInt, err := asBigInt(values[1])
+	if err != nil {
+		return model.V4InitializeEventData{}, model.V4PoolKey{}, err
+	}
+	tickSpacing, err := int24FromBig(tickSpacingInt)
+	if err != nil {
+		return model.V4InitializeEventData{}, model.V4PoolKey{}, err
+	}
+	hooks, err := asAddress(values[2])
+	if err != nil {
+		return model.V4InitializeEventData{}, model.V4PoolKey{}, err
+	}
+	sqrtPrice, err := asBigInt(values[3])
+	if err != nil {
+		return model.V4InitializeEventData{}, model.V4PoolKey{}, err
+	}
+	tickInt, err := asBigInt(values[4])
+	if err != nil {
+		return model.V4InitializeEventData{}, model.V4PoolKey{}, err
+	}
+	tick, err := int24FromBig(tickInt)
+	if err != nil {
+		return model.V4InitializeEventData{}, model.V4PoolKey{}, err
+	}
+
+	key := model.V4PoolKey{
+		PoolID:           common.Hash(indexed.Id).Hex(),
+		Manager:          common.HexToAddress(log.Address).Hex(),
+		Currency0:        indexed.Currency0.Hex(),
+		Currency1:        indexed.Currency1.Hex(),
+		Fee:              uint32(fee.Uint64()),
+		TickSpacing:      tickSpacing,
+		Hooks:            hooks.Hex(),
+		InitializedBlock: log.BlockNumber,
+		TxHash:           log.TxHash,
+	}
+	return model.V4InitializeEventData{
+		Currency0:    key.Currency0,
+		Currency1:    key.Currency1,
+		Fee:          key.Fee,
+		TickSpacing:  key.TickSpacing,
+		Hooks:        key.Hooks,
+		SqrtPriceX96: sqrtPrice.String(),
+		Tick:         tick,
+	}, key, nil
+}
+
+func decodeV4ModifyLiquidity(event abi.Event, log model.LogRecord) (model.V4ModifyLiquidityEventData, error) {
+	indexedTopics, err := parseIndexedTopics(event, log.Topics)
+	if err != nil {
+		return model.V4ModifyLiquidityEventData{}, err
+	}
+
+	var indexed struct {
+		Id     [32]byte
+		Sender common.Address
+	}
+	if err := abi.ParseTopics(&indexed, indexedArguments(event.Inputs), indexedTopics); err != nil {
+		return model.V4ModifyLiquidityEventData{}, fmt.Errorf("parse topics: %w", err)
+	}
+
+	values, err := unpackNonIndexed(event, log.Data)
+	if err != nil {
+		return model.V4ModifyLiquidityEventData{}, err
+	}
+	if len(values) != 4 {
+		return model.V4ModifyLiquidityEventData{}, fmt.Errorf("unexpected modifyliquidity values: %d", len(values))
+	}
+
+	tickLowerInt, err := asBigInt(values[0])
+	if err != nil {
+		return model.V4ModifyLiquidityEventData{}, err
+	}
+	tickLower, err := int24FromBig(tickLowerInt)
+	if err != nil {
+		return model.V4ModifyLiquidityEventData{}, err
+	}
+	tickUpperInt, err := asBigInt(values[1])
+	if err != nil {
+		return model.V4ModifyLiquidityEventData{}, err
+	}
+	tickUpper, err := int24FromBig(tickUpperInt)
+	if err != nil {
+		return model.V4ModifyLiquidityEventData{}, err
+	}
+	liquidityDelta, err := asBigInt(values[2])
+	if err != nil {
+		return model.V4ModifyLiquidityEventData{}, err
+	}
+	salt, ok := values[3].([32]byte)
+	if !ok {
+		return model.V4ModifyLiquidityEventData{}, fmt.Errorf("unsupported salt type %T", values[3])
+	}
+
+	return model.V4ModifyLiquidityEventData{
+		Sender:         indexed.Sender.Hex(),
+		TickLower:      tickLower,
+		TickUpper:      tickUpper,
+		LiquidityDelta: liquidityDelta.String(),
+		Salt:           hexutil.Encode(salt[:]),
+	}, nil
+}
+
+func decodeV4Swap(event abi.Event, log model.LogRecord) (model.V4SwapEventData, error) {
+	indexedTopics, err := parseIndexedTopics(event, log.Topics)
+	if err != nil {
+		return model.V4SwapEventData{}, err
+	}
+
+	var indexed struct {
+		Id     [32]byte
+		Sender common.Address
+	}
+	if err := abi.ParseTopics(&indexed, indexedArguments(event.Inputs), indexedTopics); err != nil {
+		return model.V4SwapEventData{}, fmt.Errorf("parse topics: %w", err)
+	}
+
+	values, err := unpackNonIndexed(event, log.Data)
+	if err != nil {
+		return model.V4SwapEventData{}, err
+	}
+	if len(values) != 6 {
+		return model.V4SwapEventData{}, fmt.Errorf("unexpected swap values: %d", len(values))
+	}
+
+	ints := make([]string, 0, 4)
+	for _, value := range values[:4] {
+		amount, err := asBigInt(value)
+		if err != nil {
+			return model.V4SwapEventData{}, err
+		}
+		ints = append(ints, amount.String())
+	}
+	tickInt, err := asBigInt(values[4])
+	if err != nil {
+		return model.V4SwapEventData{}, err
+	}
+	tick, err := int24FromBig(tickInt)
+	if err != nil {
+		return model.V4SwapEventData{}, err
+	}
+	fee, err := asBigInt(values[5])
+	if err != nil {
+		return model.V4SwapEventData{}, err
+	}
+
+	return model.V4SwapEventData{
+		Sender:       indexed.Sender.Hex(),
+		Amount0:      ints[0],
+		Amount1:      ints[1],
+		SqrtPriceX96: ints[2],
+		Liquidity:    ints[3],
+		Tick:         tick,
+		Fee:          uint32(fee.Uint64()),
+	}, nil
+}
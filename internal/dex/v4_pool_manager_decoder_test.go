@@ -0,0 +1,272 @@
+package dex
+
+import (
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+
+	"liquidityScope/internal/model"
+)
+
+func TestV4PoolManagerDecoderInitializeSwap(t *testing.T) {
+	managerABI, err := V4PoolManagerABI()
+	if err != nil {
+		t.Fatalf("abi parse: %v", err)
+	}
+
+	registryPath := filepath.Join(t.TempDir(), "v4_pools.json")
+	registry, err := LoadV4PoolRegistry(registryPath)
+	if err != nil {
+		t.Fatalf("load registry: %v", err)
+	}
+	manager := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	decoder, err := NewV4PoolManagerDecoder(registry, []common.Address{manager})
+	if err != nil {
+		t.Fatalf("v4 decoder: %v", err)
+	}
+	ctx := DecodeContext{Logger: zap.NewNop()}
+
+	token := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	hooks := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	poolID := v4PoolID(model.V4PoolKey{Currency0: NativeCurrency.Hex(), Currency1: token.Hex(), Fee: model.V4DynamicFeeFlag, TickSpacing: 60, Hooks: hooks.Hex()})
+	sender := common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	swapData, err := managerABI.Events["Swap"].Inputs.NonIndexed().Pack(
+		big.NewInt(-1000),
+		big.NewInt(1990),
+		new(big.Int).Lsh(big.NewInt(1), 96),
+		big.NewInt(5000),
+		big.NewInt(-7),
+		big.NewInt(3000),
+	)
+	if err != nil {
+		t.Fatalf("pack swap: %v", err)
+	}
+	swapLog := buildLogRecord(manager, managerABI.Events["Swap"].ID, swapData, []common.Hash{poolID, topicFromAddress(sender)})
+
+	if _, err := decoder.Decode(swapLog, ctx); err == nil || !strings.Contains(err.Error(), "unknown v4 pool id") {
+		t.Fatalf("expected unknown pool id error, got %v", err)
+	}
+
+	initData, err := managerABI.Events["Initialize"].Inputs.NonIndexed().Pack(
+		big.NewInt(int64(model.V4DynamicFeeFlag)),
+		big.NewInt(60),
+		hooks,
+		new(big.Int).Lsh(big.NewInt(1), 96),
+		big.NewInt(0),
+	)
+	if err != nil {
+		t.Fatalf("pack initialize: %v", err)
+	}
+	initLog := buildLogRecord(manager, managerABI.Events["Initialize"].ID, initData, []common.Hash{
+		poolID,
+		topicFromAddress(NativeCurrency),
+		topicFromAddress(token),
+	})
+
+	initEvent, err := decoder.Decode(initLog, ctx)
+	if err != nil {
+		t.Fatalf("decode initialize: %v", err)
+	}
+	initialize, ok := initEvent.Decoded.(model.V4InitializeEventData)
+	if !ok {
+		t.Fatalf("initialize type mismatch: %T", initEvent.Decoded)
+	}
+	if initialize.Fee != model.V4DynamicFeeFlag || initialize.TickSpacing != 60 || initialize.Hooks != hooks.Hex() {
+		t.Fatalf("initialize mismatch: %+v", initialize)
+	}
+	if initEvent.PoolID != poolID.Hex() {
+		t.Fatalf("pool id mismatch: %s", initEvent.PoolID)
+	}
+
+	swapEvent, err := decoder.Decode(swapLog, ctx)
+	if err != nil {
+		t.Fatalf("decode swap: %v", err)
+	}
+	swap, ok := swapEvent.Decoded.(model.V4SwapEventData)
+	if !ok {
+		t.Fatalf("swap type mismatch: %T", swapEvent.Decoded)
+	}
+	if swap.Amount0 != "-1000" || swap.Amount1 != "1990" || swap.Tick != -7 || swap.Fee != 3000 {
+		t.Fatalf("swap mismatch: %+v", swap)
+	}
+	meta := swapEvent.PoolMeta
+	if swapEvent.PoolID != poolID.Hex() || meta.Protocol != model.ProtocolV4 || !meta.DynamicFee || meta.Fee != 0 {
+		t.Fatalf("swap pool mismatch: %s %+v", swapEvent.PoolID, meta)
+	}
+	if meta.Token0 != NativeCurrency.Hex() || meta.Token1 != token.Hex() {
+		t.Fatalf("swap currencies mismatch: %+v", meta)
+	}
+
+	if err := registry.Save(); err != nil {
+		t.Fatalf("save registry: %v", err)
+	}
+	reloaded, err := LoadV4PoolRegistry(registryPath)
+	if err != nil {
+		t.Fatalf("reload registry: %v", err)
+	}
+	key, ok := reloaded.Get(manager, poolID)
+	if !ok || !key.DynamicFee() || key.Manager != manager.Hex() {
+		t.Fatalf("reloaded key mismatch: %+v", key)
+	}
+}
+
+func TestV4PoolManagerDecoderRejectsSpoofedInitialize(t *testing.T) {
+	managerABI, err := V4PoolManagerABI()
+	if err != nil {
+		t.Fatalf("abi parse: %v", err)
+	}
+	registry, err := LoadV4PoolRegistry("")
+	if err != nil {
+		t.Fatalf("load registry: %v", err)
+	}
+	manager := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	decoder, err := NewV4PoolManagerDecoder(registry, []common.Address{manager})
+	if err != nil {
+		t.Fatalf("v4 decoder: %v", err)
+	}
+	ctx := DecodeContext{Logger: zap.NewNop()}
+
+	token := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	poolID := v4PoolID(model.V4PoolKey{Currency0: NativeCurrency.Hex(), Currency1: token.Hex(), Fee: 3000, TickSpacing: 60, Hooks: common.Address{}.Hex()})
+	initialize := func(emitter common.Address, fee int64) model.LogRecord {
+		data, err := managerABI.Events["Initialize"].Inputs.NonIndexed().Pack(
+			big.NewInt(fee),
+			big.NewInt(60),
+			common.Address{},
+			new(big.Int).Lsh(big.NewInt(1), 96),
+			big.NewInt(0),
+		)
+		if err != nil {
+			t.Fatalf("pack initialize: %v", err)
+		}
+		return buildLogRecord(emitter, managerABI.Events["Initialize"].ID, data, []common.Hash{
+			poolID,
+			topicFromAddress(NativeCurrency),
+			topicFromAddress(token),
+		})
+	}
+
+	if _, err := decoder.Decode(initialize(manager, 3000), ctx); err != nil {
+		t.Fatalf("decode initialize: %v", err)
+	}
+
+	spoofer := common.HexToAddress("0x7777777777777777777777777777777777777777")
+	if _, err := decoder.Decode(initialize(spoofer, 100), ctx); err == nil {
+		t.Fatalf("expected initialize from an unknown emitter to be rejected")
+	}
+	if _, err := decoder.Decode(initialize(manager, 100), ctx); err == nil {
+		t.Fatalf("expected a key that does not hash to the pool id to be rejected")
+	}
+
+	key, ok := registry.Get(manager, poolID)
+	if !ok || key.Fee != 3000 {
+		t.Fatalf("registered key must be kept, got %+v", key)
+	}
+	if _, ok := registry.Get(spoofer, poolID); ok {
+		t.Fatalf("spoofed emitter must not register pools")
+	}
+}
+
+func TestV4PoolID(t *testing.T) {
+	// The ETH/USDC 0.05% pool of the Ethereum mainnet PoolManager.
+	key := model.V4PoolKey{
+		Currency0:   common.Address{}.Hex(),
+		Currency1:   "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48",
+		Fee:         500,
+		TickSpacing: 10,
+		Hooks:       common.Address{}.Hex(),
+	}
+	want := common.HexToHash("0x21c67e77068de97969ba93d4aab21826d33ca12bb9f565d8496e8fda8a82ca27")
+	if got := v4PoolID(key); got != want {
+		t.Fatalf("pool id mismatch: %s", got.Hex())
+	}
+}
+
+func TestV4PoolManagerDecoderRemovedInitialize(t *testing.T) {
+	managerABI, err := V4PoolManagerABI()
+	if err != nil {
+		t.Fatalf("abi parse: %v", err)
+	}
+	registry, err := LoadV4PoolRegistry("")
+	if err != nil {
+		t.Fatalf("load registry: %v", err)
+	}
+	manager := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	decoder, err := NewV4PoolManagerDecoder(registry, []common.Address{manager})
+	if err != nil {
+		t.Fatalf("v4 decoder: %v", err)
+	}
+	ctx := DecodeContext{Logger: zap.NewNop()}
+
+	token := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	poolID := v4PoolID(model.V4PoolKey{Currency0: NativeCurrency.Hex(), Currency1: token.Hex(), Fee: 3000, TickSpacing: 60, Hooks: common.Address{}.Hex()})
+	initData, err := managerABI.Events["Initialize"].Inputs.NonIndexed().Pack(
+		big.NewInt(3000),
+		big.NewInt(60),
+		common.Address{},
+		new(big.Int).Lsh(big.NewInt(1), 96),
+		big.NewInt(0),
+	)
+	if err != nil {
+		t.Fatalf("pack initialize: %v", err)
+	}
+	initLog := buildLogRecord(manager, managerABI.Events["Initialize"].ID, initData, []common.Hash{
+		poolID,
+		topicFromAddress(NativeCurrency),
+		topicFromAddress(token),
+	})
+	swapData, err := managerABI.Events["Swap"].Inputs.NonIndexed().Pack(
+		big.NewInt(-1000),
+		big.NewInt(1990),
+		new(big.Int).Lsh(big.NewInt(1), 96),
+		big.NewInt(5000),
+		big.NewInt(-7),
+		big.NewInt(3000),
+	)
+	if err != nil {
+		t.Fatalf("pack swap: %v", err)
+	}
+	swapLog := buildLogRecord(manager, managerABI.Events["Swap"].ID, swapData, []common.Hash{poolID, topicFromAddress(token)})
+
+	// A tombstone of an Initialize never registered leaves the registry empty.
+	removedInit := initLog
+	removedInit.Removed = true
+	if _, err := decoder.Decode(removedInit, ctx); err != nil {
+		t.Fatalf("decode removed initialize: %v", err)
+	}
+	if registry.Len() != 0 {
+		t.Fatalf("expected removed initialize not to register a key")
+	}
+
+	if _, err := decoder.Decode(initLog, ctx); err != nil {
+		t.Fatalf("decode initialize: %v", err)
+	}
+	if _, err := decoder.Decode(swapLog, ctx); err != nil {
+		t.Fatalf("decode swap: %v", err)
+	}
+
+	// The reorg orphans the Initialize and the swap after it.
+	if _, err := decoder.Decode(removedInit, ctx); err != nil {
+		t.Fatalf("decode removed initialize: %v", err)
+	}
+	if _, ok := registry.Get(manager, poolID); ok {
+		t.Fatalf("expected the orphaned key to be unregistered")
+	}
+	removedSwap := swapLog
+	removedSwap.Removed = true
+	event, err := decoder.Decode(removedSwap, ctx)
+	if err != nil {
+		t.Fatalf("decode removed swap: %v", err)
+	}
+	if event.PoolID != poolID.Hex() {
+		t.Fatalf("pool id mismatch: %s", event.PoolID)
+	}
+	if _, err := decoder.Decode(swapLog, ctx); err == nil {
+		t.Fatalf("expected a swap of an unregistered pool to fail")
+	}
+}
@@ -0,0 +1,196 @@
+package dex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"liquidityScope/internal/model"
+)
+
+// V4PoolRegistry maps V4 poolIds to the pool keys seen in Initialize events,
+// persisted as a JSON file. Entries are scoped to the PoolManager that
+// emitted them. An empty path keeps the registry in memory.
+type V4PoolRegistry struct {
+	path string
+	mu   sync.RWMutex
+	keys map[v4PoolRef]model.V4PoolKey
+}
+
+// v4PoolRef identifies a pool by its PoolManager and poolId.
+type v4PoolRef struct {
+	manager common.Address
+	id      common.Hash
+}
+
+type v4RegistryFile struct {
+	Pools []model.V4PoolKey `json:"pools"`
+}
+
+// LoadV4PoolRegistry reads the registry at path. A missing file yields an
+// empty registry that will be created on the first Save.
+func LoadV4PoolRegistry(path string) (*V4PoolRegistry, error) {
+	r := &V4PoolRegistry{path: path, keys: make(map[v4PoolRef]model.V4PoolKey)}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read v4 pool registry: %w", err)
+	}
+
+	var file v4RegistryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse v4 pool registry: %w", err)
+	}
+	for _, key := range file.Pools {
+		id, err := parseTopicHashes([]string{key.PoolID})
+		if err != nil {
+			return nil, fmt.Errorf("invalid pool id in v4 registry: %s", key.PoolID)
+		}
+		if !common.IsHexAddress(key.Manager) {
+			return nil, fmt.Errorf("invalid manager in v4 registry for pool %s: %q", key.PoolID, key.Manager)
+		}
+		if v4PoolID(key) != id[0] {
+			return nil, fmt.Errorf("pool id %s in v4 registry does not match its key", key.PoolID)
+		}
+		r.keys[v4PoolRef{manager: common.HexToAddress(key.Manager), id: id[0]}] = key
+	}
+	return r, nil
+}
+
+// Len returns the number of registered pools.
+func (r *V4PoolRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.keys)
+}
+
+// Get returns the pool key of a poolId under a PoolManager.
+func (r *V4PoolRegistry) Get(manager common.Address, id common.Hash) (model.V4PoolKey, bool) {
+	r.mu.RLock()
+	key, ok := r.keys[v4PoolRef{manager: manager, id: id}]
+	r.mu.RUnlock()
+	return key, ok
+}
+
+// Add registers a pool key and reports whether the registry changed. A poolId
+// is the hash of its key, so a key that does not hash to its poolId, or a
+// second key for a registered pool that differs in currencies, fee, tick
+// spacing or hooks, is rejected rather than replacing it. The same key seen
+// again, e.g. after a reorg, only moves its initialization to the new log.
+func (r *V4PoolRegistry) Add(key model.V4PoolKey) (bool, error) {
+	ref := v4PoolRef{manager: common.HexToAddress(key.Manager), id: common.HexToHash(key.PoolID)}
+	if v4PoolID(key) != ref.id {
+		return false, fmt.Errorf("v4 pool id %s of %s does not match its key", key.PoolID, key.Manager)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.keys[ref]
+	if ok && existing == key {
+		return false, nil
+	}
+	if ok && !sameV4Pool(existing, key) {
+		return false, fmt.Errorf("v4 pool %s of %s is already registered with a different key", key.PoolID, key.Manager)
+	}
+	r.keys[ref] = key
+	return true, nil
+}
+
+// Remove unregisters a pool key if it was registered by the same Initialize
+// log, e.g. when that log is reorged out, and returns the removed entry. A key
+// registered again from a later log is kept.
+func (r *V4PoolRegistry) Remove(key model.V4PoolKey) (model.V4PoolKey, bool) {
+	ref := v4PoolRef{manager: common.HexToAddress(key.Manager), id: common.HexToHash(key.PoolID)}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.keys[ref]
+	if !ok || existing.InitializedBlock != key.InitializedBlock || !strings.EqualFold(existing.TxHash, key.TxHash) {
+		return model.V4PoolKey{}, false
+	}
+	delete(r.keys, ref)
+	return existing, true
+}
+
+// v4PoolID returns the poolId of a key, keccak256(abi.encode(PoolKey)).
+func v4PoolID(key model.V4PoolKey) common.Hash {
+	encoded := make([]byte, 0, 5*32)
+	encoded = append(encoded, common.LeftPadBytes(common.HexToAddress(key.Currency0).Bytes(), 32)...)
+	encoded = append(encoded, common.LeftPadBytes(common.HexToAddress(key.Currency1).Bytes(), 32)...)
+	encoded = append(encoded, math.U256Bytes(new(big.Int).SetUint64(uint64(key.Fee)))...)
+	encoded = append(encoded, math.U256Bytes(big.NewInt(int64(key.TickSpacing)))...)
+	encoded = append(encoded, common.LeftPadBytes(common.HexToAddress(key.Hooks).Bytes(), 32)...)
+	return crypto.Keccak256Hash(encoded)
+}
+
+// sameV4Pool reports whether two keys describe the same pool.
+func sameV4Pool(a, b model.V4PoolKey) bool {
+	return common.HexToAddress(a.Currency0) == common.HexToAddress(b.Currency0) &&
+		common.HexToAddress(a.Currency1) == common.HexToAddress(b.Currency1) &&
+		a.Fee == b.Fee &&
+		a.TickSpacing == b.TickSpacing &&
+		common.HexToAddress(a.Hooks) == common.HexToAddress(b.Hooks)
+}
+
+// Keys returns all entries ordered by initialization block, poolId and
+// manager.
+func (r *V4PoolRegistry) Keys() []model.V4PoolKey {
+	r.mu.RLock()
+	keys := make([]model.V4PoolKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].InitializedBlock != keys[j].InitializedBlock {
+			return keys[i].InitializedBlock < keys[j].InitializedBlock
+		}
+		if !strings.EqualFold(keys[i].PoolID, keys[j].PoolID) {
+			return strings.ToLower(keys[i].PoolID) < strings.ToLower(keys[j].PoolID)
+		}
+		return strings.ToLower(keys[i].Manager) < strings.ToLower(keys[j].Manager)
+	})
+	return keys
+}
+
+// Save writes the registry atomically. In-memory registries are not saved.
+func (r *V4PoolRegistry) Save() error {
+	if r.path == "" {
+		return nil
+	}
+	dir := filepath.Dir(r.path)
+	if dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create v4 registry dir: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(v4RegistryFile{Pools: r.Keys()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal v4 pool registry: %w", err)
+	}
+
+	tmpPath := r.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("write v4 pool registry tmp: %w", err)
+	}
+	if err := os.Rename(tmpPath, r.path); err != nil {
+		return fmt.Errorf("rename v4 pool registry: %w", err)
+	}
+	return nil
+}
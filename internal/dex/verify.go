@@ -0,0 +1,215 @@
+package dex
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go.uber.org/zap"
+
+	"liquidityScope/internal/chain"
+	"liquidityScope/internal/model"
+)
+
+// maxRejected bounds the emitters remembered as rejected.
+const maxRejected = 10000
+
+// PoolDeployer describes how a V3 factory derives pool addresses with CREATE2.
+type PoolDeployer struct {
+	// Factory is recorded in the registry for verified pools.
+	Factory common.Address
+	// Deployer is the contract that executes CREATE2; it is the factory
+	// itself for Uniswap V3 and a separate pool deployer for PancakeSwap V3.
+	Deployer     common.Address
+	InitCodeHash common.Hash
+}
+
+// Known V3 pool deployers on BSC.
+var (
+	PancakeV3Deployer = PoolDeployer{
+		Factory:      PancakeV3Factory,
+		Deployer:     common.HexToAddress("0x41ff9AA7e16B8B1a8a8dc4f0eFacd93D02d071c9"),
+		InitCodeHash: common.HexToHash("0x6ce8eb472fa82df5469c6ab6d485f17c3ad13c8cd7af59b3d4a8026c5ce0f7e2"),
+	}
+	UniswapV3Deployer = PoolDeployer{
+		Factory:      UniswapV3Factory,
+		Deployer:     UniswapV3Factory,
+		InitCodeHash: common.HexToHash("0xe34f199b19b2b4f47f68442619d555527d244f78a3297ea89325f843f87b8b54"),
+	}
+)
+
+// PoolAddress derives the CREATE2 address of the pool for a token pair and fee.
+func (d PoolDeployer) PoolAddress(token0, token1 common.Address, fee uint32) common.Address {
+	salt := crypto.Keccak256(
+		common.LeftPadBytes(token0.Bytes(), 32),
+		common.LeftPadBytes(token1.Bytes(), 32),
+		common.LeftPadBytes(new(big.Int).SetUint64(uint64(fee)).Bytes(), 32),
+	)
+	var salt32 [32]byte
+	copy(salt32[:], salt)
+	return crypto.CreateAddress2(d.Deployer, salt32, d.InitCodeHash.Bytes())
+}
+
+// PoolVerifier checks that log emitters are genuine factory pools. Pools in
+// the registry are trusted; other addresses are asked for token0, token1,
+// fee and tickSpacing and accepted when a known deployer derives the same
+// address. Verified pools are added to the registry. Rejections are only
+// remembered when they are definitive, in a bounded LRU.
+type PoolVerifier struct {
+	chain     *chain.Client
+	registry  *PoolRegistry
+	deployers []PoolDeployer
+	logger    *zap.Logger
+
+	mu       sync.Mutex
+	rejected map[common.Address]*list.Element
+	order    *list.List
+}
+
+func NewPoolVerifier(chainClient *chain.Client, registry *PoolRegistry, deployers []PoolDeployer, logger *zap.Logger) *PoolVerifier {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &PoolVerifier{
+		chain:     chainClient,
+		registry:  registry,
+		deployers: deployers,
+		logger:    logger,
+		rejected:  make(map[common.Address]*list.Element),
+		order:     list.New(),
+	}
+}
+
+// Verify reports for each address whether it is a verified pool. The error
+// is reserved for failures of the lookup itself, including getters that
+// failed with a retryable error; callers may retry.
+func (v *PoolVerifier) Verify(ctx context.Context, addresses []common.Address) (map[common.Address]bool, error) {
+	out := make(map[common.Address]bool, len(addresses))
+	unknown := make([]common.Address, 0)
+
+	v.mu.Lock()
+	for _, address := range addresses {
+		if _, ok := out[address]; ok {
+			continue
+		}
+		if _, ok := v.registry.Get(address); ok {
+			out[address] = true
+			continue
+		}
+		if elem, ok := v.rejected[address]; ok {
+			v.order.MoveToFront(elem)
+			out[address] = false
+			continue
+		}
+		out[address] = false
+		unknown = append(unknown, address)
+	}
+	v.mu.Unlock()
+
+	if len(unknown) == 0 {
+		return out, nil
+	}
+
+	poolABI, err := V3PoolABI()
+	if err != nil {
+		return nil, fmt.Errorf("parse pool abi: %w", err)
+	}
+	methods := []string{"token0", "token1", "fee", "tickSpacing"}
+	calls := make([]contractCall, 0, len(unknown)*len(methods))
+	for _, address := range unknown {
+		for _, method := range methods {
+			calls = append(calls, contractCall{to: address, abi: poolABI, method: method})
+		}
+	}
+	outputs, err := callBatch(ctx, v.chain, calls, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	var lookupErr error
+	v.mu.Lock()
+	for i, address := range unknown {
+		base := i * len(methods)
+		pool, ok, err := v.match(poolABI, address, outputs[base:base+len(methods)])
+		if err != nil {
+			if lookupErr == nil {
+				lookupErr = fmt.Errorf("verify %s: %w", address.Hex(), err)
+			}
+			continue
+		}
+		if !ok {
+			v.reject(address)
+			v.logger.Debug("unverified emitter", zap.String("address", address.Hex()))
+			continue
+		}
+		out[address] = true
+		if v.registry.Add(pool) {
+			changed = true
+		}
+		v.logger.Info("pool verified", zap.String("pool", pool.Address), zap.String("factory", pool.Factory))
+	}
+	v.mu.Unlock()
+
+	if changed {
+		if err := v.registry.Save(); err != nil {
+			return nil, err
+		}
+	}
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+	return out, nil
+}
+
+// reject remembers a definitive rejection, evicting the least recently seen
+// one beyond maxRejected.
+func (v *PoolVerifier) reject(address common.Address) {
+	if elem, ok := v.rejected[address]; ok {
+		v.order.MoveToFront(elem)
+		return
+	}
+	v.rejected[address] = v.order.PushFront(address)
+	for v.order.Len() > maxRejected {
+		oldest := v.order.Back()
+		v.order.Remove(oldest)
+		delete(v.rejected, oldest.Value.(common.Address))
+	}
+}
+
+// match decodes the pool getters and checks the address against every
+// deployer. A getter that failed with a retryable error is returned as an
+// error, since the address may still be a pool.
+func (v *PoolVerifier) match(poolABI abi.ABI, address common.Address, outputs []callOutput) (model.RegisteredPool, bool, error) {
+	for _, output := range outputs {
+		if output.err != nil && chain.Classify(output.err).Retryable() {
+			return model.RegisteredPool{}, false, output.err
+		}
+	}
+	meta, err := poolMetaFromOutputs(poolABI, outputs[0], outputs[1], outputs[2], outputs[3])
+	if err != nil {
+		return model.RegisteredPool{}, false, nil
+	}
+	token0 := common.HexToAddress(meta.Token0)
+	token1 := common.HexToAddress(meta.Token1)
+
+	for _, deployer := range v.deployers {
+		if deployer.PoolAddress(token0, token1, meta.Fee) != address {
+			continue
+		}
+		return model.RegisteredPool{
+			Address:     address.Hex(),
+			Factory:     deployer.Factory.Hex(),
+			Token0:      token0.Hex(),
+			Token1:      token1.Hex(),
+			Fee:         meta.Fee,
+			TickSpacing: meta.TickSpacing,
+		}, true, nil
+	}
+	return model.RegisteredPool{}, false, nil
+}
@@ -0,0 +1,63 @@
+package dex
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"liquidityScope/internal/chain"
+)
+
+func TestPoolDeployerPoolAddress(t *testing.T) {
+	// Uniswap V3 USDC/WETH 0.05% on Ethereum mainnet, which shares the init
+	// code hash with the BSC deployment.
+	mainnet := PoolDeployer{
+		Deployer:     common.HexToAddress("0x1F98431c8aD98523631AE4a59f267346ea31F984"),
+		InitCodeHash: UniswapV3Deployer.InitCodeHash,
+	}
+	usdc := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+
+	got := mainnet.PoolAddress(usdc, weth, 500)
+	want := common.HexToAddress("0x88e6A0c2dDD26FEEb64F039a2c41296FcB3f5640")
+	if got != want {
+		t.Fatalf("pool address mismatch: got %s want %s", got.Hex(), want.Hex())
+	}
+	if mainnet.PoolAddress(usdc, weth, 3000) == want {
+		t.Fatalf("fee must be part of the salt")
+	}
+}
+
+func TestPoolVerifierMatchRetryable(t *testing.T) {
+	poolABI, err := V3PoolABI()
+	if err != nil {
+		t.Fatalf("abi parse: %v", err)
+	}
+	verifier := NewPoolVerifier(nil, nil, []PoolDeployer{PancakeV3Deployer}, nil)
+	address := common.HexToAddress("0x4444444444444444444444444444444444444444")
+
+	transient := []callOutput{{err: errors.New("connection reset by peer")}, {}, {}, {}}
+	if _, ok, err := verifier.match(poolABI, address, transient); ok || err == nil {
+		t.Fatalf("expected a retryable lookup error, got ok=%v err=%v", ok, err)
+	}
+
+	reverted := []callOutput{{err: chain.ErrCallFailed}, {}, {}, {}}
+	if _, ok, err := verifier.match(poolABI, address, reverted); ok || err != nil {
+		t.Fatalf("expected a definitive rejection, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestPoolVerifierRejectBounded(t *testing.T) {
+	verifier := NewPoolVerifier(nil, nil, nil, nil)
+	for i := 0; i <= maxRejected; i++ {
+		verifier.reject(common.BigToAddress(big.NewInt(int64(i + 1))))
+	}
+	if len(verifier.rejected) != maxRejected {
+		t.Fatalf("expected %d remembered rejections, got %d", maxRejected, len(verifier.rejected))
+	}
+	if _, ok := verifier.rejected[common.BigToAddress(big.NewInt(1))]; ok {
+		t.Fatalf("expected the oldest rejection to be evicted")
+	}
+}
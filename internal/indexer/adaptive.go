@@ -0,0 +1,60 @@
+package indexer
+
+import (
+	"sync"
+)
+
+// growAfter is the number of consecutive successful queries before the span is doubled.
+const growAfter = 4
+
+// adaptiveSpan tracks the largest block span eth_getLogs currently accepts.
+// It halves on provider limit errors and doubles back toward the configured
+// batch size after a run of successful queries.
+type adaptiveSpan struct {
+	mu        sync.Mutex
+	max       uint64
+	current   uint64
+	successes int
+}
+
+func newAdaptiveSpan(max uint64) *adaptiveSpan {
+	return &adaptiveSpan{max: max, current: max}
+}
+
+func (a *adaptiveSpan) Get() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.current
+}
+
+// Shrink lowers the span below a size the provider rejected.
+func (a *adaptiveSpan) Shrink(rejected uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.successes = 0
+	next := rejected / 2
+	if next == 0 {
+		next = 1
+	}
+	if next < a.current {
+		a.current = next
+	}
+}
+
+// Grow records a successful query and widens the span after enough of them.
+func (a *adaptiveSpan) Grow() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.current >= a.max {
+		return
+	}
+	a.successes++
+	if a.successes < growAfter {
+		return
+	}
+	a.successes = 0
+	a.current *= 2
+	if a.current > a.max {
+		a.current = a.max
+	}
+}
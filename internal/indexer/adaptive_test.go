@@ -0,0 +1,32 @@
+package indexer
+
+import (
+	"testing"
+)
+
+func TestAdaptiveSpan(t *testing.T) {
+	span := newAdaptiveSpan(2000)
+
+	span.Shrink(2000)
+	if got := span.Get(); got != 1000 {
+		t.Fatalf("expected 1000 after shrink, got %d", got)
+	}
+	span.Shrink(1)
+	if got := span.Get(); got != 1 {
+		t.Fatalf("expected floor of 1, got %d", got)
+	}
+
+	for i := 0; i < growAfter; i++ {
+		span.Grow()
+	}
+	if got := span.Get(); got != 2 {
+		t.Fatalf("expected 2 after growth, got %d", got)
+	}
+
+	for i := 0; i < growAfter*20; i++ {
+		span.Grow()
+	}
+	if got := span.Get(); got != 2000 {
+		t.Fatalf("expected span capped at 2000, got %d", got)
+	}
+}
@@ -8,9 +8,17 @@ import (
 	"time"
 )
 
-// Checkpoint tracks the last processed block.
+// Checkpoint tracks the last processed block and, for append-only sinks, the
+// output length at that block so a resumed run can drop partial batches. The
+// offsets are only valid for the output they were taken from, which is
+// recorded next to them.
 type Checkpoint struct {
 	LastProcessedBlock uint64 `json:"last_processed_block"`
+	OutputPath         string `json:"output_path,omitempty"`
+	OutputKind         string `json:"output_kind,omitempty"`
+	OutputOffset       *int64 `json:"output_offset,omitempty"`
+	QuarantinePath     string `json:"quarantine_path,omitempty"`
+	QuarantineOffset   *int64 `json:"quarantine_offset,omitempty"`
 	UpdatedAt          string `json:"updated_at"`
 }
 
@@ -53,7 +61,9 @@ func (c *CheckpointStore) Load() (Checkpoint, bool, error) {
 	return cp, true, nil
 }
 
-func (c *CheckpointStore) Save(lastProcessed uint64) error {
+// Save durably replaces the checkpoint: the new file is synced before it is
+// renamed over the old one, and the directory is synced after.
+func (c *CheckpointStore) Save(cp Checkpoint) error {
 	if !c.enabled {
 		return nil
 	}
@@ -65,22 +75,47 @@ func (c *CheckpointStore) Save(lastProcessed uint64) error {
 		}
 	}
 
-	cp := Checkpoint{
-		LastProcessedBlock: lastProcessed,
-		UpdatedAt:          time.Now().UTC().Format(time.RFC3339Nano),
-	}
+	cp.UpdatedAt = time.Now().UTC().Format(time.RFC3339Nano)
 	data, err := json.Marshal(cp)
 	if err != nil {
 		return fmt.Errorf("marshal checkpoint: %w", err)
 	}
 
 	tmpPath := c.path + ".tmp"
-	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+	if err := writeFileSync(tmpPath, data); err != nil {
 		return fmt.Errorf("write checkpoint tmp: %w", err)
 	}
 	if err := os.Rename(tmpPath, c.path); err != nil {
 		return fmt.Errorf("rename checkpoint: %w", err)
 	}
+	if err := syncDir(dir); err != nil {
+		return fmt.Errorf("sync checkpoint dir: %w", err)
+	}
 
 	return nil
 }
+
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func syncDir(dir string) error {
+	handle, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+	return handle.Sync()
+}
@@ -0,0 +1,46 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"liquidityScope/internal/storage"
+)
+
+func TestRestoreOutputRefusesOtherOutput(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "other.jsonl")
+	if err := os.WriteFile(out, []byte("{}\n{}\n"), 0o644); err != nil {
+		t.Fatalf("write output: %v", err)
+	}
+
+	runner := NewRunner(RunConfig{
+		BatchSize:  10,
+		OutputPath: out,
+		OutputKind: storage.FormatJSONL,
+	}, nil, storage.NewJsonlStorage(out), nil)
+
+	offset := int64(3)
+	cp := Checkpoint{
+		LastProcessedBlock: 100,
+		OutputPath:         filepath.Join(dir, "logs.jsonl"),
+		OutputKind:         storage.FormatJSONL,
+		OutputOffset:       &offset,
+	}
+	if err := runner.restoreOutput(cp); err == nil {
+		t.Fatalf("expected a checkpoint for another output to be refused")
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || len(data) != 6 {
+		t.Fatalf("output must be left untouched, got %q (%v)", data, err)
+	}
+
+	cp.OutputPath = out
+	if err := runner.restoreOutput(cp); err != nil {
+		t.Fatalf("restore own output: %v", err)
+	}
+	if data, _ := os.ReadFile(out); len(data) != 3 {
+		t.Fatalf("expected output cut back to 3 bytes, got %d", len(data))
+	}
+}
@@ -0,0 +1,68 @@
+package indexer
+
+import (
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// defaultAddressChunkSize is the largest address list sent in one eth_getLogs
+// filter when RunConfig.AddressChunkSize is not set.
+const defaultAddressChunkSize = 100
+
+// chunkAddresses splits addresses into lists of at most size entries. An
+// empty list yields a single empty chunk so topic-only queries still run.
+func chunkAddresses(addresses []common.Address, size int) [][]common.Address {
+	if size <= 0 {
+		size = defaultAddressChunkSize
+	}
+	if len(addresses) <= size {
+		return [][]common.Address{addresses}
+	}
+
+	chunks := make([][]common.Address, 0, (len(addresses)+size-1)/size)
+	for start := 0; start < len(addresses); start += size {
+		end := start + size
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+		chunks = append(chunks, addresses[start:end])
+	}
+	return chunks
+}
+
+// mergeLogs combines the results of per-chunk queries into the order a single
+// query returns: by block number, then log index, without duplicates.
+func mergeLogs(parts [][]types.Log) []types.Log {
+	type logKey struct {
+		block   uint64
+		index   uint
+		removed bool
+	}
+
+	total := 0
+	for _, part := range parts {
+		total += len(part)
+	}
+	merged := make([]types.Log, 0, total)
+	seen := make(map[logKey]struct{}, total)
+	for _, part := range parts {
+		for _, log := range part {
+			key := logKey{block: log.BlockNumber, index: log.Index, removed: log.Removed}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			merged = append(merged, log)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].BlockNumber != merged[j].BlockNumber {
+			return merged[i].BlockNumber < merged[j].BlockNumber
+		}
+		return merged[i].Index < merged[j].Index
+	})
+	return merged
+}
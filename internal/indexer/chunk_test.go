@@ -0,0 +1,40 @@
+package indexer
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestChunkAddresses(t *testing.T) {
+	addresses := make([]common.Address, 5)
+	for i := range addresses {
+		addresses[i] = common.BigToAddress(common.Big1)
+	}
+
+	chunks := chunkAddresses(addresses, 2)
+	if len(chunks) != 3 || len(chunks[0]) != 2 || len(chunks[2]) != 1 {
+		t.Fatalf("unexpected chunks: %v", chunks)
+	}
+
+	if chunks := chunkAddresses(nil, 2); len(chunks) != 1 || len(chunks[0]) != 0 {
+		t.Fatalf("expected one empty chunk, got %v", chunks)
+	}
+}
+
+func TestMergeLogs(t *testing.T) {
+	a := []types.Log{{BlockNumber: 10, Index: 3}, {BlockNumber: 12, Index: 0}}
+	b := []types.Log{{BlockNumber: 10, Index: 1}, {BlockNumber: 10, Index: 3}, {BlockNumber: 11, Index: 7}}
+
+	got := mergeLogs([][]types.Log{a, b})
+	want := [][2]uint64{{10, 1}, {10, 3}, {11, 7}, {12, 0}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d logs, got %d", len(want), len(got))
+	}
+	for i, log := range got {
+		if log.BlockNumber != want[i][0] || uint64(log.Index) != want[i][1] {
+			t.Fatalf("log %d: got (%d, %d), want %v", i, log.BlockNumber, log.Index, want[i])
+		}
+	}
+}
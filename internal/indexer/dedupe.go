@@ -0,0 +1,108 @@
+package indexer
+
+import (
+	"fmt"
+	"strings"
+
+	"liquidityScope/internal/model"
+)
+
+// defaultDedupeWindow is the number of blocks below the newest committed
+// block that stay deduplicated when reorg tracking is disabled.
+const defaultDedupeWindow = 64
+
+// LogDedupe remembers the logs written for a sliding window of recent blocks.
+// Blocks more than window below the newest committed block can only be fetched
+// again by an overlapping run and are forgotten to bound memory.
+type LogDedupe struct {
+	window uint64
+	head   uint64
+	blocks map[uint64]map[string]struct{}
+}
+
+func NewLogDedupe(window uint64) *LogDedupe {
+	if window == 0 {
+		window = defaultDedupeWindow
+	}
+	return &LogDedupe{window: window, blocks: make(map[uint64]map[string]struct{})}
+}
+
+// Add records a log and reports whether it was new.
+func (d *LogDedupe) Add(blockNumber uint64, txHash string, logIndex uint64) bool {
+	key := dedupeKey(txHash, logIndex)
+	block, ok := d.blocks[blockNumber]
+	if !ok {
+		block = make(map[string]struct{})
+		d.blocks[blockNumber] = block
+	}
+	if _, ok := block[key]; ok {
+		return false
+	}
+	block[key] = struct{}{}
+	return true
+}
+
+// Remove forgets a log and reports whether it had been recorded.
+func (d *LogDedupe) Remove(blockNumber uint64, txHash string, logIndex uint64) bool {
+	block, ok := d.blocks[blockNumber]
+	if !ok {
+		return false
+	}
+	key := dedupeKey(txHash, logIndex)
+	if _, ok := block[key]; !ok {
+		return false
+	}
+	delete(block, key)
+	if len(block) == 0 {
+		delete(d.blocks, blockNumber)
+	}
+	return true
+}
+
+// Advance moves the window so that head is the newest committed block and
+// drops blocks that fell out of it.
+func (d *LogDedupe) Advance(head uint64) {
+	if head > d.head {
+		d.head = head
+	}
+	if d.head < d.window {
+		return
+	}
+	floor := d.head - d.window
+	for number := range d.blocks {
+		if number <= floor {
+			delete(d.blocks, number)
+		}
+	}
+}
+
+// Rebuild replays records read back from the output, in file order.
+// Tombstones cancel the log they compensate.
+func (d *LogDedupe) Rebuild(records []model.LogRecord) {
+	for _, record := range records {
+		if record.Removed {
+			d.Remove(record.BlockNumber, record.TxHash, record.LogIndex)
+			continue
+		}
+		d.Add(record.BlockNumber, record.TxHash, record.LogIndex)
+		d.Advance(record.BlockNumber)
+	}
+}
+
+// Len returns the number of remembered logs.
+func (d *LogDedupe) Len() int {
+	total := 0
+	for _, block := range d.blocks {
+		total += len(block)
+	}
+	return total
+}
+
+// Window returns the number of blocks kept below the newest committed block.
+func (d *LogDedupe) Window() uint64 {
+	return d.window
+}
+
+func dedupeKey(txHash string, logIndex uint64) string {
+	return fmt.Sprintf("%s:%d", strings.ToLower(txHash), logIndex)
+}
@@ -0,0 +1,42 @@
+package indexer
+
+import (
+	"testing"
+
+	"liquidityScope/internal/model"
+)
+
+func TestLogDedupeWindow(t *testing.T) {
+	dedupe := NewLogDedupe(2)
+	if !dedupe.Add(10, "0xAA", 1) {
+		t.Fatalf("expected first add to be new")
+	}
+	if dedupe.Add(10, "0xaa", 1) {
+		t.Fatalf("expected duplicate regardless of hash case")
+	}
+
+	dedupe.Add(11, "0xbb", 0)
+	dedupe.Advance(12)
+	if dedupe.Len() != 1 {
+		t.Fatalf("expected block 10 to be pruned, tracking %d logs", dedupe.Len())
+	}
+	if !dedupe.Remove(11, "0xbb", 0) || dedupe.Remove(11, "0xbb", 0) {
+		t.Fatalf("expected remove to report the log once")
+	}
+}
+
+func TestLogDedupeRebuild(t *testing.T) {
+	dedupe := NewLogDedupe(64)
+	dedupe.Rebuild([]model.LogRecord{
+		{BlockNumber: 5, TxHash: "0x01", LogIndex: 0},
+		{BlockNumber: 6, TxHash: "0x02", LogIndex: 3},
+		{BlockNumber: 6, TxHash: "0x02", LogIndex: 3, Removed: true},
+	})
+
+	if dedupe.Add(5, "0x01", 0) {
+		t.Fatalf("expected rebuilt log to be a duplicate")
+	}
+	if !dedupe.Add(6, "0x02", 3) {
+		t.Fatalf("expected tombstoned log to be writable again")
+	}
+}
@@ -0,0 +1,98 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.uber.org/zap"
+
+	"liquidityScope/internal/chain"
+)
+
+// rangeResult holds everything fetched for a block range before it is committed.
+type rangeResult struct {
+	logs       []types.Log
+	timestamps map[uint64]uint64
+	ref        chain.BlockRef
+	err        error
+}
+
+// rangeFetcher fetches block ranges on a bounded worker pool and hands the
+// results back in range order. A slot is only released once its result has
+// been consumed, so at most `concurrency` results are buffered at any time.
+type rangeFetcher struct {
+	slots   chan struct{}
+	results []chan rangeResult
+}
+
+func (r *Runner) startFetch(ctx context.Context, ranges []BlockRange) *rangeFetcher {
+	concurrency := r.cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	f := &rangeFetcher{
+		slots:   make(chan struct{}, concurrency),
+		results: make([]chan rangeResult, len(ranges)),
+	}
+	for i := range f.results {
+		f.results[i] = make(chan rangeResult, 1)
+	}
+
+	go func() {
+		for i, blockRange := range ranges {
+			select {
+			case f.slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(out chan<- rangeResult, blockRange BlockRange) {
+				out <- r.fetchRange(ctx, blockRange)
+			}(f.results[i], blockRange)
+		}
+	}()
+
+	return f
+}
+
+// next waits for the result of the i-th range and frees its worker slot.
+func (f *rangeFetcher) next(ctx context.Context, i int) (rangeResult, error) {
+	select {
+	case <-ctx.Done():
+		return rangeResult{}, ctx.Err()
+	case result := <-f.results[i]:
+		<-f.slots
+		return result, result.err
+	}
+}
+
+// fetchRange loads logs, block timestamps and, when reorg tracking is on, the
+// reference of the last block in the range.
+func (r *Runner) fetchRange(ctx context.Context, blockRange BlockRange) rangeResult {
+	r.logger.Info("fetch logs", zap.Uint64("from", blockRange.From), zap.Uint64("to", blockRange.To))
+
+	logs, err := r.filterLogs(ctx, blockRange)
+	if err != nil {
+		return rangeResult{err: fmt.Errorf("filter logs: %w", err)}
+	}
+
+	numbers := make([]uint64, 0, len(logs))
+	for _, log := range logs {
+		numbers = append(numbers, log.BlockNumber)
+	}
+	timestamps, err := r.blockTimestampsWithRetry(ctx, numbers)
+	if err != nil {
+		return rangeResult{err: fmt.Errorf("block timestamps %d-%d: %w", blockRange.From, blockRange.To, err)}
+	}
+
+	result := rangeResult{logs: logs, timestamps: timestamps}
+	if r.reorg != nil {
+		ref, err := r.blockRefWithRetry(ctx, blockRange.To)
+		if err != nil {
+			return rangeResult{err: fmt.Errorf("block ref %d: %w", blockRange.To, err)}
+		}
+		result.ref = ref
+	}
+	return result
+}
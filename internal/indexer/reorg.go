@@ -0,0 +1,219 @@
+package indexer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"liquidityScope/internal/model"
+)
+
+// ReorgError reports that blocks above ForkBlock were replaced on chain.
+type ReorgError struct {
+	ForkBlock uint64
+	Orphaned  int
+}
+
+func (e *ReorgError) Error() string {
+	return fmt.Sprintf("chain reorg: rewound to block %d (%d orphaned logs)", e.ForkBlock, e.Orphaned)
+}
+
+// ReorgTracker keeps a window of recent block hashes along with the log
+// records written for those blocks, to the output or to quarantine, so
+// orphaned logs can be compensated where they were written.
+type ReorgTracker struct {
+	window uint64
+	head   uint64
+	blocks map[uint64]*trackedBlock
+}
+
+type trackedBlock struct {
+	hash        common.Hash
+	records     []model.LogRecord
+	quarantined []model.LogRecord
+}
+
+func NewReorgTracker(window uint64) *ReorgTracker {
+	return &ReorgTracker{window: window, blocks: make(map[uint64]*trackedBlock)}
+}
+
+// Head returns the newest tracked block number.
+func (t *ReorgTracker) Head() uint64 {
+	return t.head
+}
+
+// Hash returns the tracked hash for a block number.
+func (t *ReorgTracker) Hash(number uint64) (common.Hash, bool) {
+	block, ok := t.blocks[number]
+	if !ok {
+		return common.Hash{}, false
+	}
+	return block.hash, true
+}
+
+// Observe records the hash of a block. It returns false if a different hash
+// was already tracked for the same number.
+func (t *ReorgTracker) Observe(number uint64, hash common.Hash) bool {
+	block, ok := t.blocks[number]
+	if ok {
+		return block.hash == hash
+	}
+	t.blocks[number] = &trackedBlock{hash: hash}
+	if number > t.head {
+		t.head = number
+	}
+	t.prune()
+	return true
+}
+
+// Record attaches a written log record to its tracked block.
+func (t *ReorgTracker) Record(record model.LogRecord) {
+	block, ok := t.blocks[record.BlockNumber]
+	if !ok {
+		return
+	}
+	block.records = append(block.records, record)
+}
+
+// RecordQuarantined attaches a quarantined log record to its tracked block.
+func (t *ReorgTracker) RecordQuarantined(record model.LogRecord) {
+	block, ok := t.blocks[record.BlockNumber]
+	if !ok {
+		return
+	}
+	block.quarantined = append(block.quarantined, record)
+}
+
+// Forget drops a previously recorded log, e.g. when the node reports it as removed.
+func (t *ReorgTracker) Forget(record model.LogRecord) {
+	block, ok := t.blocks[record.BlockNumber]
+	if !ok {
+		return
+	}
+	block.records = withoutRecord(block.records, record)
+	block.quarantined = withoutRecord(block.quarantined, record)
+}
+
+func withoutRecord(records []model.LogRecord, record model.LogRecord) []model.LogRecord {
+	kept := records[:0]
+	for _, existing := range records {
+		if strings.EqualFold(existing.TxHash, record.TxHash) && existing.LogIndex == record.LogIndex {
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	return kept
+}
+
+// Before returns the newest tracked block below number.
+func (t *ReorgTracker) Before(number uint64) (uint64, common.Hash, bool) {
+	var found uint64
+	ok := false
+	for tracked := range t.blocks {
+		if tracked < number && (!ok || tracked > found) {
+			found, ok = tracked, true
+		}
+	}
+	if !ok {
+		return 0, common.Hash{}, false
+	}
+	return found, t.blocks[found].hash, true
+}
+
+// Rebuild replays records read back from the output, in file order, so a
+// restarted run still detects reorgs of blocks written before it stopped.
+// A block written again under a new hash replaces the old one, whose logs were
+// tombstoned before it.
+func (t *ReorgTracker) Rebuild(records []model.LogRecord) {
+	for _, record := range records {
+		if record.Removed {
+			t.Forget(record)
+			continue
+		}
+		hash := common.HexToHash(record.BlockHash)
+		if !t.Observe(record.BlockNumber, hash) {
+			t.blocks[record.BlockNumber] = &trackedBlock{hash: hash}
+		}
+		t.Record(record)
+	}
+}
+
+// RebuildQuarantined replays records read back from the quarantine output
+// after Rebuild. Logs of blocks the output has under another hash are stale
+// and skipped.
+func (t *ReorgTracker) RebuildQuarantined(records []model.LogRecord) {
+	for _, record := range records {
+		if record.Removed {
+			t.Forget(record)
+			continue
+		}
+		if t.Observe(record.BlockNumber, common.HexToHash(record.BlockHash)) {
+			t.RecordQuarantined(record)
+		}
+	}
+}
+
+// Numbers returns tracked block numbers from newest to oldest.
+func (t *ReorgTracker) Numbers() []uint64 {
+	numbers := make([]uint64, 0, len(t.blocks))
+	for number := range t.blocks {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] > numbers[j] })
+	return numbers
+}
+
+// Rewind drops every block above forkBlock and returns their log records and
+// quarantined log records, each in (block, log index) order.
+func (t *ReorgTracker) Rewind(forkBlock uint64) ([]model.LogRecord, []model.LogRecord) {
+	var orphaned, quarantined []model.LogRecord
+	for number, block := range t.blocks {
+		if number <= forkBlock {
+			continue
+		}
+		orphaned = append(orphaned, block.records...)
+		quarantined = append(quarantined, block.quarantined...)
+		delete(t.blocks, number)
+	}
+	if t.head > forkBlock {
+		t.head = forkBlock
+	}
+
+	sortRecords(orphaned)
+	sortRecords(quarantined)
+	return orphaned, quarantined
+}
+
+func sortRecords(records []model.LogRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].BlockNumber != records[j].BlockNumber {
+			return records[i].BlockNumber < records[j].BlockNumber
+		}
+		return records[i].LogIndex < records[j].LogIndex
+	})
+}
+
+func (t *ReorgTracker) prune() {
+	if t.head < t.window {
+		return
+	}
+	floor := t.head - t.window
+	for number := range t.blocks {
+		if number <= floor {
+			delete(t.blocks, number)
+		}
+	}
+}
+
+// tombstones converts orphaned records into compensating records flagged as removed.
+func tombstones(records []model.LogRecord, ingestedAt string) []model.LogRecord {
+	out := make([]model.LogRecord, 0, len(records))
+	for _, record := range records {
+		record.Removed = true
+		record.IngestedAt = ingestedAt
+		out = append(out, record)
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package indexer
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"liquidityScope/internal/model"
+)
+
+func TestReorgTrackerRewind(t *testing.T) {
+	tracker := NewReorgTracker(10)
+	for number := uint64(100); number <= 103; number++ {
+		if !tracker.Observe(number, common.BigToHash(new(big.Int).SetUint64(number))) {
+			t.Fatalf("unexpected conflict at %d", number)
+		}
+	}
+
+	tracker.Record(model.LogRecord{BlockNumber: 102, TxHash: "0xa", LogIndex: 3})
+	tracker.Record(model.LogRecord{BlockNumber: 103, TxHash: "0xb", LogIndex: 1})
+	tracker.Record(model.LogRecord{BlockNumber: 102, TxHash: "0xa", LogIndex: 1})
+	tracker.Record(model.LogRecord{BlockNumber: 101, TxHash: "0xc", LogIndex: 0})
+
+	orphaned, _ := tracker.Rewind(101)
+	if len(orphaned) != 3 {
+		t.Fatalf("expected 3 orphaned records, got %d", len(orphaned))
+	}
+	if orphaned[0].LogIndex != 1 || orphaned[1].LogIndex != 3 || orphaned[2].BlockNumber != 103 {
+		t.Fatalf("orphaned order mismatch: %+v", orphaned)
+	}
+	if _, ok := tracker.Hash(102); ok {
+		t.Fatalf("block 102 should be dropped")
+	}
+	if _, ok := tracker.Hash(101); !ok {
+		t.Fatalf("block 101 should be kept")
+	}
+
+	stones := tombstones(orphaned, "2024-01-01T00:00:00Z")
+	for _, stone := range stones {
+		if !stone.Removed || stone.IngestedAt != "2024-01-01T00:00:00Z" {
+			t.Fatalf("tombstone mismatch: %+v", stone)
+		}
+	}
+}
+
+func TestReorgTrackerObserveConflict(t *testing.T) {
+	tracker := NewReorgTracker(10)
+	tracker.Observe(5, common.HexToHash("0x01"))
+	if tracker.Observe(5, common.HexToHash("0x02")) {
+		t.Fatalf("expected conflict for replaced hash")
+	}
+	if !tracker.Observe(5, common.HexToHash("0x01")) {
+		t.Fatalf("same hash should not conflict")
+	}
+}
+
+func TestReorgTrackerPrune(t *testing.T) {
+	tracker := NewReorgTracker(3)
+	for number := uint64(1); number <= 10; number++ {
+		tracker.Observe(number, common.HexToHash("0x01"))
+	}
+
+	numbers := tracker.Numbers()
+	if len(numbers) != 3 || numbers[0] != 10 || numbers[2] != 8 {
+		t.Fatalf("unexpected window: %v", numbers)
+	}
+}
+
+func TestReorgTrackerRebuild(t *testing.T) {
+	tracker := NewReorgTracker(64)
+	tracker.Rebuild([]model.LogRecord{
+		{BlockNumber: 7, BlockHash: "0x07", TxHash: "0xa", LogIndex: 0},
+		{BlockNumber: 8, BlockHash: "0x08", TxHash: "0xb", LogIndex: 0},
+		{BlockNumber: 8, BlockHash: "0x08", TxHash: "0xb", LogIndex: 0, Removed: true},
+		{BlockNumber: 8, BlockHash: "0x88", TxHash: "0xc", LogIndex: 1},
+	})
+
+	number, hash, ok := tracker.Before(20)
+	if !ok || number != 8 || hash != common.HexToHash("0x88") {
+		t.Fatalf("expected block 8 under its replacement hash, got %d %s %v", number, hash.Hex(), ok)
+	}
+	orphaned, _ := tracker.Rewind(6)
+	if len(orphaned) != 2 || orphaned[0].TxHash != "0xa" || orphaned[1].TxHash != "0xc" {
+		t.Fatalf("unexpected orphaned records after rebuild: %+v", orphaned)
+	}
+}
+
+func TestReorgTrackerRewindQuarantined(t *testing.T) {
+	tracker := NewReorgTracker(64)
+	tracker.Rebuild([]model.LogRecord{
+		{BlockNumber: 7, BlockHash: "0x07", TxHash: "0xa", LogIndex: 0},
+		{BlockNumber: 8, BlockHash: "0x08", TxHash: "0xb", LogIndex: 0},
+	})
+	tracker.RebuildQuarantined([]model.LogRecord{
+		{BlockNumber: 8, BlockHash: "0x08", TxHash: "0xc", LogIndex: 2},
+		{BlockNumber: 8, BlockHash: "0x88", TxHash: "0xd", LogIndex: 3},
+		{BlockNumber: 9, BlockHash: "0x09", TxHash: "0xe", LogIndex: 1},
+		{BlockNumber: 9, BlockHash: "0x09", TxHash: "0xf", LogIndex: 4},
+		{BlockNumber: 9, BlockHash: "0x09", TxHash: "0xf", LogIndex: 4, Removed: true},
+	})
+
+	orphaned, quarantined := tracker.Rewind(7)
+	if len(orphaned) != 1 || orphaned[0].TxHash != "0xb" {
+		t.Fatalf("unexpected orphaned records: %+v", orphaned)
+	}
+	if len(quarantined) != 2 || quarantined[0].TxHash != "0xc" || quarantined[1].TxHash != "0xe" {
+		t.Fatalf("expected quarantined logs of the rewound blocks only, got %+v", quarantined)
+	}
+}
@@ -2,18 +2,26 @@ package indexer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 
 	"liquidityScope/internal/chain"
 	"liquidityScope/internal/model"
 	"liquidityScope/internal/storage"
 )
 
+const defaultPollInterval = 3 * time.Second
+
+// chunkConcurrency bounds the address chunks of one range queried at once.
+// Ranges are already fetched in parallel, so this multiplies with Concurrency.
+const chunkConcurrency = 4
+
 // RunConfig holds runtime settings for the indexer.
 type RunConfig struct {
 	FromBlock         uint64
@@ -25,6 +33,28 @@ type RunConfig struct {
 	CheckpointEnabled bool
 	MaxRetries        int
 	RetryBackoff      time.Duration
+	Follow            bool
+	Confirmations     uint64
+	PollInterval      time.Duration
+	ReorgWindow       uint64
+	Concurrency       int
+	// AddressChunkSize caps the addresses sent in one eth_getLogs filter;
+	// larger sets are queried in concurrent chunks.
+	AddressChunkSize int
+	// Verifier, when set, checks every emitter before its logs are written.
+	// With no Addresses the runner filters by Topic0 alone and relies on it.
+	Verifier Verifier
+	// Quarantine receives logs from emitters the Verifier rejected.
+	Quarantine storage.Storage
+	// OutputPath and OutputKind identify the storage, and QuarantinePath the
+	// quarantine. They are recorded in the checkpoint so a resumed run never
+	// truncates a file the checkpoint was not written for.
+	OutputPath     string
+	OutputKind     string
+	QuarantinePath string
+	// RefreshAddresses, when set, is called before every sync in follow mode
+	// and replaces Addresses, so pools registered meanwhile are picked up.
+	RefreshAddresses func() ([]common.Address, error)
 }
 
 // Runner streams logs from the chain and writes them to storage.
@@ -33,8 +63,10 @@ type Runner struct {
 	chain      *chain.Client
 	storage    storage.Storage
 	logger     *zap.Logger
-	seen       map[string]struct{}
+	dedupe     *LogDedupe
 	checkpoint *CheckpointStore
+	reorg      *ReorgTracker
+	span       *adaptiveSpan
 }
 
 // NewRunner builds a Runner with its dependencies.
@@ -42,13 +74,22 @@ func NewRunner(cfg RunConfig, chainClient *chain.Client, storageSink storage.Sto
 	if logger == nil {
 		logger = zap.NewNop()
 	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	var reorg *ReorgTracker
+	if cfg.ReorgWindow > 0 {
+		reorg = NewReorgTracker(cfg.ReorgWindow)
+	}
 	return &Runner{
 		cfg:        cfg,
 		chain:      chainClient,
 		storage:    storageSink,
 		logger:     logger,
-		seen:       make(map[string]struct{}),
+		dedupe:     NewLogDedupe(cfg.ReorgWindow),
 		checkpoint: NewCheckpointStore(cfg.CheckpointPath, cfg.CheckpointEnabled),
+		reorg:      reorg,
+		span:       newAdaptiveSpan(cfg.BatchSize),
 	}
 }
 
@@ -64,7 +105,15 @@ func (r *Runner) Run(ctx context.Context) error {
 		return fmt.Errorf("batch size must be greater than zero")
 	}
 	if len(r.cfg.Addresses) == 0 {
-		return fmt.Errorf("at least one address is required")
+		if r.cfg.Verifier == nil {
+			return fmt.Errorf("at least one address is required")
+		}
+		if len(r.cfg.Topic0) == 0 {
+			return fmt.Errorf("topic0 is required without an address filter")
+		}
+	}
+	if r.cfg.Follow && r.cfg.ToBlock != 0 {
+		return fmt.Errorf("to block cannot be combined with follow mode")
 	}
 
 	chainID, err := r.chain.GetChainID(ctx)
@@ -77,111 +126,617 @@ func (r *Runner) Run(ctx context.Context) error {
 	chainIDValue := chainID.Uint64()
 
 	from := r.cfg.FromBlock
-	to := r.cfg.ToBlock
-	if to == 0 {
-		latest, err := r.chain.LatestBlockNumber(ctx)
+	if sink, ok := r.checkpointer(); ok {
+		last, ok, err := sink.LoadCheckpoint()
 		if err != nil {
-			return fmt.Errorf("get latest block: %w", err)
+			return err
 		}
-		to = latest
-	}
-
-	if r.checkpoint != nil {
+		if ok && last >= from {
+			from = last + 1
+			r.logger.Info("resume from checkpoint", zap.Uint64("last_processed", last), zap.Uint64("from", from))
+		}
+	} else if r.checkpoint != nil {
 		cp, ok, err := r.checkpoint.Load()
 		if err != nil {
 			return err
 		}
+		if ok {
+			if err := r.restoreOutput(cp); err != nil {
+				return err
+			}
+		}
 		if ok && cp.LastProcessedBlock >= from {
 			from = cp.LastProcessedBlock + 1
 			r.logger.Info("resume from checkpoint", zap.Uint64("last_processed", cp.LastProcessedBlock), zap.Uint64("from", from))
 		}
 	}
+	if err := r.restoreTail(); err != nil {
+		return err
+	}
+
+	if r.cfg.Follow {
+		return r.follow(ctx, chainIDValue, from)
+	}
+
+	// Confirmations only hold back follow mode; a one-shot run syncs up to
+	// the block it was asked for, or the current head.
+	to := r.cfg.ToBlock
+	if to == 0 {
+		latest, err := r.latestBlockWithRetry(ctx)
+		if err != nil {
+			return fmt.Errorf("get latest block: %w", err)
+		}
+		to = latest
+	}
 
 	if from > to {
 		r.logger.Info("nothing to sync", zap.Uint64("from", from), zap.Uint64("to", to))
 		return nil
 	}
 
-	ranges, err := SplitRange(from, to, r.cfg.BatchSize)
-	if err != nil {
+	for {
+		err := r.syncRange(ctx, chainIDValue, from, to)
+		var reorgErr *ReorgError
+		if errors.As(err, &reorgErr) {
+			from = reorgErr.ForkBlock + 1
+			continue
+		}
 		return err
 	}
+}
 
-	for _, blockRange := range ranges {
+// follow tails the chain head, syncing every block that has reached the
+// confirmation depth, until the context is cancelled.
+func (r *Runner) follow(ctx context.Context, chainID uint64, from uint64) error {
+	r.logger.Info("follow mode", zap.Uint64("from", from), zap.Uint64("confirmations", r.cfg.Confirmations), zap.Duration("poll_interval", r.cfg.PollInterval))
+
+	next := from
+	for {
+		head, ok, err := r.safeHead(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return r.stopFollow(next)
+			}
+			return err
+		}
+
+		if ok && head >= next {
+			if err := r.refreshAddresses(); err != nil {
+				return err
+			}
+			if err := r.syncRange(ctx, chainID, next, head); err != nil {
+				var reorgErr *ReorgError
+				if errors.As(err, &reorgErr) {
+					next = reorgErr.ForkBlock + 1
+					continue
+				}
+				if ctx.Err() != nil {
+					return r.stopFollow(next)
+				}
+				return err
+			}
+			next = head + 1
+		} else {
+			r.logger.Debug("waiting for confirmed blocks", zap.Uint64("next", next), zap.Uint64("safe_head", head))
+		}
+
+		timer := time.NewTimer(r.cfg.PollInterval)
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		default:
+			timer.Stop()
+			return r.stopFollow(next)
+		case <-timer.C:
 		}
+	}
+}
+
+// refreshAddresses reloads the address filter between syncs.
+func (r *Runner) refreshAddresses() error {
+	if r.cfg.RefreshAddresses == nil {
+		return nil
+	}
+	addresses, err := r.cfg.RefreshAddresses()
+	if err != nil {
+		return fmt.Errorf("refresh addresses: %w", err)
+	}
+	if len(addresses) != len(r.cfg.Addresses) {
+		r.logger.Info("address filter refreshed", zap.Int("addresses", len(addresses)), zap.Int("previous", len(r.cfg.Addresses)))
+	}
+	r.cfg.Addresses = addresses
+	return nil
+}
+
+func (r *Runner) stopFollow(next uint64) error {
+	r.logger.Info("follow stopped", zap.Uint64("next", next))
+	return nil
+}
 
-		r.logger.Info("fetch logs", zap.Uint64("from", blockRange.From), zap.Uint64("to", blockRange.To))
+// safeHead returns the latest block that has reached the confirmation depth.
+// ok is false while the chain is shorter than the confirmation depth.
+func (r *Runner) safeHead(ctx context.Context) (uint64, bool, error) {
+	latest, err := r.latestBlockWithRetry(ctx)
+	if err != nil {
+		return 0, false, fmt.Errorf("get latest block: %w", err)
+	}
+	if latest < r.cfg.Confirmations {
+		return 0, false, nil
+	}
+	return latest - r.cfg.Confirmations, true, nil
+}
 
-		logs, err := r.filterLogsWithRetry(ctx, blockRange.From, blockRange.To)
+func (r *Runner) syncRange(ctx context.Context, chainID uint64, from, to uint64) error {
+	ranges, err := SplitRange(from, to, r.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	fetcher := r.startFetch(fetchCtx, ranges)
+
+	for i, blockRange := range ranges {
+		result, err := fetcher.next(ctx, i)
 		if err != nil {
-			return fmt.Errorf("filter logs: %w", err)
+			return err
 		}
 
-		ingestedAt := time.Now().UTC()
-		records := make([]model.LogRecord, 0, len(logs))
-		for _, log := range logs {
-			if r.isDuplicate(log) {
-				continue
-			}
+		if err := r.checkContinuity(ctx, blockRange.From); err != nil {
+			return err
+		}
 
-			ts, err := r.blockTimestampWithRetry(ctx, log.BlockNumber)
+		for refetches := 0; ; refetches++ {
+			consistent, err := r.observeRange(blockRange, result)
 			if err != nil {
-				return fmt.Errorf("block timestamp %d: %w", log.BlockNumber, err)
+				return err
+			}
+			if consistent {
+				break
+			}
+			if refetches >= r.cfg.MaxRetries {
+				return fmt.Errorf("block hashes unstable for range %d-%d", blockRange.From, blockRange.To)
+			}
+			r.logger.Warn("block hash changed during batch, refetching", zap.Uint64("from", blockRange.From), zap.Uint64("to", blockRange.To))
+			result = r.fetchRange(ctx, blockRange)
+			if result.err != nil {
+				return result.err
 			}
-			records = append(records, buildLogRecord(chainIDValue, log, ts, ingestedAt))
 		}
 
-		if err := r.storage.PutLogBatch(records); err != nil {
-			return fmt.Errorf("store logs: %w", err)
+		if err := r.commitRange(ctx, chainID, blockRange, result); err != nil {
+			return err
 		}
+	}
 
-		if r.checkpoint != nil {
-			if err := r.checkpoint.Save(blockRange.To); err != nil {
-				return err
+	return nil
+}
+
+// commitRange writes a fetched range and advances the checkpoint. Ranges are
+// committed strictly in order so the output stays deterministic.
+func (r *Runner) commitRange(ctx context.Context, chainID uint64, blockRange BlockRange, result rangeResult) error {
+	ingestedAt := time.Now().UTC()
+	records := make([]model.LogRecord, 0, len(result.logs))
+	for _, log := range result.logs {
+		if log.Removed {
+			if !r.forget(log) {
+				continue
 			}
+		} else if r.isDuplicate(log) {
+			continue
 		}
+		records = append(records, buildLogRecord(chainID, log, result.timestamps[log.BlockNumber], ingestedAt))
+	}
 
-		r.logger.Info("batch complete", zap.Int("logs", len(records)), zap.Uint64("from", blockRange.From), zap.Uint64("to", blockRange.To))
+	records, quarantined, err := r.partitionVerified(ctx, records)
+	if err != nil {
+		return fmt.Errorf("verify emitters: %w", err)
+	}
+	if len(quarantined) > 0 && r.cfg.Quarantine != nil {
+		if err := r.cfg.Quarantine.PutLogBatch(quarantined); err != nil {
+			return fmt.Errorf("store quarantined logs: %w", err)
+		}
 	}
 
+	if err := r.commit(records, blockRange.To); err != nil {
+		return fmt.Errorf("store logs: %w", err)
+	}
+	if r.reorg != nil {
+		for _, record := range records {
+			if !record.Removed {
+				r.reorg.Record(record)
+			}
+		}
+		if r.cfg.Quarantine != nil {
+			for _, record := range quarantined {
+				if !record.Removed {
+					r.reorg.RecordQuarantined(record)
+				}
+			}
+		}
+	}
+
+	r.dedupe.Advance(blockRange.To)
+
+	r.logger.Info("batch complete", zap.Int("logs", len(records)), zap.Int("quarantined", len(quarantined)), zap.Uint64("from", blockRange.From), zap.Uint64("to", blockRange.To))
 	return nil
 }
 
-func (r *Runner) filterLogsWithRetry(ctx context.Context, fromBlock, toBlock uint64) ([]types.Log, error) {
-	var logs []types.Log
-	err := withRetry(ctx, r.cfg.MaxRetries, r.cfg.RetryBackoff, func(ctx context.Context) error {
+// commit writes records and moves the checkpoint to lastProcessed, in one
+// transaction when the sink keeps the checkpoint itself.
+func (r *Runner) commit(records []model.LogRecord, lastProcessed uint64) error {
+	if sink, ok := r.checkpointer(); ok {
+		return sink.PutLogBatchAt(records, lastProcessed)
+	}
+	if err := r.storage.PutLogBatch(records); err != nil {
+		return err
+	}
+	return r.saveCheckpoint(lastProcessed)
+}
+
+// checkpointer returns the sink when it stores the checkpoint itself and
+// checkpointing is enabled.
+func (r *Runner) checkpointer() (storage.Checkpointer, bool) {
+	if !r.cfg.CheckpointEnabled {
+		return nil, false
+	}
+	sink, ok := r.storage.(storage.Checkpointer)
+	return sink, ok
+}
+
+// saveCheckpoint records lastProcessed together with the current length of
+// every append-only sink. Sinks are synced on write, so the checkpoint never
+// points past durable output.
+func (r *Runner) saveCheckpoint(lastProcessed uint64) error {
+	if r.checkpoint == nil {
+		return nil
+	}
+	cp := Checkpoint{
+		LastProcessedBlock: lastProcessed,
+		OutputPath:         r.cfg.OutputPath,
+		OutputKind:         r.cfg.OutputKind,
+	}
+	var err error
+	if cp.OutputOffset, err = sinkPosition(r.storage); err != nil {
+		return err
+	}
+	if cp.QuarantineOffset, err = sinkPosition(r.cfg.Quarantine); err != nil {
+		return err
+	}
+	if cp.QuarantineOffset != nil {
+		cp.QuarantinePath = r.cfg.QuarantinePath
+	}
+	return r.checkpoint.Save(cp)
+}
+
+// restoreOutput truncates append-only sinks to the offsets in cp, discarding
+// batches written after the checkpoint by a run that crashed before saving it.
+// It refuses to touch sinks other than those the checkpoint was written for.
+func (r *Runner) restoreOutput(cp Checkpoint) error {
+	if cp.OutputOffset != nil && (cp.OutputPath != r.cfg.OutputPath || cp.OutputKind != r.cfg.OutputKind) {
+		return fmt.Errorf("checkpoint %s belongs to %s output %q, not %s output %q; use a fresh checkpoint", r.cfg.CheckpointPath, cp.OutputKind, cp.OutputPath, r.cfg.OutputKind, r.cfg.OutputPath)
+	}
+	if cp.QuarantineOffset != nil && r.cfg.Quarantine != nil && cp.QuarantinePath != r.cfg.QuarantinePath {
+		return fmt.Errorf("checkpoint %s belongs to quarantine %q, not %q; use a fresh checkpoint", r.cfg.CheckpointPath, cp.QuarantinePath, r.cfg.QuarantinePath)
+	}
+	if err := truncateSink(r.storage, cp.OutputOffset); err != nil {
+		return fmt.Errorf("restore output: %w", err)
+	}
+	if err := truncateSink(r.cfg.Quarantine, cp.QuarantineOffset); err != nil {
+		return fmt.Errorf("restore quarantine: %w", err)
+	}
+	return nil
+}
+
+// restoreTail reloads the dedupe and reorg windows from the tail of the
+// output and quarantine so ranges fetched again after a restart do not repeat
+// logs already written, and a reorg of blocks written before the restart is
+// still caught.
+func (r *Runner) restoreTail() error {
+	records, err := sinkTail(r.storage, r.dedupe.Window())
+	if err != nil {
+		return fmt.Errorf("read output tail: %w", err)
+	}
+	quarantined, err := sinkTail(r.cfg.Quarantine, r.dedupe.Window())
+	if err != nil {
+		return fmt.Errorf("read quarantine tail: %w", err)
+	}
+	if len(records) == 0 && len(quarantined) == 0 {
+		return nil
+	}
+	r.dedupe.Rebuild(records)
+	r.dedupe.Rebuild(quarantined)
+	if r.reorg != nil {
+		r.reorg.Rebuild(records)
+		r.reorg.RebuildQuarantined(quarantined)
+	}
+	r.logger.Info("recent logs restored from output", zap.Int("records", len(records)), zap.Int("quarantined", len(quarantined)), zap.Int("tracked_logs", r.dedupe.Len()))
+	return nil
+}
+
+func sinkTail(sink storage.Storage, blocks uint64) ([]model.LogRecord, error) {
+	tailer, ok := sink.(storage.Tailer)
+	if !ok {
+		return nil, nil
+	}
+	return tailer.Tail(blocks)
+}
+
+func sinkPosition(sink storage.Storage) (*int64, error) {
+	positioner, ok := sink.(storage.Positioner)
+	if !ok {
+		return nil, nil
+	}
+	offset, err := positioner.Position()
+	if err != nil {
+		return nil, err
+	}
+	return &offset, nil
+}
+
+func truncateSink(sink storage.Storage, offset *int64) error {
+	positioner, ok := sink.(storage.Positioner)
+	if !ok || offset == nil {
+		return nil
+	}
+	return positioner.Truncate(*offset)
+}
+
+// checkContinuity verifies that the block at from still builds on the last
+// tracked block and rewinds to the fork point if it does not. After a restart
+// the newest tracked block may lie further back, and its own hash is checked.
+func (r *Runner) checkContinuity(ctx context.Context, from uint64) error {
+	if r.reorg == nil || from == 0 {
+		return nil
+	}
+	number, hash, ok := r.reorg.Before(from)
+	if !ok {
+		return nil
+	}
+
+	if number < from-1 {
+		ref, err := r.blockRefWithRetry(ctx, number)
+		if err != nil {
+			return fmt.Errorf("block ref %d: %w", number, err)
+		}
+		if ref.Hash == hash {
+			return nil
+		}
+		r.logger.Warn("block hash mismatch", zap.Uint64("block_number", number), zap.String("expected", hash.Hex()), zap.String("actual", ref.Hash.Hex()))
+		return r.rewind(ctx)
+	}
+
+	ref, err := r.blockRefWithRetry(ctx, from)
+	if err != nil {
+		return fmt.Errorf("block ref %d: %w", from, err)
+	}
+	if ref.ParentHash == hash {
+		return nil
+	}
+
+	r.logger.Warn("parent hash mismatch", zap.Uint64("block_number", from), zap.String("expected_parent", hash.Hex()), zap.String("actual_parent", ref.ParentHash.Hex()))
+	return r.rewind(ctx)
+}
+
+// rewind walks the tracked window back to the newest block that is still
+// canonical, writes tombstones for the orphaned logs and resets the checkpoint.
+func (r *Runner) rewind(ctx context.Context) error {
+	var forkBlock uint64
+	found := false
+	for _, number := range r.reorg.Numbers() {
+		tracked, _ := r.reorg.Hash(number)
+		ref, err := r.blockRefWithRetry(ctx, number)
+		if err != nil {
+			return fmt.Errorf("block ref %d: %w", number, err)
+		}
+		if ref.Hash == tracked {
+			forkBlock = number
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("chain reorg deeper than tracking window of %d blocks", r.cfg.ReorgWindow)
+	}
+
+	orphaned, quarantined, err := r.rewindTo(forkBlock)
+	if err != nil {
+		return err
+	}
+	for _, record := range append(orphaned, quarantined...) {
+		r.dedupe.Remove(record.BlockNumber, record.TxHash, record.LogIndex)
+	}
+	ingestedAt := time.Now().UTC().Format(time.RFC3339Nano)
+	if len(quarantined) > 0 && r.cfg.Quarantine != nil {
+		if err := r.cfg.Quarantine.PutLogBatch(tombstones(quarantined, ingestedAt)); err != nil {
+			return fmt.Errorf("store quarantined tombstones: %w", err)
+		}
+	}
+	if err := r.commit(tombstones(orphaned, ingestedAt), forkBlock); err != nil {
+		return fmt.Errorf("store tombstones: %w", err)
+	}
+
+	reorgErr := &ReorgError{ForkBlock: forkBlock, Orphaned: len(orphaned)}
+	r.logger.Warn("chain reorg handled", zap.Uint64("fork_block", forkBlock), zap.Int("orphaned_logs", len(orphaned)), zap.Int("orphaned_quarantined", len(quarantined)))
+	return reorgErr
+}
+
+// rewindTo drops the tracked blocks above forkBlock along with their cached
+// timestamps, which may be those of the orphaned blocks.
+func (r *Runner) rewindTo(forkBlock uint64) ([]model.LogRecord, []model.LogRecord, error) {
+	head := r.reorg.Head()
+	orphaned, quarantined := r.reorg.Rewind(forkBlock)
+	if r.chain != nil && head > forkBlock {
+		if err := r.chain.ForgetTimestamps(forkBlock+1, head); err != nil {
+			return nil, nil, fmt.Errorf("forget timestamps %d-%d: %w", forkBlock+1, head, err)
+		}
+	}
+	return orphaned, quarantined, nil
+}
+
+// observeRange records the block hashes seen in a fetched range and the hash
+// of its last block. It returns false if any hash disagrees with the tracked
+// window, in which case the range must be fetched again.
+func (r *Runner) observeRange(blockRange BlockRange, result rangeResult) (bool, error) {
+	if r.reorg == nil {
+		return true, nil
+	}
+
+	consistent := r.reorg.Observe(result.ref.Number, result.ref.Hash)
+	for _, log := range result.logs {
+		if log.Removed {
+			continue
+		}
+		if !r.reorg.Observe(log.BlockNumber, log.BlockHash) {
+			consistent = false
+		}
+	}
+	if !consistent && blockRange.From > 0 {
+		if _, _, err := r.rewindTo(blockRange.From - 1); err != nil {
+			return false, err
+		}
+	}
+	return consistent, nil
+}
+
+func (r *Runner) retryPolicy() chain.RetryPolicy {
+	return chain.RetryPolicy{MaxRetries: r.cfg.MaxRetries, BaseDelay: r.cfg.RetryBackoff}
+}
+
+func (r *Runner) latestBlockWithRetry(ctx context.Context) (uint64, error) {
+	var latest uint64
+	err := chain.Retry(ctx, r.retryPolicy(), func(ctx context.Context) error {
 		var err error
-		logs, err = r.chain.FilterLogs(ctx, fromBlock, toBlock, r.cfg.Addresses, r.cfg.Topic0)
+		latest, err = r.chain.LatestBlockNumber(ctx)
 		if err != nil {
-			r.logger.Warn("filter logs failed", zap.Error(err), zap.Uint64("from", fromBlock), zap.Uint64("to", toBlock))
+			r.logger.Warn("latest block fetch failed", zap.Error(err))
+		}
+		return err
+	})
+	return latest, err
+}
+
+// filterLogs fetches a range for every address chunk, at most
+// chunkConcurrency at a time, and merges the results, so the output matches a
+// single unchunked query. The first failing chunk cancels the others.
+func (r *Runner) filterLogs(ctx context.Context, blockRange BlockRange) ([]types.Log, error) {
+	chunks := chunkAddresses(r.cfg.Addresses, r.cfg.AddressChunkSize)
+	if len(chunks) == 1 {
+		return r.filterLogsForAddresses(ctx, blockRange, chunks[0])
+	}
+
+	parts := make([][]types.Log, len(chunks))
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(chunkConcurrency)
+	for i, chunk := range chunks {
+		i, chunk := i, chunk
+		group.Go(func() error {
+			var err error
+			parts[i], err = r.filterLogsForAddresses(groupCtx, blockRange, chunk)
+			return err
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	return mergeLogs(parts), nil
+}
+
+// filterLogsForAddresses fetches a range in sub-ranges no wider than the
+// current adaptive span, bisecting any sub-range the provider rejects as too
+// large.
+func (r *Runner) filterLogsForAddresses(ctx context.Context, blockRange BlockRange, addresses []common.Address) ([]types.Log, error) {
+	parts, err := SplitRange(blockRange.From, blockRange.To, r.span.Get())
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []types.Log
+	for _, part := range parts {
+		partLogs, err := r.filterLogsSplitting(ctx, part, addresses)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, partLogs...)
+	}
+	return logs, nil
+}
+
+func (r *Runner) filterLogsSplitting(ctx context.Context, blockRange BlockRange, addresses []common.Address) ([]types.Log, error) {
+	logs, err := r.filterLogsWithRetry(ctx, blockRange.From, blockRange.To, addresses)
+	if err == nil {
+		r.span.Grow()
+		return logs, nil
+	}
+	if !chain.IsProviderLimit(err) || blockRange.From == blockRange.To {
+		return nil, err
+	}
+
+	size := blockRange.To - blockRange.From + 1
+	r.span.Shrink(size)
+	mid := blockRange.From + (blockRange.To-blockRange.From)/2
+	r.logger.Info("bisect range on provider limit", zap.Uint64("from", blockRange.From), zap.Uint64("to", blockRange.To), zap.Uint64("span", r.span.Get()))
+
+	left, err := r.filterLogsSplitting(ctx, BlockRange{From: blockRange.From, To: mid}, addresses)
+	if err != nil {
+		return nil, err
+	}
+	right, err := r.filterLogsSplitting(ctx, BlockRange{From: mid + 1, To: blockRange.To}, addresses)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+func (r *Runner) filterLogsWithRetry(ctx context.Context, fromBlock, toBlock uint64, addresses []common.Address) ([]types.Log, error) {
+	var logs []types.Log
+	err := chain.Retry(ctx, r.retryPolicy(), func(ctx context.Context) error {
+		var err error
+		logs, err = r.chain.FilterLogs(ctx, fromBlock, toBlock, addresses, r.cfg.Topic0)
+		if err != nil && !chain.IsProviderLimit(err) {
+			r.logger.Warn("filter logs failed", zap.Error(err), zap.String("class", chain.Classify(err).String()), zap.Uint64("from", fromBlock), zap.Uint64("to", toBlock))
 		}
 		return err
 	})
 	return logs, err
 }
 
-func (r *Runner) blockTimestampWithRetry(ctx context.Context, blockNumber uint64) (uint64, error) {
-	var ts uint64
-	err := withRetry(ctx, r.cfg.MaxRetries, r.cfg.RetryBackoff, func(ctx context.Context) error {
+func (r *Runner) blockRefWithRetry(ctx context.Context, blockNumber uint64) (chain.BlockRef, error) {
+	var ref chain.BlockRef
+	err := chain.Retry(ctx, r.retryPolicy(), func(ctx context.Context) error {
+		var err error
+		ref, err = r.chain.BlockRefByNumber(ctx, blockNumber)
+		if err != nil {
+			r.logger.Warn("block ref fetch failed", zap.Error(err), zap.Uint64("block_number", blockNumber))
+		}
+		return err
+	})
+	return ref, err
+}
+
+func (r *Runner) blockTimestampsWithRetry(ctx context.Context, blockNumbers []uint64) (map[uint64]uint64, error) {
+	var timestamps map[uint64]uint64
+	err := chain.Retry(ctx, r.retryPolicy(), func(ctx context.Context) error {
 		var err error
-		ts, err = r.chain.BlockTimestamp(ctx, blockNumber)
+		timestamps, err = r.chain.BlockTimestamps(ctx, blockNumbers)
 		if err != nil {
-			r.logger.Warn("block timestamp fetch failed", zap.Error(err), zap.Uint64("block_number", blockNumber))
+			r.logger.Warn("block timestamps fetch failed", zap.Error(err), zap.Int("blocks", len(blockNumbers)))
 		}
 		return err
 	})
-	return ts, err
+	return timestamps, err
 }
 
 func (r *Runner) isDuplicate(log types.Log) bool {
-	id := fmt.Sprintf("%d:%s:%d", log.BlockNumber, log.TxHash.Hex(), log.Index)
-	if _, ok := r.seen[id]; ok {
-		return true
+	return !r.dedupe.Add(log.BlockNumber, log.TxHash.Hex(), uint64(log.Index))
+}
+
+// forget handles a log the node reports as removed. It returns true if the log
+// was previously written and therefore needs a tombstone.
+func (r *Runner) forget(log types.Log) bool {
+	if !r.dedupe.Remove(log.BlockNumber, log.TxHash.Hex(), uint64(log.Index)) {
+		return false
+	}
+	if r.reorg != nil {
+		r.reorg.Forget(model.LogRecord{BlockNumber: log.BlockNumber, TxHash: log.TxHash.Hex(), LogIndex: uint64(log.Index)})
 	}
-	r.seen[id] = struct{}{}
-	return false
+	return true
 }
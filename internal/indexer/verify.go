@@ -0,0 +1,54 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+
+	"liquidityScope/internal/chain"
+	"liquidityScope/internal/model"
+)
+
+// Verifier decides which log emitters may be written to the main sink.
+// It is required when the runner filters by topic0 only.
+type Verifier interface {
+	Verify(ctx context.Context, addresses []common.Address) (map[common.Address]bool, error)
+}
+
+// partitionVerified splits records into those from verified emitters and
+// those to quarantine.
+func (r *Runner) partitionVerified(ctx context.Context, records []model.LogRecord) ([]model.LogRecord, []model.LogRecord, error) {
+	if r.cfg.Verifier == nil || len(records) == 0 {
+		return records, nil, nil
+	}
+
+	addresses := make([]common.Address, 0, len(records))
+	for _, record := range records {
+		addresses = append(addresses, common.HexToAddress(record.Address))
+	}
+
+	var verified map[common.Address]bool
+	err := chain.Retry(ctx, r.retryPolicy(), func(ctx context.Context) error {
+		var err error
+		verified, err = r.cfg.Verifier.Verify(ctx, addresses)
+		if err != nil {
+			r.logger.Warn("verify emitters failed", zap.Error(err), zap.Int("addresses", len(addresses)))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accepted := make([]model.LogRecord, 0, len(records))
+	var quarantined []model.LogRecord
+	for _, record := range records {
+		if verified[common.HexToAddress(record.Address)] {
+			accepted = append(accepted, record)
+		} else {
+			quarantined = append(quarantined, record)
+		}
+	}
+	return accepted, quarantined, nil
+}
@@ -0,0 +1,48 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"liquidityScope/internal/model"
+)
+
+type staticVerifier map[common.Address]bool
+
+func (v staticVerifier) Verify(_ context.Context, addresses []common.Address) (map[common.Address]bool, error) {
+	out := make(map[common.Address]bool, len(addresses))
+	for _, address := range addresses {
+		out[address] = v[address]
+	}
+	return out, nil
+}
+
+func TestPartitionVerified(t *testing.T) {
+	pool := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	spoof := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	runner := NewRunner(RunConfig{Verifier: staticVerifier{pool: true}}, nil, nil, nil)
+
+	records := []model.LogRecord{
+		{Address: pool.Hex(), LogIndex: 0},
+		{Address: spoof.Hex(), LogIndex: 1},
+		{Address: pool.Hex(), LogIndex: 2},
+	}
+	accepted, quarantined, err := runner.partitionVerified(context.Background(), records)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(accepted) != 2 || accepted[0].LogIndex != 0 || accepted[1].LogIndex != 2 {
+		t.Fatalf("accepted mismatch: %+v", accepted)
+	}
+	if len(quarantined) != 1 || quarantined[0].Address != spoof.Hex() {
+		t.Fatalf("quarantined mismatch: %+v", quarantined)
+	}
+
+	plain := NewRunner(RunConfig{}, nil, nil, nil)
+	accepted, quarantined, err = plain.partitionVerified(context.Background(), records)
+	if err != nil || len(accepted) != 3 || len(quarantined) != 0 {
+		t.Fatalf("without a verifier all records pass: %d %d %v", len(accepted), len(quarantined), err)
+	}
+}
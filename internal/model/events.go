@@ -9,6 +9,10 @@ type SwapEventData struct {
 	SqrtPriceX96 string `json:"sqrt_price_x96"`
 	Liquidity    string `json:"liquidity"`
 	Tick         int32  `json:"tick"`
+	// ProtocolFeesToken0 and ProtocolFeesToken1 are the protocol's share of
+	// the swap fee, reported only by PancakeSwap V3 pools.
+	ProtocolFeesToken0 string `json:"protocol_fees_token0,omitempty"`
+	ProtocolFeesToken1 string `json:"protocol_fees_token1,omitempty"`
 }
 
 // MintEventData is the decoded Mint event payload.
@@ -41,3 +45,123 @@ type CollectEventData struct {
 	Amount0   string `json:"amount0"`
 	Amount1   string `json:"amount1"`
 }
+
+// V2SwapEventData is the decoded V2 pair Swap event payload.
+type V2SwapEventData struct {
+	Sender     string `json:"sender"`
+	To         string `json:"to"`
+	Amount0In  string `json:"amount0_in"`
+	Amount1In  string `json:"amount1_in"`
+	Amount0Out string `json:"amount0_out"`
+	Amount1Out string `json:"amount1_out"`
+}
+
+// SyncEventData is the decoded V2 pair Sync event payload: the reserves after
+// the transfer that emitted it.
+type SyncEventData struct {
+	Reserve0 string `json:"reserve0"`
+	Reserve1 string `json:"reserve1"`
+}
+
+// V2MintEventData is the decoded V2 pair Mint event payload.
+type V2MintEventData struct {
+	Sender  string `json:"sender"`
+	Amount0 string `json:"amount0"`
+	Amount1 string `json:"amount1"`
+}
+
+// V2BurnEventData is the decoded V2 pair Burn event payload.
+type V2BurnEventData struct {
+	Sender  string `json:"sender"`
+	To      string `json:"to"`
+	Amount0 string `json:"amount0"`
+	Amount1 string `json:"amount1"`
+}
+
+// V4InitializeEventData is the decoded V4 PoolManager Initialize event
+// payload. Fee keeps the dynamic-fee flag of the pool key.
+type V4InitializeEventData struct {
+	Currency0    string `json:"currency0"`
+	Currency1    string `json:"currency1"`
+	Fee          uint32 `json:"fee"`
+	TickSpacing  int32  `json:"tick_spacing"`
+	Hooks        string `json:"hooks"`
+	SqrtPriceX96 string `json:"sqrt_price_x96"`
+	Tick         int32  `json:"tick"`
+}
+
+// V4ModifyLiquidityEventData is the decoded V4 PoolManager ModifyLiquidity
+// event payload.
+type V4ModifyLiquidityEventData struct {
+	Sender         string `json:"sender"`
+	TickLower      int32  `json:"tick_lower"`
+	TickUpper      int32  `json:"tick_upper"`
+	LiquidityDelta string `json:"liquidity_delta"`
+	Salt           string `json:"salt"`
+}
+
+// V4SwapEventData is the decoded V4 PoolManager Swap event payload. Amounts
+// are the balance deltas of the swapper, negative for the token paid in, and
+// Fee is the fee charged on this swap.
+type V4SwapEventData struct {
+	Sender       string `json:"sender"`
+	Amount0      string `json:"amount0"`
+	Amount1      string `json:"amount1"`
+	SqrtPriceX96 string `json:"sqrt_price_x96"`
+	Liquidity    string `json:"liquidity"`
+	Tick         int32  `json:"tick"`
+	Fee          uint32 `json:"fee"`
+}
+
+// AlgebraFeeEventData is the decoded Algebra pool Fee event payload: the fee
+// in force from this log on, in hundredths of a bip. Pools with directional
+// fees (Camelot) emit Fee(feeZto, feeOtz), decoded as the zero-for-one Fee and
+// the one-for-zero FeeOtz; other pools leave FeeOtz 0.
+type AlgebraFeeEventData struct {
+	Fee    uint32 `json:"fee"`
+	FeeOtz uint32 `json:"fee_otz,omitempty"`
+}
+
+// InitializeEventData is the decoded V3 pool Initialize event payload: the
+// initial price, set when the pool is created.
+type InitializeEventData struct {
+	SqrtPriceX96 string `json:"sqrt_price_x96"`
+	Tick         int32  `json:"tick"`
+}
+
+// FlashEventData is the decoded Flash event payload. Paid0 and Paid1 are the
+// fees paid on the loan.
+type FlashEventData struct {
+	Sender    string `json:"sender"`
+	Recipient string `json:"recipient"`
+	Amount0   string `json:"amount0"`
+	Amount1   string `json:"amount1"`
+	Paid0     string `json:"paid0"`
+	Paid1     string `json:"paid1"`
+}
+
+// CollectProtocolEventData is the decoded CollectProtocol event payload.
+type CollectProtocolEventData struct {
+	Sender    string `json:"sender"`
+	Recipient string `json:"recipient"`
+	Amount0   string `json:"amount0"`
+	Amount1   string `json:"amount1"`
+}
+
+// SetFeeProtocolEventData is the decoded SetFeeProtocol event payload.
+// Uniswap V3 pools take 1/FeeProtocol of the fees as protocol fees.
+// PancakeSwap V3 pools take FeeProtocol/Denominator, and set Denominator.
+type SetFeeProtocolEventData struct {
+	FeeProtocol0Old uint32 `json:"fee_protocol0_old"`
+	FeeProtocol1Old uint32 `json:"fee_protocol1_old"`
+	FeeProtocol0New uint32 `json:"fee_protocol0_new"`
+	FeeProtocol1New uint32 `json:"fee_protocol1_new"`
+	Denominator     uint32 `json:"denominator,omitempty"`
+}
+
+// IncreaseObservationCardinalityNextEventData is the decoded
+// IncreaseObservationCardinalityNext event payload.
+type IncreaseObservationCardinalityNextEventData struct {
+	ObservationCardinalityNextOld uint16 `json:"observation_cardinality_next_old"`
+	ObservationCardinalityNextNew uint16 `json:"observation_cardinality_next_new"`
+}
@@ -1,6 +1,7 @@
 package model
 
-// Pool represents a V3 pool metadata record for storage.
+// Pool represents a pool metadata record for storage. Address is the pool
+// contract address, or the poolId of a V4 pool.
 type Pool struct {
 	ChainID        uint64 `json:"chain_id"`
 	Address        string `json:"address"`
@@ -8,5 +9,7 @@ type Pool struct {
 	Token1         string `json:"token1"`
 	Fee            uint32 `json:"fee"`
 	TickSpacing    int32  `json:"tick_spacing"`
+	Protocol       string `json:"protocol,omitempty"`
+	Hooks          string `json:"hooks,omitempty"`
 	FirstSeenBlock uint64 `json:"first_seen_block"`
 }
@@ -1,13 +1,43 @@
 package model
 
+// Pool protocols other than V3, which is the default.
+const (
+	ProtocolV2      = "v2"
+	ProtocolV4      = "v4"
+	ProtocolAlgebra = "algebra"
+)
+
 // PoolMeta captures immutable pool metadata with optional live fields.
+// DynamicFee marks pools whose fee changes over time, for which Fee is only a
+// starting value (0 when unknown), and Hooks is the hooks contract of a V4
+// pool. Pools with directional fees (Camelot) charge Fee on zero-for-one swaps
+// and FeeOtz on one-for-zero swaps; other pools leave FeeOtz 0.
+// FeeProtocol0 and FeeProtocol1 are the fee protocol of a V3 pool at the same
+// point as the starting fee, 0 when unknown: Uniswap V3 pools take
+// 1/FeeProtocol of the fees, PancakeSwap V3 pools FeeProtocol/
+// FeeProtocolDenominator.
 type PoolMeta struct {
-	Token0      string     `json:"token0"`
-	Token1      string     `json:"token1"`
-	Fee         uint32     `json:"fee"`
-	TickSpacing int32      `json:"tick_spacing"`
-	Liquidity   string     `json:"liquidity,omitempty"`
-	Slot0       *PoolSlot0 `json:"slot0,omitempty"`
+	Token0                 string     `json:"token0"`
+	Token1                 string     `json:"token1"`
+	Fee                    uint32     `json:"fee"`
+	FeeOtz                 uint32     `json:"fee_otz,omitempty"`
+	TickSpacing            int32      `json:"tick_spacing"`
+	Protocol               string     `json:"protocol,omitempty"`
+	DynamicFee             bool       `json:"dynamic_fee,omitempty"`
+	Hooks                  string     `json:"hooks,omitempty"`
+	FeeProtocol0           uint32     `json:"fee_protocol0,omitempty"`
+	FeeProtocol1           uint32     `json:"fee_protocol1,omitempty"`
+	FeeProtocolDenominator uint32     `json:"fee_protocol_denominator,omitempty"`
+	Liquidity              string     `json:"liquidity,omitempty"`
+	Slot0                  *PoolSlot0 `json:"slot0,omitempty"`
+}
+
+// SwapFee returns the starting fee of a swap in the given direction.
+func (m PoolMeta) SwapFee(zeroForOne bool) uint32 {
+	if !zeroForOne && m.FeeOtz != 0 {
+		return m.FeeOtz
+	}
+	return m.Fee
 }
 
 // PoolSlot0 includes select slot0 fields.
@@ -14,6 +14,8 @@ type PoolWindowMetrics struct {
 	Volume1          string
 	Fee0             string
 	Fee1             string
+	ProtocolFee0     string
+	ProtocolFee1     string
 	FeeUSD           *string
 	FeeRate0         *string
 	FeeRate1         *string
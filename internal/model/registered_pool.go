@@ -0,0 +1,23 @@
+package model
+
+// RegisteredPool is a pool discovered from a factory PoolCreated event.
+type RegisteredPool struct {
+	Address      string `json:"address"`
+	Factory      string `json:"factory"`
+	Token0       string `json:"token0"`
+	Token1       string `json:"token1"`
+	Fee          uint32 `json:"fee"`
+	TickSpacing  int32  `json:"tick_spacing"`
+	CreatedBlock uint64 `json:"created_block"`
+	TxHash       string `json:"tx_hash"`
+}
+
+// Meta returns the immutable pool metadata carried by the registry entry.
+func (p RegisteredPool) Meta() PoolMeta {
+	return PoolMeta{
+		Token0:      p.Token0,
+		Token1:      p.Token1,
+		Fee:         p.Fee,
+		TickSpacing: p.TickSpacing,
+	}
+}
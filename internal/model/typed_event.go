@@ -1,6 +1,7 @@
 package model
 
-// TypedEvent is a decoded pool event enriched with metadata.
+// TypedEvent is a decoded pool event enriched with metadata. PoolID identifies
+// V4 pools, which all emit from the PoolManager address.
 type TypedEvent struct {
 	ChainID     uint64      `json:"chain_id"`
 	BlockNumber uint64      `json:"block_number"`
@@ -8,11 +9,13 @@ type TypedEvent struct {
 	TxHash      string      `json:"tx_hash"`
 	LogIndex    uint64      `json:"log_index"`
 	Address     string      `json:"address"`
+	PoolID      string      `json:"pool_id,omitempty"`
 	EventName   string      `json:"event_name"`
 	Timestamp   uint64      `json:"timestamp"`
 	Decoded     interface{} `json:"decoded"`
 	PoolMeta    PoolMeta    `json:"pool_meta"`
 	Raw         *RawLogRef  `json:"raw,omitempty"`
+	Removed     bool        `json:"removed,omitempty"`
 }
 
 // RawLogRef keeps a minimal raw reference for traceability.
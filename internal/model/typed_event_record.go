@@ -10,9 +10,20 @@ type TypedEventRecord struct {
 	TxHash      string          `json:"tx_hash"`
 	LogIndex    uint64          `json:"log_index"`
 	Address     string          `json:"address"`
+	PoolID      string          `json:"pool_id,omitempty"`
 	EventName   string          `json:"event_name"`
 	Timestamp   uint64          `json:"timestamp"`
 	Decoded     json.RawMessage `json:"decoded"`
 	PoolMeta    PoolMeta        `json:"pool_meta"`
 	Raw         *RawLogRef      `json:"raw,omitempty"`
+	Removed     bool            `json:"removed,omitempty"`
+}
+
+// Pool returns the identity of the pool that emitted the event: its poolId for
+// V4 pools and its contract address otherwise.
+func (r TypedEventRecord) Pool() string {
+	if r.PoolID != "" {
+		return r.PoolID
+	}
+	return r.Address
 }
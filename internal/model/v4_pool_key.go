@@ -0,0 +1,40 @@
+package model
+
+// V4DynamicFeeFlag is the pool key fee of V4 pools whose fee is set by hooks.
+const V4DynamicFeeFlag uint32 = 0x800000
+
+// V4PoolKey is the key of a V4 pool, registered from its Initialize event.
+type V4PoolKey struct {
+	PoolID           string `json:"pool_id"`
+	Manager          string `json:"manager"`
+	Currency0        string `json:"currency0"`
+	Currency1        string `json:"currency1"`
+	Fee              uint32 `json:"fee"`
+	TickSpacing      int32  `json:"tick_spacing"`
+	Hooks            string `json:"hooks"`
+	InitializedBlock uint64 `json:"initialized_block"`
+	TxHash           string `json:"tx_hash"`
+}
+
+// DynamicFee reports whether the pool fee is set per swap by its hooks.
+func (k V4PoolKey) DynamicFee() bool {
+	return k.Fee == V4DynamicFeeFlag
+}
+
+// Meta returns the pool metadata carried by the key. Dynamic-fee pools have
+// no fixed fee.
+func (k V4PoolKey) Meta() PoolMeta {
+	meta := PoolMeta{
+		Token0:      k.Currency0,
+		Token1:      k.Currency1,
+		Fee:         k.Fee,
+		TickSpacing: k.TickSpacing,
+		Protocol:    ProtocolV4,
+		Hooks:       k.Hooks,
+	}
+	if k.DynamicFee() {
+		meta.Fee = 0
+		meta.DynamicFee = true
+	}
+	return meta
+}
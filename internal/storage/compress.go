@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the codec of a JSONL file, chosen by its extension.
+type Compression int
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionZstd
+)
+
+// CompressionFor returns the codec for path: .gz is gzip, .zst is zstd and
+// anything else is plain text.
+func CompressionFor(path string) Compression {
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		return CompressionGzip
+	case strings.HasSuffix(path, ".zst"):
+		return CompressionZstd
+	default:
+		return CompressionNone
+	}
+}
+
+// NewFrameWriter starts one compressed frame on w. Closing the returned
+// writer ends the frame without closing w, so every batch appended to a file
+// is an independent gzip member or zstd frame and the file stays readable
+// when cut at a batch boundary.
+func NewFrameWriter(w io.Writer, compression Compression) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		encoder, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, fmt.Errorf("create zstd writer: %w", err)
+		}
+		return encoder, nil
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
+
+// NewDecompressor reads all frames of r as one stream.
+func NewDecompressor(r io.Reader, compression Compression) (io.ReadCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		reader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("open gzip reader: %w", err)
+		}
+		return reader, nil
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("open zstd reader: %w", err)
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
+
+// OpenFile opens a JSONL file for reading, decompressing it by extension.
+func OpenFile(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if stat.Size() == 0 {
+		// An empty compressed file has no header yet; read it as empty.
+		return file, nil
+	}
+	reader, err := NewDecompressor(file, CompressionFor(path))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileReader{ReadCloser: reader, file: file}, nil
+}
+
+type fileReader struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (r *fileReader) Close() error {
+	r.ReadCloser.Close()
+	return r.file.Close()
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
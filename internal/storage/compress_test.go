@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"liquidityScope/internal/model"
+)
+
+func TestJsonlStorageCompressedBatches(t *testing.T) {
+	for _, name := range []string{"logs.jsonl.gz", "logs.jsonl.zst"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			store := NewJsonlStorage(path)
+
+			if err := store.PutLogBatch([]model.LogRecord{{BlockNumber: 1}, {BlockNumber: 2}}); err != nil {
+				t.Fatalf("put batch: %v", err)
+			}
+			committed, err := store.Position()
+			if err != nil {
+				t.Fatalf("position: %v", err)
+			}
+			if err := store.PutLogBatch([]model.LogRecord{{BlockNumber: 3}}); err != nil {
+				t.Fatalf("put batch: %v", err)
+			}
+
+			tail, err := store.Tail(2)
+			if err != nil {
+				t.Fatalf("tail: %v", err)
+			}
+			if len(tail) != 2 || tail[0].BlockNumber != 2 || tail[1].BlockNumber != 3 {
+				t.Fatalf("unexpected tail across frames: %+v", tail)
+			}
+
+			// Cutting at a batch boundary leaves a readable file.
+			if err := store.Truncate(committed); err != nil {
+				t.Fatalf("truncate: %v", err)
+			}
+			if err := store.PutLogBatch([]model.LogRecord{{BlockNumber: 4}}); err != nil {
+				t.Fatalf("put batch: %v", err)
+			}
+			input, err := OpenInput(path, SegmentFilter{})
+			if err != nil {
+				t.Fatalf("open input: %v", err)
+			}
+			defer input.Close()
+			data, err := io.ReadAll(input)
+			if err != nil {
+				t.Fatalf("read input: %v", err)
+			}
+			var blocks []uint64
+			for _, line := range bytes.Split(bytes.TrimSpace(data), []byte{'\n'}) {
+				var record model.LogRecord
+				if err := json.Unmarshal(line, &record); err != nil {
+					t.Fatalf("parse: %v", err)
+				}
+				blocks = append(blocks, record.BlockNumber)
+			}
+			if len(blocks) != 3 || blocks[0] != 1 || blocks[1] != 2 || blocks[2] != 4 {
+				t.Fatalf("unexpected blocks after resume: %v", blocks)
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if CompressionFor(path) != CompressionNone && len(raw) > 0 && raw[0] == '{' {
+				t.Fatalf("expected compressed output")
+			}
+		})
+	}
+}
+
+func TestJsonlStorageCompressedTailSeeks(t *testing.T) {
+	for _, name := range []string{"logs.jsonl.gz", "logs.jsonl.zst"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			store := NewJsonlStorage(path)
+			for block := uint64(1); block <= 3; block++ {
+				if err := store.PutLogBatch([]model.LogRecord{{BlockNumber: block}}); err != nil {
+					t.Fatalf("put batch: %v", err)
+				}
+			}
+			committed, err := store.Position()
+			if err != nil {
+				t.Fatalf("position: %v", err)
+			}
+			if err := store.PutLogBatch([]model.LogRecord{{BlockNumber: 9}}); err != nil {
+				t.Fatalf("put batch: %v", err)
+			}
+			if err := store.Truncate(committed); err != nil {
+				t.Fatalf("truncate: %v", err)
+			}
+			if err := store.PutLogBatch([]model.LogRecord{{BlockNumber: 4}}); err != nil {
+				t.Fatalf("put batch: %v", err)
+			}
+
+			// Tail must not read the first frame, so breaking it goes unnoticed.
+			file, err := os.OpenFile(path, os.O_WRONLY, 0o644)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			if _, err := file.WriteAt([]byte{0, 0, 0, 0}, 0); err != nil {
+				t.Fatalf("corrupt first frame: %v", err)
+			}
+			file.Close()
+
+			tail, err := store.Tail(2)
+			if err != nil {
+				t.Fatalf("tail: %v", err)
+			}
+			if len(tail) != 2 || tail[0].BlockNumber != 3 || tail[1].BlockNumber != 4 {
+				t.Fatalf("unexpected tail: %+v", tail)
+			}
+		})
+	}
+}
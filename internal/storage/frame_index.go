@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// frameIndexSuffix names the sidecar that indexes the frames of a compressed
+// JSONL file.
+const frameIndexSuffix = ".frames"
+
+// frameEntrySize is the size of one frame index entry: the frame's offset in
+// the output and the newest block it holds.
+const frameEntrySize = 16
+
+// frameEntry locates one compressed frame.
+type frameEntry struct {
+	offset   int64
+	maxBlock uint64
+}
+
+func frameIndexPath(path string) string {
+	return path + frameIndexSuffix
+}
+
+// appendFrameEntry records a frame written to the output at path.
+func appendFrameEntry(path string, entry frameEntry) error {
+	file, err := os.OpenFile(frameIndexPath(path), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open frame index: %w", err)
+	}
+	defer file.Close()
+
+	var buf [frameEntrySize]byte
+	binary.BigEndian.PutUint64(buf[:8], uint64(entry.offset))
+	binary.BigEndian.PutUint64(buf[8:], entry.maxBlock)
+	if _, err := file.Write(buf[:]); err != nil {
+		return fmt.Errorf("write frame index: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("sync frame index: %w", err)
+	}
+	return nil
+}
+
+// truncateFrameIndex drops the entries of frames at or after offset.
+func truncateFrameIndex(path string, offset int64) error {
+	file, err := os.OpenFile(frameIndexPath(path), os.O_RDWR, 0o644)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open frame index: %w", err)
+	}
+	defer file.Close()
+
+	entries, err := readFrameIndex(file)
+	if err != nil {
+		return err
+	}
+	kept := len(entries)
+	for kept > 0 && entries[kept-1].offset >= offset {
+		kept--
+	}
+	if kept == len(entries) {
+		return nil
+	}
+	if err := file.Truncate(int64(kept) * frameEntrySize); err != nil {
+		return fmt.Errorf("truncate frame index: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("sync frame index: %w", err)
+	}
+	return nil
+}
+
+// tailFrameOffset returns the offset of the first frame that may hold blocks
+// within blocks of the newest indexed block, walking the index back from its
+// end. Entries past size belong to output that was cut back. Without an index
+// the whole output is read from 0.
+func tailFrameOffset(path string, blocks uint64, size int64) (int64, error) {
+	file, err := os.Open(frameIndexPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("open frame index: %w", err)
+	}
+	defer file.Close()
+
+	entries, err := readFrameIndex(file)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		start  int64
+		newest uint64
+	)
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.offset >= size {
+			continue
+		}
+		newest = max(newest, entry.maxBlock)
+		if entry.maxBlock+blocks <= newest {
+			return start, nil
+		}
+		start = entry.offset
+	}
+	// Frames before the first entry, if any, were written unindexed.
+	return 0, nil
+}
+
+func readFrameIndex(file *os.File) ([]frameEntry, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat frame index: %w", err)
+	}
+	// A torn last entry is ignored.
+	buf := make([]byte, stat.Size()/frameEntrySize*frameEntrySize)
+	if _, err := file.ReadAt(buf, 0); err != nil {
+		return nil, fmt.Errorf("read frame index: %w", err)
+	}
+	entries := make([]frameEntry, 0, len(buf)/frameEntrySize)
+	for i := 0; i < len(buf); i += frameEntrySize {
+		entries = append(entries, frameEntry{
+			offset:   int64(binary.BigEndian.Uint64(buf[i : i+8])),
+			maxBlock: binary.BigEndian.Uint64(buf[i+8 : i+16]),
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// SegmentFilter selects the segments of a partitioned input. Zero fields do
+// not restrict.
+type SegmentFilter struct {
+	FromBlock uint64
+	ToBlock   uint64
+	// AfterTimestamp skips segments whose records are all at or before it.
+	AfterTimestamp uint64
+}
+
+func (f SegmentFilter) includes(segment Segment) bool {
+	if f.FromBlock > 0 && segment.ToBlock < f.FromBlock {
+		return false
+	}
+	if f.ToBlock > 0 && segment.FromBlock > f.ToBlock {
+		return false
+	}
+	if f.AfterTimestamp > 0 && segment.MaxTimestamp <= f.AfterTimestamp {
+		return false
+	}
+	return true
+}
+
+// StdioPath selects stdin as input or stdout as output.
+const StdioPath = "-"
+
+// Input reads a JSONL input that is either a single file, stdin, or the
+// segments of a partitioned output, given as its directory or manifest. Files
+// ending in .gz or .zst are decompressed.
+type Input struct {
+	// Files are the files read, in order.
+	Files []string
+	// Skipped counts manifest segments excluded by the filter.
+	Skipped int
+
+	current io.ReadCloser
+	next    int
+}
+
+// OpenInput resolves path and selects the segments matching filter. A plain
+// file or stdin is always read in full.
+func OpenInput(path string, filter SegmentFilter) (*Input, error) {
+	if path == StdioPath {
+		return &Input{Files: []string{path}}, nil
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("open input: %w", err)
+	}
+
+	manifestPath := ""
+	if stat.IsDir() {
+		manifestPath = filepath.Join(path, ManifestName)
+	} else if filepath.Base(path) == ManifestName {
+		manifestPath = path
+	}
+	if manifestPath == "" {
+		return &Input{Files: []string{path}}, nil
+	}
+
+	if _, err := os.Stat(manifestPath); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("open input: no %s in %s", ManifestName, filepath.Dir(manifestPath))
+	}
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	in := &Input{}
+	dir := filepath.Dir(manifestPath)
+	for _, segment := range manifest.Segments {
+		if !filter.includes(segment) {
+			in.Skipped++
+			continue
+		}
+		in.Files = append(in.Files, filepath.Join(dir, filepath.FromSlash(segment.Path)))
+	}
+	return in, nil
+}
+
+// Read reads the selected files back to back.
+func (in *Input) Read(p []byte) (int, error) {
+	for {
+		if in.current == nil {
+			if in.next >= len(in.Files) {
+				return 0, io.EOF
+			}
+			file, err := openInputFile(in.Files[in.next])
+			if err != nil {
+				return 0, fmt.Errorf("open input: %w", err)
+			}
+			in.current = file
+			in.next++
+		}
+
+		n, err := in.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			in.current.Close()
+			in.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func openInputFile(path string) (io.ReadCloser, error) {
+	if path == StdioPath {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return OpenFile(path)
+}
+
+// Close releases the file being read.
+func (in *Input) Close() error {
+	if in.current == nil {
+		return nil
+	}
+	err := in.current.Close()
+	in.current = nil
+	return err
+}
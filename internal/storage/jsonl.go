@@ -2,8 +2,11 @@ package storage
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -11,7 +14,12 @@ import (
 	"liquidityScope/internal/model"
 )
 
-// JsonlStorage writes log records to a JSONL file.
+// tailChunkSize is how much of the output Tail reads per step from the end.
+const tailChunkSize = 64 << 10
+
+// JsonlStorage writes log records to a JSONL file. Paths ending in .gz or .zst
+// are compressed, one gzip member or zstd frame per batch, and every frame is
+// indexed in a .frames sidecar so Tail can seek to the last ones.
 type JsonlStorage struct {
 	path string
 	mu   sync.Mutex
@@ -42,8 +50,68 @@ func (s *JsonlStorage) PutLogBatch(logs []model.LogRecord) error {
 		return fmt.Errorf("open output file: %w", err)
 	}
 	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat output file: %w", err)
+	}
+
+	compression := CompressionFor(s.path)
+	if compression != CompressionNone && stat.Size() == 0 {
+		// A new output starts a new index.
+		if err := truncateFrameIndex(s.path, 0); err != nil {
+			return err
+		}
+	}
+	frame, err := NewFrameWriter(file, compression)
+	if err != nil {
+		return err
+	}
+	if err := writeLogLines(frame, logs); err != nil {
+		return err
+	}
+	if err := frame.Close(); err != nil {
+		return fmt.Errorf("close output frame: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("sync output: %w", err)
+	}
+
+	if compression != CompressionNone {
+		entry := frameEntry{offset: stat.Size()}
+		for _, record := range logs {
+			entry.maxBlock = max(entry.maxBlock, record.BlockNumber)
+		}
+		return appendFrameEntry(s.path, entry)
+	}
+	return nil
+}
+
+// JsonlStreamStorage writes log records as JSON lines to a stream such as
+// stdout. It has no position, so checkpoints carry no output offset and
+// nothing is cut back on resume.
+type JsonlStreamStorage struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func NewJsonlStreamStorage(w io.Writer) *JsonlStreamStorage {
+	return &JsonlStreamStorage{w: w}
+}
+
+// PutLogBatch writes a batch and flushes it, blocking while the reader is
+// behind.
+func (s *JsonlStreamStorage) PutLogBatch(logs []model.LogRecord) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeLogLines(s.w, logs)
+}
 
-	writer := bufio.NewWriter(file)
+func writeLogLines(w io.Writer, logs []model.LogRecord) error {
+	writer := bufio.NewWriter(w)
 	for _, record := range logs {
 		line, err := json.Marshal(record)
 		if err != nil {
@@ -56,10 +124,227 @@ func (s *JsonlStorage) PutLogBatch(logs []model.LogRecord) error {
 			return fmt.Errorf("write newline: %w", err)
 		}
 	}
-
 	if err := writer.Flush(); err != nil {
 		return fmt.Errorf("flush output: %w", err)
 	}
+	return nil
+}
+
+// Position returns the size of the output file, 0 if it does not exist yet.
+func (s *JsonlStorage) Position() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stat, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("stat output file: %w", err)
+	}
+	return stat.Size(), nil
+}
+
+// Truncate cuts the output file back to offset, dropping lines appended after
+// the last checkpoint. A file shorter than offset means output was lost and is
+// reported as an error.
+func (s *JsonlStorage) Truncate(offset int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	// The index is cut first, so an interrupted truncate never leaves entries
+	// for frames that are gone.
+	if CompressionFor(s.path) != CompressionNone {
+		if err := truncateFrameIndex(s.path, offset); err != nil {
+			return err
+		}
+	}
+
+	stat, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		if offset == 0 {
+			return nil
+		}
+		return fmt.Errorf("output file %s is missing, checkpoint expects %d bytes", s.path, offset)
+	}
+	if err != nil {
+		return fmt.Errorf("stat output file: %w", err)
+	}
+	if stat.Size() < offset {
+		return fmt.Errorf("output file %s has %d bytes, checkpoint expects %d", s.path, stat.Size(), offset)
+	}
+	if stat.Size() == offset {
+		return nil
+	}
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open output file: %w", err)
+	}
+	defer file.Close()
+	if err := file.Truncate(offset); err != nil {
+		return fmt.Errorf("truncate output: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("sync output: %w", err)
+	}
 	return nil
 }
+
+// Tail reads the output backwards and returns the records whose block is
+// within blocks of the newest block, in file order. Compressed files cannot
+// be read backwards and are scanned from the first frame the index places in
+// that window.
+func (s *JsonlStorage) Tail(blocks uint64) ([]model.LogRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if CompressionFor(s.path) != CompressionNone {
+		return s.tailFrames(blocks)
+	}
+
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open output file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat output file: %w", err)
+	}
+
+	var (
+		records []model.LogRecord
+		newest  uint64
+		found   bool
+		partial []byte
+	)
+	offset := stat.Size()
+	for offset > 0 {
+		size := int64(tailChunkSize)
+		if size > offset {
+			size = offset
+		}
+		offset -= size
+
+		buf := make([]byte, size, size+int64(len(partial)))
+		if _, err := file.ReadAt(buf, offset); err != nil {
+			return nil, fmt.Errorf("read output tail: %w", err)
+		}
+		buf = append(buf, partial...)
+
+		lines := bytes.Split(buf, []byte{'\n'})
+		first := 0
+		if offset > 0 {
+			// The first line may continue in the previous chunk.
+			partial = lines[0]
+			first = 1
+		}
+		for i := len(lines) - 1; i >= first; i-- {
+			line := bytes.TrimSpace(lines[i])
+			if len(line) == 0 {
+				continue
+			}
+			var record model.LogRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				return nil, fmt.Errorf("parse output tail: %w", err)
+			}
+			if !found {
+				newest = record.BlockNumber
+				found = true
+			}
+			if record.BlockNumber+blocks <= newest {
+				return reverseRecords(records), nil
+			}
+			records = append(records, record)
+		}
+	}
+	return reverseRecords(records), nil
+}
+
+func reverseRecords(records []model.LogRecord) []model.LogRecord {
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records
+}
+
+// tailFrames decompresses the output from the first frame that may hold
+// records near the newest block, as found in the frame index.
+func (s *JsonlStorage) tailFrames(blocks uint64) ([]model.LogRecord, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open output file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat output file: %w", err)
+	}
+	if stat.Size() == 0 || blocks == 0 {
+		return nil, nil
+	}
+	start, err := tailFrameOffset(s.path, blocks, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek output tail: %w", err)
+	}
+	reader, err := NewDecompressor(file, CompressionFor(s.path))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return tailForward(reader, blocks)
+}
+
+// tailForward scans reader to the end, keeping only records near the newest
+// block seen so far.
+func tailForward(reader io.Reader, blocks uint64) ([]model.LogRecord, error) {
+	var (
+		records []model.LogRecord
+		newest  uint64
+	)
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record model.LogRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("parse output tail: %w", err)
+		}
+		records = append(records, record)
+		if record.BlockNumber > newest {
+			newest = record.BlockNumber
+			// Drop the prefix that fell out of the window.
+			start := 0
+			for start < len(records) && records[start].BlockNumber+blocks <= newest {
+				start++
+			}
+			records = append(records[:0], records[start:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan output tail: %w", err)
+	}
+
+	kept := records[:0]
+	for _, record := range records {
+		if record.BlockNumber+blocks > newest {
+			kept = append(kept, record)
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"liquidityScope/internal/model"
+)
+
+func TestJsonlStorageTruncateToPosition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	store := NewJsonlStorage(path)
+
+	if offset, err := store.Position(); err != nil || offset != 0 {
+		t.Fatalf("expected empty position, got %d (%v)", offset, err)
+	}
+	if err := store.PutLogBatch([]model.LogRecord{{BlockNumber: 1}}); err != nil {
+		t.Fatalf("put batch: %v", err)
+	}
+	committed, err := store.Position()
+	if err != nil {
+		t.Fatalf("position: %v", err)
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+
+	if err := store.PutLogBatch([]model.LogRecord{{BlockNumber: 2}}); err != nil {
+		t.Fatalf("put batch: %v", err)
+	}
+	if err := store.Truncate(committed); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("output mismatch after truncate:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := store.Truncate(committed + 1); err == nil {
+		t.Fatalf("expected error when output is shorter than checkpoint")
+	}
+}
+
+func TestJsonlStorageTail(t *testing.T) {
+	store := NewJsonlStorage(filepath.Join(t.TempDir(), "logs.jsonl"))
+
+	var batch []model.LogRecord
+	for block := uint64(1); block <= 100; block++ {
+		for index := uint64(0); index < 5; index++ {
+			batch = append(batch, model.LogRecord{BlockNumber: block, LogIndex: index, TxHash: "0x" + strings.Repeat("ab", 32)})
+		}
+	}
+	if err := store.PutLogBatch(batch); err != nil {
+		t.Fatalf("put batch: %v", err)
+	}
+
+	tail, err := store.Tail(3)
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if len(tail) != 15 {
+		t.Fatalf("expected 15 records, got %d", len(tail))
+	}
+	if tail[0].BlockNumber != 98 || tail[0].LogIndex != 0 || tail[14].BlockNumber != 100 || tail[14].LogIndex != 4 {
+		t.Fatalf("unexpected tail bounds: %+v .. %+v", tail[0], tail[14])
+	}
+
+	all, err := store.Tail(1000)
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if len(all) != len(batch) {
+		t.Fatalf("expected %d records, got %d", len(batch), len(all))
+	}
+}
+
+func TestJsonlStreamStorageThroughPipe(t *testing.T) {
+	reader, writer := io.Pipe()
+	store := NewJsonlStreamStorage(writer)
+
+	done := make(chan error, 1)
+	go func() {
+		for block := uint64(1); block <= 3; block++ {
+			if err := store.PutLogBatch([]model.LogRecord{{BlockNumber: block}}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- writer.Close()
+	}()
+
+	var blocks []uint64
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var record model.LogRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("parse line: %v", err)
+		}
+		blocks = append(blocks, record.BlockNumber)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("write stream: %v", err)
+	}
+	if len(blocks) != 3 || blocks[0] != 1 || blocks[2] != 3 {
+		t.Fatalf("unexpected blocks %v", blocks)
+	}
+}
@@ -0,0 +1,325 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"liquidityScope/internal/model"
+)
+
+// Output formats.
+const (
+	FormatJSONL   = "jsonl"
+	FormatParquet = "parquet"
+)
+
+// parquetRowGroupSize disables size-based row group flushing so row groups
+// are cut only at block range boundaries.
+const parquetRowGroupSize = 1 << 40
+
+// LogRow is the Parquet schema of a LogRecord. Topics are spread over four
+// nullable columns so they can be filtered without unnesting.
+type LogRow struct {
+	ChainID     int64   `parquet:"name=chain_id, type=INT64, convertedtype=UINT_64"`
+	BlockNumber int64   `parquet:"name=block_number, type=INT64, convertedtype=UINT_64"`
+	BlockHash   string  `parquet:"name=block_hash, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TxHash      string  `parquet:"name=tx_hash, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TxIndex     int64   `parquet:"name=tx_index, type=INT64, convertedtype=UINT_64"`
+	LogIndex    int64   `parquet:"name=log_index, type=INT64, convertedtype=UINT_64"`
+	Address     string  `parquet:"name=address, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Topic0      *string `parquet:"name=topic0, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, repetitiontype=OPTIONAL"`
+	Topic1      *string `parquet:"name=topic1, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Topic2      *string `parquet:"name=topic2, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Topic3      *string `parquet:"name=topic3, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Data        string  `parquet:"name=data, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Removed     bool    `parquet:"name=removed, type=BOOLEAN"`
+	Timestamp   int64   `parquet:"name=timestamp, type=INT64, convertedtype=UINT_64"`
+	IngestedAt  string  `parquet:"name=ingested_at, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+func newLogRow(record model.LogRecord) LogRow {
+	row := LogRow{
+		ChainID:     int64(record.ChainID),
+		BlockNumber: int64(record.BlockNumber),
+		BlockHash:   record.BlockHash,
+		TxHash:      record.TxHash,
+		TxIndex:     int64(record.TxIndex),
+		LogIndex:    int64(record.LogIndex),
+		Address:     record.Address,
+		Data:        record.Data,
+		Removed:     record.Removed,
+		Timestamp:   int64(record.Timestamp),
+		IngestedAt:  record.IngestedAt,
+	}
+	topics := []**string{&row.Topic0, &row.Topic1, &row.Topic2, &row.Topic3}
+	for i, topic := range record.Topics {
+		if i >= len(topics) {
+			break
+		}
+		value := topic
+		*topics[i] = &value
+	}
+	return row
+}
+
+// Record converts the row back to a LogRecord.
+func (row LogRow) Record() model.LogRecord {
+	record := model.LogRecord{
+		ChainID:     uint64(row.ChainID),
+		BlockNumber: uint64(row.BlockNumber),
+		BlockHash:   row.BlockHash,
+		TxHash:      row.TxHash,
+		TxIndex:     uint64(row.TxIndex),
+		LogIndex:    uint64(row.LogIndex),
+		Address:     row.Address,
+		Topics:      []string{},
+		Data:        row.Data,
+		Removed:     row.Removed,
+		Timestamp:   uint64(row.Timestamp),
+		IngestedAt:  row.IngestedAt,
+	}
+	for _, topic := range []*string{row.Topic0, row.Topic1, row.Topic2, row.Topic3} {
+		if topic == nil {
+			break
+		}
+		record.Topics = append(record.Topics, *topic)
+	}
+	return record
+}
+
+// parquetFile writes rows of one schema and starts a new row group whenever
+// the block number crosses a multiple of rowGroupBlocks.
+type parquetFile struct {
+	pw             *writer.ParquetWriter
+	rowGroupBlocks uint64
+	bucket         uint64
+	rows           int
+}
+
+func newParquetFile(w io.Writer, schema interface{}, rowGroupBlocks uint64) (*parquetFile, error) {
+	pw, err := writer.NewParquetWriterFromWriter(w, schema, 1)
+	if err != nil {
+		return nil, fmt.Errorf("create parquet writer: %w", err)
+	}
+	pw.RowGroupSize = parquetRowGroupSize
+	return &parquetFile{pw: pw, rowGroupBlocks: rowGroupBlocks}, nil
+}
+
+func (f *parquetFile) write(blockNumber uint64, row interface{}) error {
+	bucket := blockNumber
+	if f.rowGroupBlocks > 0 {
+		bucket = blockNumber / f.rowGroupBlocks
+	}
+	if f.rows > 0 && bucket != f.bucket {
+		if err := f.pw.Flush(true); err != nil {
+			return fmt.Errorf("flush parquet row group: %w", err)
+		}
+	}
+	f.bucket = bucket
+	f.rows++
+	if err := f.pw.Write(row); err != nil {
+		return fmt.Errorf("write parquet row: %w", err)
+	}
+	return nil
+}
+
+func (f *parquetFile) close() error {
+	if err := f.pw.WriteStop(); err != nil {
+		return fmt.Errorf("finish parquet file: %w", err)
+	}
+	return nil
+}
+
+// ParquetStorage writes every batch of log records to its own Parquet file
+// (part-000001.parquet, ...) under a directory, since Parquet files cannot be
+// appended to. Row groups within a part are aligned to rowGroupBlocks.
+type ParquetStorage struct {
+	dir            string
+	rowGroupBlocks uint64
+	mu             sync.Mutex
+	parts          int
+}
+
+func NewParquetStorage(dir string, rowGroupBlocks uint64) (*ParquetStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create output dir: %w", err)
+	}
+	s := &ParquetStorage{dir: dir, rowGroupBlocks: rowGroupBlocks}
+	parts, err := s.listParts()
+	if err != nil {
+		return nil, err
+	}
+	s.parts = len(parts)
+	return s, nil
+}
+
+// PutLogBatch writes a batch as the next part. The part is renamed into
+// place only once complete.
+func (s *ParquetStorage) PutLogBatch(logs []model.LogRecord) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.partPath(s.parts + 1)
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create parquet part: %w", err)
+	}
+	defer file.Close()
+
+	out, err := newParquetFile(file, new(LogRow), s.rowGroupBlocks)
+	if err != nil {
+		return err
+	}
+	for _, record := range logs {
+		if err := out.write(record.BlockNumber, newLogRow(record)); err != nil {
+			return err
+		}
+	}
+	if err := out.close(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("sync parquet part: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("rename parquet part: %w", err)
+	}
+	s.parts++
+	return nil
+}
+
+// Position returns the number of parts written.
+func (s *ParquetStorage) Position() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(s.parts), nil
+}
+
+// Truncate removes every part after the first offset parts.
+func (s *ParquetStorage) Truncate(offset int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if int64(s.parts) < offset {
+		return fmt.Errorf("parquet output %s has %d parts, checkpoint expects %d", s.dir, s.parts, offset)
+	}
+	for seq := s.parts; int64(seq) > offset; seq-- {
+		if err := os.Remove(s.partPath(seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove parquet part: %w", err)
+		}
+	}
+	s.parts = int(offset)
+	return nil
+}
+
+// Tail reads parts from the newest backwards and returns the records of the
+// last blocks blocks in write order.
+func (s *ParquetStorage) Tail(blocks uint64) ([]model.LogRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		parts  [][]model.LogRecord
+		newest uint64
+	)
+	for seq := s.parts; seq > 0 && blocks > 0; seq-- {
+		records, err := readLogParquet(s.partPath(seq))
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			continue
+		}
+		oldest := records[0].BlockNumber
+		for _, record := range records {
+			newest = max(newest, record.BlockNumber)
+			oldest = min(oldest, record.BlockNumber)
+		}
+		parts = append(parts, records)
+		if oldest+blocks <= newest {
+			break
+		}
+	}
+
+	var out []model.LogRecord
+	for i := len(parts) - 1; i >= 0; i-- {
+		for _, record := range parts[i] {
+			if record.BlockNumber+blocks > newest {
+				out = append(out, record)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *ParquetStorage) partPath(seq int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("part-%06d.parquet", seq))
+}
+
+// listParts returns the part sequence numbers in order and checks that they
+// are contiguous.
+func (s *ParquetStorage) listParts() ([]int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("list parquet parts: %w", err)
+	}
+	var seqs []int
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "part-") || !strings.HasSuffix(name, ".parquet") {
+			continue
+		}
+		seq, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "part-"), ".parquet"))
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Ints(seqs)
+	for i, seq := range seqs {
+		if seq != i+1 {
+			return nil, fmt.Errorf("parquet parts in %s are not contiguous at part %d", s.dir, i+1)
+		}
+	}
+	return seqs, nil
+}
+
+func readLogParquet(path string) ([]model.LogRecord, error) {
+	file, err := local.NewLocalFileReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("open parquet part: %w", err)
+	}
+	defer file.Close()
+
+	pr, err := reader.NewParquetReader(file, new(LogRow), 1)
+	if err != nil {
+		return nil, fmt.Errorf("read parquet part %s: %w", path, err)
+	}
+	defer pr.ReadStop()
+
+	rows := make([]LogRow, pr.GetNumRows())
+	if err := pr.Read(&rows); err != nil {
+		return nil, fmt.Errorf("read parquet rows %s: %w", path, err)
+	}
+	records := make([]model.LogRecord, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, row.Record())
+	}
+	return records, nil
+}
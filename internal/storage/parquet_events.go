@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"liquidityScope/internal/model"
+)
+
+// Every typed event file starts with the same event columns, followed by the
+// columns of its payload. Token amounts and other uint256/int256 values are
+// stored as decimal strings. The parquet library does not flatten embedded
+// structs, so the shared columns are repeated in each row type.
+
+// SwapRow is the Parquet schema of swap.parquet.
+type SwapRow struct {
+	ChainID      int64  `parquet:"name=chain_id, type=INT64, convertedtype=UINT_64"`
+	BlockNumber  int64  `parquet:"name=block_number, type=INT64, convertedtype=UINT_64"`
+	BlockHash    string `parquet:"name=block_hash, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TxHash       string `parquet:"name=tx_hash, type=BYTE_ARRAY, convertedtype=UTF8"`
+	LogIndex     int64  `parquet:"name=log_index, type=INT64, convertedtype=UINT_64"`
+	Address      string `parquet:"name=address, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	EventName    string `parquet:"name=event_name, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Timestamp    int64  `parquet:"name=timestamp, type=INT64, convertedtype=UINT_64"`
+	Removed      bool   `parquet:"name=removed, type=BOOLEAN"`
+	Token0       string `parquet:"name=token0, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Token1       string `parquet:"name=token1, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Fee          int64  `parquet:"name=fee, type=INT64, convertedtype=UINT_64"`
+	TickSpacing  int32  `parquet:"name=tick_spacing, type=INT32"`
+	Sender       string `parquet:"name=sender, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Recipient    string `parquet:"name=recipient, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Amount0      string `parquet:"name=amount0, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Amount1      string `parquet:"name=amount1, type=BYTE_ARRAY, convertedtype=UTF8"`
+	SqrtPriceX96 string `parquet:"name=sqrt_price_x96, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Liquidity    string `parquet:"name=liquidity, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Tick         int32  `parquet:"name=tick, type=INT32"`
+	// Protocol fees are set for PancakeSwap V3 swaps only.
+	ProtocolFeesToken0 *string `parquet:"name=protocol_fees_token0, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	ProtocolFeesToken1 *string `parquet:"name=protocol_fees_token1, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+}
+
+// MintRow is the Parquet schema of mint.parquet.
+type MintRow struct {
+	ChainID     int64  `parquet:"name=chain_id, type=INT64, convertedtype=UINT_64"`
+	BlockNumber int64  `parquet:"name=block_number, type=INT64, convertedtype=UINT_64"`
+	BlockHash   string `parquet:"name=block_hash, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TxHash      string `parquet:"name=tx_hash, type=BYTE_ARRAY, convertedtype=UTF8"`
+	LogIndex    int64  `parquet:"name=log_index, type=INT64, convertedtype=UINT_64"`
+	Address     string `parquet:"name=address, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	EventName   string `parquet:"name=event_name, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Timestamp   int64  `parquet:"name=timestamp, type=INT64, convertedtype=UINT_64"`
+	Removed     bool   `parquet:"name=removed, type=BOOLEAN"`
+	Token0      string `parquet:"name=token0, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Token1      string `parquet:"name=token1, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Fee         int64  `parquet:"name=fee, type=INT64, convertedtype=UINT_64"`
+	TickSpacing int32  `parquet:"name=tick_spacing, type=INT32"`
+	Sender      string `parquet:"name=sender, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Owner       string `parquet:"name=owner, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TickLower   int32  `parquet:"name=tick_lower, type=INT32"`
+	TickUpper   int32  `parquet:"name=tick_upper, type=INT32"`
+	Amount      string `parquet:"name=amount, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Amount0     string `parquet:"name=amount0, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Amount1     string `parquet:"name=amount1, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// BurnRow is the Parquet schema of burn.parquet.
+type BurnRow struct {
+	ChainID     int64  `parquet:"name=chain_id, type=INT64, convertedtype=UINT_64"`
+	BlockNumber int64  `parquet:"name=block_number, type=INT64, convertedtype=UINT_64"`
+	BlockHash   string `parquet:"name=block_hash, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TxHash      string `parquet:"name=tx_hash, type=BYTE_ARRAY, convertedtype=UTF8"`
+	LogIndex    int64  `parquet:"name=log_index, type=INT64, convertedtype=UINT_64"`
+	Address     string `parquet:"name=address, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	EventName   string `parquet:"name=event_name, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Timestamp   int64  `parquet:"name=timestamp, type=INT64, convertedtype=UINT_64"`
+	Removed     bool   `parquet:"name=removed, type=BOOLEAN"`
+	Token0      string `parquet:"name=token0, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Token1      string `parquet:"name=token1, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Fee         int64  `parquet:"name=fee, type=INT64, convertedtype=UINT_64"`
+	TickSpacing int32  `parquet:"name=tick_spacing, type=INT32"`
+	Owner       string `parquet:"name=owner, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TickLower   int32  `parquet:"name=tick_lower, type=INT32"`
+	TickUpper   int32  `parquet:"name=tick_upper, type=INT32"`
+	Amount      string `parquet:"name=amount, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Amount0     string `parquet:"name=amount0, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Amount1     string `parquet:"name=amount1, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// CollectRow is the Parquet schema of collect.parquet.
+type CollectRow struct {
+	ChainID     int64  `parquet:"name=chain_id, type=INT64, convertedtype=UINT_64"`
+	BlockNumber int64  `parquet:"name=block_number, type=INT64, convertedtype=UINT_64"`
+	BlockHash   string `parquet:"name=block_hash, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TxHash      string `parquet:"name=tx_hash, type=BYTE_ARRAY, convertedtype=UTF8"`
+	LogIndex    int64  `parquet:"name=log_index, type=INT64, convertedtype=UINT_64"`
+	Address     string `parquet:"name=address, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	EventName   string `parquet:"name=event_name, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Timestamp   int64  `parquet:"name=timestamp, type=INT64, convertedtype=UINT_64"`
+	Removed     bool   `parquet:"name=removed, type=BOOLEAN"`
+	Token0      string `parquet:"name=token0, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Token1      string `parquet:"name=token1, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Fee         int64  `parquet:"name=fee, type=INT64, convertedtype=UINT_64"`
+	TickSpacing int32  `parquet:"name=tick_spacing, type=INT32"`
+	Owner       string `parquet:"name=owner, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Recipient   string `parquet:"name=recipient, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TickLower   int32  `parquet:"name=tick_lower, type=INT32"`
+	TickUpper   int32  `parquet:"name=tick_upper, type=INT32"`
+	Amount0     string `parquet:"name=amount0, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Amount1     string `parquet:"name=amount1, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// OtherEventRow keeps events without a dedicated schema, with the decoded
+// payload as JSON. PoolID is set for V4 events.
+type OtherEventRow struct {
+	ChainID     int64   `parquet:"name=chain_id, type=INT64, convertedtype=UINT_64"`
+	BlockNumber int64   `parquet:"name=block_number, type=INT64, convertedtype=UINT_64"`
+	BlockHash   string  `parquet:"name=block_hash, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TxHash      string  `parquet:"name=tx_hash, type=BYTE_ARRAY, convertedtype=UTF8"`
+	LogIndex    int64   `parquet:"name=log_index, type=INT64, convertedtype=UINT_64"`
+	Address     string  `parquet:"name=address, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	EventName   string  `parquet:"name=event_name, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Timestamp   int64   `parquet:"name=timestamp, type=INT64, convertedtype=UINT_64"`
+	Removed     bool    `parquet:"name=removed, type=BOOLEAN"`
+	Token0      string  `parquet:"name=token0, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Token1      string  `parquet:"name=token1, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Fee         int64   `parquet:"name=fee, type=INT64, convertedtype=UINT_64"`
+	TickSpacing int32   `parquet:"name=tick_spacing, type=INT32"`
+	Decoded     string  `parquet:"name=decoded, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PoolID      *string `parquet:"name=pool_id, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+}
+
+// ParquetEventWriter writes typed events to one Parquet file per event kind
+// (swap.parquet, mint.parquet, ...) under a directory. Row groups are aligned
+// to rowGroupBlocks. Files are complete only after Close.
+type ParquetEventWriter struct {
+	dir            string
+	rowGroupBlocks uint64
+	files          map[string]*eventFile
+}
+
+type eventFile struct {
+	file *os.File
+	out  *parquetFile
+}
+
+func NewParquetEventWriter(dir string, rowGroupBlocks uint64) (*ParquetEventWriter, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create output dir: %w", err)
+	}
+	return &ParquetEventWriter{dir: dir, rowGroupBlocks: rowGroupBlocks, files: make(map[string]*eventFile)}, nil
+}
+
+// Write appends an event to the file of its kind.
+func (w *ParquetEventWriter) Write(event *model.TypedEvent) error {
+	kind, schema, row, err := eventRow(event)
+	if err != nil {
+		return err
+	}
+	file, ok := w.files[kind]
+	if !ok {
+		handle, err := os.OpenFile(filepath.Join(w.dir, kind+".parquet"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return fmt.Errorf("create parquet file: %w", err)
+		}
+		out, err := newParquetFile(handle, schema, w.rowGroupBlocks)
+		if err != nil {
+			handle.Close()
+			return err
+		}
+		file = &eventFile{file: handle, out: out}
+		w.files[kind] = file
+	}
+	return file.out.write(event.BlockNumber, row)
+}
+
+// Close finishes every file.
+func (w *ParquetEventWriter) Close() error {
+	var firstErr error
+	for kind, file := range w.files {
+		if err := file.out.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if err := file.file.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("close parquet file: %w", err)
+		}
+		delete(w.files, kind)
+	}
+	return firstErr
+}
+
+// eventRow maps an event to its file name, schema and row.
+func eventRow(event *model.TypedEvent) (string, interface{}, interface{}, error) {
+	meta := event.PoolMeta
+	switch decoded := event.Decoded.(type) {
+	case model.SwapEventData:
+		return "swap", new(SwapRow), SwapRow{
+			ChainID:            int64(event.ChainID),
+			BlockNumber:        int64(event.BlockNumber),
+			BlockHash:          event.BlockHash,
+			TxHash:             event.TxHash,
+			LogIndex:           int64(event.LogIndex),
+			Address:            event.Address,
+			EventName:          event.EventName,
+			Timestamp:          int64(event.Timestamp),
+			Removed:            event.Removed,
+			Token0:             meta.Token0,
+			Token1:             meta.Token1,
+			Fee:                int64(meta.Fee),
+			TickSpacing:        meta.TickSpacing,
+			Sender:             decoded.Sender,
+			Recipient:          decoded.Recipient,
+			Amount0:            decoded.Amount0,
+			Amount1:            decoded.Amount1,
+			SqrtPriceX96:       decoded.SqrtPriceX96,
+			Liquidity:          decoded.Liquidity,
+			Tick:               decoded.Tick,
+			ProtocolFeesToken0: optionalString(decoded.ProtocolFeesToken0),
+			ProtocolFeesToken1: optionalString(decoded.ProtocolFeesToken1),
+		}, nil
+	case model.MintEventData:
+		return "mint", new(MintRow), MintRow{
+			ChainID:     int64(event.ChainID),
+			BlockNumber: int64(event.BlockNumber),
+			BlockHash:   event.BlockHash,
+			TxHash:      event.TxHash,
+			LogIndex:    int64(event.LogIndex),
+			Address:     event.Address,
+			EventName:   event.EventName,
+			Timestamp:   int64(event.Timestamp),
+			Removed:     event.Removed,
+			Token0:      meta.Token0,
+			Token1:      meta.Token1,
+			Fee:         int64(meta.Fee),
+			TickSpacing: meta.TickSpacing,
+			Sender:      decoded.Sender,
+			Owner:       decoded.Owner,
+			TickLower:   decoded.TickLower,
+			TickUpper:   decoded.TickUpper,
+			Amount:      decoded.Amount,
+			Amount0:     decoded.Amount0,
+			Amount1:     decoded.Amount1,
+		}, nil
+	case model.BurnEventData:
+		return "burn", new(BurnRow), BurnRow{
+			ChainID:     int64(event.ChainID),
+			BlockNumber: int64(event.BlockNumber),
+			BlockHash:   event.BlockHash,
+			TxHash:      event.TxHash,
+			LogIndex:    int64(event.LogIndex),
+			Address:     event.Address,
+			EventName:   event.EventName,
+			Timestamp:   int64(event.Timestamp),
+			Removed:     event.Removed,
+			Token0:      meta.Token0,
+			Token1:      meta.Token1,
+			Fee:         int64(meta.Fee),
+			TickSpacing: meta.TickSpacing,
+			Owner:       decoded.Owner,
+			TickLower:   decoded.TickLower,
+			TickUpper:   decoded.TickUpper,
+			Amount:      decoded.Amount,
+			Amount0:     decoded.Amount0,
+			Amount1:     decoded.Amount1,
+		}, nil
+	case model.CollectEventData:
+		return "collect", new(CollectRow), CollectRow{
+			ChainID:     int64(event.ChainID),
+			BlockNumber: int64(event.BlockNumber),
+			BlockHash:   event.BlockHash,
+			TxHash:      event.TxHash,
+			LogIndex:    int64(event.LogIndex),
+			Address:     event.Address,
+			EventName:   event.EventName,
+			Timestamp:   int64(event.Timestamp),
+			Removed:     event.Removed,
+			Token0:      meta.Token0,
+			Token1:      meta.Token1,
+			Fee:         int64(meta.Fee),
+			TickSpacing: meta.TickSpacing,
+			Owner:       decoded.Owner,
+			Recipient:   decoded.Recipient,
+			TickLower:   decoded.TickLower,
+			TickUpper:   decoded.TickUpper,
+			Amount0:     decoded.Amount0,
+			Amount1:     decoded.Amount1,
+		}, nil
+	default:
+		payload, err := json.Marshal(event.Decoded)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("marshal %s payload: %w", event.EventName, err)
+		}
+		kind := strings.ToLower(event.EventName)
+		if meta.Protocol != "" {
+			kind = meta.Protocol + "_" + kind
+		}
+		return "other_" + kind, new(OtherEventRow), OtherEventRow{
+			ChainID:     int64(event.ChainID),
+			BlockNumber: int64(event.BlockNumber),
+			BlockHash:   event.BlockHash,
+			TxHash:      event.TxHash,
+			LogIndex:    int64(event.LogIndex),
+			Address:     event.Address,
+			EventName:   event.EventName,
+			Timestamp:   int64(event.Timestamp),
+			Removed:     event.Removed,
+			Token0:      meta.Token0,
+			Token1:      meta.Token1,
+			Fee:         int64(meta.Fee),
+			TickSpacing: meta.TickSpacing,
+			Decoded:     string(payload),
+			PoolID:      optionalString(event.PoolID),
+		}, nil
+	}
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
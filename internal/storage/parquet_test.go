@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+
+	"liquidityScope/internal/model"
+)
+
+func TestParquetStorageParts(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewParquetStorage(dir, 10)
+	if err != nil {
+		t.Fatalf("open storage: %v", err)
+	}
+
+	first := []model.LogRecord{
+		{ChainID: 56, BlockNumber: 8, LogIndex: 1, Topics: []string{"0xaa", "0xbb"}, Data: "0x01"},
+		{ChainID: 56, BlockNumber: 12, LogIndex: 0, Topics: []string{"0xcc"}},
+	}
+	if err := store.PutLogBatch(first); err != nil {
+		t.Fatalf("put batch: %v", err)
+	}
+	committed, _ := store.Position()
+	if err := store.PutLogBatch([]model.LogRecord{{ChainID: 56, BlockNumber: 13, Topics: []string{}}}); err != nil {
+		t.Fatalf("put batch: %v", err)
+	}
+
+	tail, err := store.Tail(6)
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if len(tail) != 3 || tail[0].BlockNumber != 8 || tail[2].BlockNumber != 13 {
+		t.Fatalf("unexpected tail: %+v", tail)
+	}
+	if len(tail[0].Topics) != 2 || tail[0].Topics[1] != "0xbb" || tail[0].Data != "0x01" {
+		t.Fatalf("record did not round-trip: %+v", tail[0])
+	}
+
+	reopened, err := NewParquetStorage(dir, 10)
+	if err != nil {
+		t.Fatalf("reopen storage: %v", err)
+	}
+	if err := reopened.Truncate(committed); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	if parts, _ := reopened.Position(); parts != 1 {
+		t.Fatalf("expected 1 part after truncate, got %d", parts)
+	}
+
+	// Row groups are cut at block 10.
+	file, err := local.NewLocalFileReader(filepath.Join(dir, "part-000001.parquet"))
+	if err != nil {
+		t.Fatalf("open part: %v", err)
+	}
+	defer file.Close()
+	pr, err := reader.NewParquetReader(file, new(LogRow), 1)
+	if err != nil {
+		t.Fatalf("read part: %v", err)
+	}
+	defer pr.ReadStop()
+	if len(pr.Footer.RowGroups) != 2 {
+		t.Fatalf("expected 2 row groups, got %d", len(pr.Footer.RowGroups))
+	}
+}
+
+func TestParquetEventWriter(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := NewParquetEventWriter(dir, 100)
+	if err != nil {
+		t.Fatalf("open writer: %v", err)
+	}
+	events := []*model.TypedEvent{
+		{BlockNumber: 1, EventName: "Swap", Decoded: model.SwapEventData{Amount0: "-123456789012345678901234567890", SqrtPriceX96: "79228162514264337593543950336"}},
+		{BlockNumber: 2, EventName: "Mint", Decoded: model.MintEventData{Amount: "1"}},
+		{BlockNumber: 150, EventName: "Swap", Decoded: model.SwapEventData{Amount0: "5"}},
+	}
+	for _, event := range events {
+		if err := writer.Write(event); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	file, err := local.NewLocalFileReader(filepath.Join(dir, "swap.parquet"))
+	if err != nil {
+		t.Fatalf("open swap file: %v", err)
+	}
+	defer file.Close()
+	pr, err := reader.NewParquetReader(file, new(SwapRow), 1)
+	if err != nil {
+		t.Fatalf("read swap file: %v", err)
+	}
+	defer pr.ReadStop()
+	if len(pr.Footer.RowGroups) != 2 {
+		t.Fatalf("expected 2 row groups, got %d", len(pr.Footer.RowGroups))
+	}
+	rows := make([]SwapRow, pr.GetNumRows())
+	if err := pr.Read(&rows); err != nil {
+		t.Fatalf("read rows: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Amount0 != "-123456789012345678901234567890" || rows[0].SqrtPriceX96 != "79228162514264337593543950336" {
+		t.Fatalf("unexpected swap rows: %+v", rows)
+	}
+}
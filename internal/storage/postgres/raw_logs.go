@@ -0,0 +1,221 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"liquidityScope/internal/model"
+)
+
+// RawLogSink writes log records into raw_logs. Inserts are idempotent on
+// (chain_id, block_hash, log_index); a tombstone flips removed on the row it
+// compensates. The checkpoint is kept in indexer_checkpoints under name and
+// committed in the same transaction as the batch. Tail reads back the rows of
+// chainID only.
+type RawLogSink struct {
+	ctx     context.Context
+	store   *Store
+	name    string
+	chainID uint64
+}
+
+// NewRawLogSink binds the sink to ctx, which bounds every write.
+func NewRawLogSink(ctx context.Context, store *Store, checkpointName string, chainID uint64) *RawLogSink {
+	return &RawLogSink{ctx: ctx, store: store, name: checkpointName, chainID: chainID}
+}
+
+// PutLogBatch inserts a batch without touching the checkpoint.
+func (s *RawLogSink) PutLogBatch(logs []model.LogRecord) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	return pgx.BeginFunc(s.ctx, s.store.pool, func(tx pgx.Tx) error {
+		return insertRawLogs(s.ctx, tx, logs)
+	})
+}
+
+// PutLogBatchAt inserts a batch and advances the checkpoint to lastProcessed
+// in one transaction.
+func (s *RawLogSink) PutLogBatchAt(logs []model.LogRecord, lastProcessed uint64) error {
+	return pgx.BeginFunc(s.ctx, s.store.pool, func(tx pgx.Tx) error {
+		if err := insertRawLogs(s.ctx, tx, logs); err != nil {
+			return err
+		}
+		_, err := tx.Exec(s.ctx, `
+			INSERT INTO indexer_checkpoints (name, last_processed_block, updated_at)
+			VALUES ($1, $2, now())
+			ON CONFLICT (name) DO UPDATE
+			SET last_processed_block = EXCLUDED.last_processed_block, updated_at = now()
+		`, s.name, int64(lastProcessed))
+		if err != nil {
+			return fmt.Errorf("save checkpoint: %w", err)
+		}
+		return nil
+	})
+}
+
+// LoadCheckpoint returns the last processed block stored under the sink name.
+func (s *RawLogSink) LoadCheckpoint() (uint64, bool, error) {
+	var block int64
+	row := s.store.pool.QueryRow(s.ctx, `SELECT last_processed_block FROM indexer_checkpoints WHERE name=$1`, s.name)
+	if err := row.Scan(&block); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("load checkpoint: %w", err)
+	}
+	return uint64(block), true, nil
+}
+
+// Tail returns the canonical rows of the last blocks blocks of the chain, in
+// (block_number, log_index) order. Tombstoned rows are left out: the row of an
+// orphaned log is flipped to removed instead of being followed by a tombstone.
+func (s *RawLogSink) Tail(blocks uint64) ([]model.LogRecord, error) {
+	var newest *int64
+	row := s.store.pool.QueryRow(s.ctx, `SELECT max(block_number) FROM raw_logs WHERE chain_id=$1`, int64(s.chainID))
+	if err := row.Scan(&newest); err != nil {
+		return nil, fmt.Errorf("query newest raw log: %w", err)
+	}
+	if newest == nil || blocks == 0 {
+		return nil, nil
+	}
+
+	rows, err := s.store.pool.Query(s.ctx, `
+		SELECT chain_id, block_number, block_hash, tx_hash, tx_index, log_index,
+			address, topics, data, removed, block_timestamp, ingested_at
+		FROM raw_logs
+		WHERE chain_id = $1 AND block_number > $2 AND NOT removed
+		ORDER BY chain_id, block_number, log_index
+	`, int64(s.chainID), *newest-int64(blocks))
+	if err != nil {
+		return nil, fmt.Errorf("query raw log tail: %w", err)
+	}
+	defer rows.Close()
+
+	var records []model.LogRecord
+	for rows.Next() {
+		record, err := scanRawLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read raw log tail: %w", err)
+	}
+	return records, nil
+}
+
+func insertRawLogs(ctx context.Context, tx pgx.Tx, logs []model.LogRecord) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for _, record := range logs {
+		topics := record.Topics
+		if topics == nil {
+			topics = []string{}
+		}
+		batch.Queue(`
+			INSERT INTO raw_logs (
+				chain_id, block_number, block_hash, tx_hash, tx_index, log_index,
+				address, topics, data, removed, block_timestamp, ingested_at
+			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+			ON CONFLICT (chain_id, block_hash, log_index)
+			DO UPDATE SET
+				removed = EXCLUDED.removed,
+				ingested_at = EXCLUDED.ingested_at
+			WHERE raw_logs.removed IS DISTINCT FROM EXCLUDED.removed
+		`,
+			int64(record.ChainID),
+			int64(record.BlockNumber),
+			record.BlockHash,
+			record.TxHash,
+			int64(record.TxIndex),
+			int64(record.LogIndex),
+			record.Address,
+			topics,
+			record.Data,
+			record.Removed,
+			int64(record.Timestamp),
+			record.IngestedAt,
+		)
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	defer br.Close()
+
+	for range logs {
+		if _, err := br.Exec(); err != nil {
+			return fmt.Errorf("insert raw log: %w", err)
+		}
+	}
+	return nil
+}
+
+// RawLogFilter restricts ScanRawLogs. Zero fields do not restrict.
+type RawLogFilter struct {
+	ChainID   uint64
+	FromBlock uint64
+	ToBlock   uint64
+}
+
+// ScanRawLogs streams the canonical (not removed) rows of raw_logs in
+// (block_number, log_index) order.
+func (s *Store) ScanRawLogs(ctx context.Context, filter RawLogFilter, fn func(model.LogRecord) error) error {
+	toBlock := int64(-1)
+	if filter.ToBlock > 0 {
+		toBlock = int64(filter.ToBlock)
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT chain_id, block_number, block_hash, tx_hash, tx_index, log_index,
+			address, topics, data, removed, block_timestamp, ingested_at
+		FROM raw_logs
+		WHERE NOT removed
+			AND ($1 = 0 OR chain_id = $1)
+			AND block_number >= $2
+			AND ($3 < 0 OR block_number <= $3)
+		ORDER BY chain_id, block_number, log_index
+	`, int64(filter.ChainID), int64(filter.FromBlock), toBlock)
+	if err != nil {
+		return fmt.Errorf("query raw logs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scanRawLog(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read raw logs: %w", err)
+	}
+	return nil
+}
+
+// scanRawLog reads a raw_logs row selected with every column in table order.
+func scanRawLog(rows pgx.Rows) (model.LogRecord, error) {
+	var (
+		record                                                  model.LogRecord
+		chainID, blockNumber, txIndex, logIndex, blockTimestamp int64
+	)
+	if err := rows.Scan(
+		&chainID, &blockNumber, &record.BlockHash, &record.TxHash, &txIndex, &logIndex,
+		&record.Address, &record.Topics, &record.Data, &record.Removed, &blockTimestamp, &record.IngestedAt,
+	); err != nil {
+		return model.LogRecord{}, fmt.Errorf("scan raw log: %w", err)
+	}
+	record.ChainID = uint64(chainID)
+	record.BlockNumber = uint64(blockNumber)
+	record.TxIndex = uint64(txIndex)
+	record.LogIndex = uint64(logIndex)
+	record.Timestamp = uint64(blockTimestamp)
+	return record, nil
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"liquidityScope/internal/model"
+)
+
+// testStore connects to INDEXER_TEST_PG_DSN in a schema of its own holding
+// the tables of migration, and skips the test when the variable is unset.
+func testStore(t *testing.T, migration string) *Store {
+	t.Helper()
+	dsn := os.Getenv("INDEXER_TEST_PG_DSN")
+	if dsn == "" {
+		t.Skip("INDEXER_TEST_PG_DSN not set")
+	}
+	ctx := context.Background()
+	schema := fmt.Sprintf("test_%d", os.Getpid())
+
+	admin, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(admin.Close)
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE")
+	})
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("parse dsn: %v", err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("connect to schema: %v", err)
+	}
+	store := &Store{pool: pool}
+	t.Cleanup(store.Close)
+
+	ddl, err := os.ReadFile(filepath.Join("..", "..", "..", "migrations", migration))
+	if err != nil {
+		t.Fatalf("read migration: %v", err)
+	}
+	if _, err := pool.Exec(ctx, string(ddl)); err != nil {
+		t.Fatalf("apply migration: %v", err)
+	}
+	return store
+}
+
+func TestRawLogSinkTail(t *testing.T) {
+	store := testStore(t, "002_raw_logs.sql")
+	ctx := context.Background()
+	log := func(chainID, block uint64, hash string, logIndex uint64) model.LogRecord {
+		return model.LogRecord{
+			ChainID:     chainID,
+			BlockNumber: block,
+			BlockHash:   hash,
+			TxHash:      fmt.Sprintf("0x%x", block),
+			LogIndex:    logIndex,
+			Address:     "0x01",
+			Data:        "0x",
+			IngestedAt:  "2024-01-01T00:00:00Z",
+		}
+	}
+	orphaned := log(56, 12, "0x0c", 0)
+	tombstone := orphaned
+	tombstone.Removed = true
+
+	sink := NewRawLogSink(ctx, store, "test", 56)
+	batches := [][]model.LogRecord{
+		{log(56, 10, "0x0a", 0), log(56, 11, "0x0b", 1), log(56, 11, "0x0b", 0), orphaned},
+		{tombstone, log(56, 12, "0xcc", 2)},
+		{log(1, 50, "0x32", 0)},
+	}
+	for _, batch := range batches {
+		if err := sink.PutLogBatch(batch); err != nil {
+			t.Fatalf("put batch: %v", err)
+		}
+	}
+
+	records, err := sink.Tail(2)
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected the 3 canonical logs of blocks 11-12, got %+v", records)
+	}
+	if records[0].LogIndex != 0 || records[1].LogIndex != 1 || records[2].BlockHash != "0xcc" {
+		t.Fatalf("unexpected tail order: %+v", records)
+	}
+
+	empty, err := NewRawLogSink(ctx, store, "test", 97).Tail(2)
+	if err != nil || len(empty) != 0 {
+		t.Fatalf("expected no rows for another chain, got %+v %v", empty, err)
+	}
+}
@@ -42,14 +42,16 @@ func (s *Store) UpsertPools(ctx context.Context, pools []model.Pool) error {
 	for _, pool := range pools {
 		batch.Queue(`
 			INSERT INTO pools (
-				chain_id, pool_address, token0, token1, fee, tick_spacing, first_seen_block, created_at, updated_at
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, now(), now())
+				chain_id, pool_address, token0, token1, fee, tick_spacing, first_seen_block, protocol, hooks, created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE(NULLIF($8, ''), 'v3'), NULLIF($9, ''), now(), now())
 			ON CONFLICT (chain_id, pool_address)
 			DO UPDATE SET
 				token0 = EXCLUDED.token0,
 				token1 = EXCLUDED.token1,
 				fee = EXCLUDED.fee,
 				tick_spacing = EXCLUDED.tick_spacing,
+				protocol = EXCLUDED.protocol,
+				hooks = EXCLUDED.hooks,
 				first_seen_block = LEAST(pools.first_seen_block, EXCLUDED.first_seen_block),
 				updated_at = now()
 		`,
@@ -60,6 +62,8 @@ func (s *Store) UpsertPools(ctx context.Context, pools []model.Pool) error {
 			pool.Fee,
 			pool.TickSpacing,
 			int64(pool.FirstSeenBlock),
+			pool.Protocol,
+			pool.Hooks,
 		)
 	}
 
@@ -85,8 +89,9 @@ func (s *Store) UpsertWindowMetrics(ctx context.Context, metrics []model.PoolWin
 			INSERT INTO pool_window_metrics (
 				chain_id, pool_address, window_size_seconds, window_start_ts, window_end_ts,
 				swap_count, volume0, volume1, fee0, fee1, fee_usd, fee_rate0, fee_rate1,
-				tvl0, tvl1, tvl_usd, apr, fee_method, tvl_method, created_at, updated_at
-			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,now(),now())
+				tvl0, tvl1, tvl_usd, apr, fee_method, tvl_method, protocol_fee0, protocol_fee1,
+				created_at, updated_at
+			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,now(),now())
 			ON CONFLICT (chain_id, pool_address, window_size_seconds, window_start_ts)
 			DO UPDATE SET
 				window_end_ts = EXCLUDED.window_end_ts,
@@ -104,6 +109,8 @@ func (s *Store) UpsertWindowMetrics(ctx context.Context, metrics []model.PoolWin
 				apr = EXCLUDED.apr,
 				fee_method = EXCLUDED.fee_method,
 				tvl_method = EXCLUDED.tvl_method,
+				protocol_fee0 = EXCLUDED.protocol_fee0,
+				protocol_fee1 = EXCLUDED.protocol_fee1,
 				updated_at = now()
 		`,
 			int64(m.ChainID),
@@ -125,6 +132,8 @@ func (s *Store) UpsertWindowMetrics(ctx context.Context, metrics []model.PoolWin
 			m.APR,
 			m.FeeMethod,
 			m.TVLMethod,
+			m.ProtocolFee0,
+			m.ProtocolFee1,
 		)
 	}
 
@@ -139,32 +148,34 @@ func (s *Store) UpsertWindowMetrics(ctx context.Context, metrics []model.PoolWin
 	return nil
 }
 
-// LoadState returns last_processed_ts for a name.
-func (s *Store) LoadState(ctx context.Context, name string) (uint64, bool, error) {
+// LoadState returns last_processed_ts and the JSON fees for a name.
+func (s *Store) LoadState(ctx context.Context, name string) (uint64, []byte, bool, error) {
 	if name == "" {
-		return 0, false, fmt.Errorf("state name required")
+		return 0, nil, false, fmt.Errorf("state name required")
 	}
 	var ts uint64
-	row := s.pool.QueryRow(ctx, `SELECT last_processed_ts FROM indexer_state WHERE name=$1`, name)
-	if err := row.Scan(&ts); err != nil {
+	var fees []byte
+	row := s.pool.QueryRow(ctx, `SELECT last_processed_ts, fees FROM indexer_state WHERE name=$1`, name)
+	if err := row.Scan(&ts, &fees); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return 0, false, nil
+			return 0, nil, false, nil
 		}
-		return 0, false, err
+		return 0, nil, false, err
 	}
-	return ts, true, nil
+	return ts, fees, true, nil
 }
 
-// SaveState upserts last_processed_ts for a name.
-func (s *Store) SaveState(ctx context.Context, name string, ts uint64) error {
+// SaveState upserts last_processed_ts and the JSON fees, which may be nil,
+// for a name.
+func (s *Store) SaveState(ctx context.Context, name string, ts uint64, fees []byte) error {
 	if name == "" {
 		return fmt.Errorf("state name required")
 	}
 	_, err := s.pool.Exec(ctx, `
-		INSERT INTO indexer_state (name, last_processed_ts, updated_at)
-		VALUES ($1, $2, now())
+		INSERT INTO indexer_state (name, last_processed_ts, fees, updated_at)
+		VALUES ($1, $2, $3, now())
 		ON CONFLICT (name) DO UPDATE
-		SET last_processed_ts = EXCLUDED.last_processed_ts, updated_at = now()
-	`, name, ts)
+		SET last_processed_ts = EXCLUDED.last_processed_ts, fees = EXCLUDED.fees, updated_at = now()
+	`, name, ts, fees)
 	return err
 }
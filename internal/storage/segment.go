@@ -0,0 +1,392 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestName is the file that lists the segments of a partitioned output
+// directory.
+const ManifestName = "manifest.json"
+
+// Partition modes for segmented output.
+const (
+	PartitionDay    = "day"
+	PartitionBlocks = "blocks"
+)
+
+// Partitioning decides which segment a record is written to.
+type Partitioning struct {
+	// Mode is PartitionDay (UTC day of the block timestamp) or PartitionBlocks.
+	Mode string
+	// BlockSpan is the width of a partition in PartitionBlocks mode.
+	BlockSpan uint64
+	// MaxRecords rotates to a new segment once the current one holds this
+	// many records; 0 disables rotation within a partition.
+	MaxRecords int
+}
+
+// Validate checks the partition mode and block span.
+func (p Partitioning) Validate() error {
+	switch p.Mode {
+	case PartitionDay:
+		return nil
+	case PartitionBlocks:
+		if p.BlockSpan == 0 {
+			return fmt.Errorf("segment block span must be greater than zero")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown partition mode %q (want %s or %s)", p.Mode, PartitionDay, PartitionBlocks)
+	}
+}
+
+func (p Partitioning) key(blockNumber, timestamp uint64) string {
+	if p.Mode == PartitionBlocks {
+		start := blockNumber / p.BlockSpan * p.BlockSpan
+		return fmt.Sprintf("%d-%d", start, start+p.BlockSpan-1)
+	}
+	return time.Unix(int64(timestamp), 0).UTC().Format("2006-01-02")
+}
+
+// Segment describes one JSONL file of a partitioned output.
+type Segment struct {
+	Seq          int    `json:"seq"`
+	Path         string `json:"path"`
+	ChainID      uint64 `json:"chain_id"`
+	Partition    string `json:"partition"`
+	FromBlock    uint64 `json:"from_block"`
+	ToBlock      uint64 `json:"to_block"`
+	MinTimestamp uint64 `json:"min_timestamp"`
+	MaxTimestamp uint64 `json:"max_timestamp"`
+	Records      int    `json:"records"`
+	Bytes        int64  `json:"bytes"`
+}
+
+func (s *Segment) add(entry SegmentEntry, size int64) {
+	if s.Records == 0 {
+		s.FromBlock, s.ToBlock = entry.BlockNumber, entry.BlockNumber
+		s.MinTimestamp, s.MaxTimestamp = entry.Timestamp, entry.Timestamp
+	} else {
+		s.FromBlock = min(s.FromBlock, entry.BlockNumber)
+		s.ToBlock = max(s.ToBlock, entry.BlockNumber)
+		s.MinTimestamp = min(s.MinTimestamp, entry.Timestamp)
+		s.MaxTimestamp = max(s.MaxTimestamp, entry.Timestamp)
+	}
+	s.Records++
+	s.Bytes += size
+}
+
+// Manifest lists the segments of a partitioned output in write order.
+type Manifest struct {
+	Segments []Segment `json:"segments"`
+}
+
+// LoadManifest reads a manifest. A missing file yields an empty manifest.
+func LoadManifest(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Manifest{}, nil
+	}
+	if err != nil {
+		return Manifest{}, fmt.Errorf("read manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("parse manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// Save writes the manifest atomically: the tmp file is synced before the
+// rename and the directory after it, so a crash leaves the old or new manifest.
+func (m Manifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("write manifest tmp: %w", err)
+	}
+	tmp, err := os.Open(tmpPath)
+	if err != nil {
+		return fmt.Errorf("open manifest tmp: %w", err)
+	}
+	syncErr := tmp.Sync()
+	tmp.Close()
+	if syncErr != nil {
+		return fmt.Errorf("sync manifest tmp: %w", syncErr)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("rename manifest: %w", err)
+	}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("sync manifest dir: %w", err)
+	}
+	return nil
+}
+
+func syncDir(dir string) error {
+	handle, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+	return handle.Sync()
+}
+
+// Size returns the total bytes of all segments.
+func (m Manifest) Size() int64 {
+	var total int64
+	for _, segment := range m.Segments {
+		total += segment.Bytes
+	}
+	return total
+}
+
+// SegmentEntry is one encoded JSON line with the fields used to place it.
+type SegmentEntry struct {
+	ChainID     uint64
+	BlockNumber uint64
+	Timestamp   uint64
+	Line        []byte
+}
+
+// SegmentWriter appends JSON lines to partitioned segment files under a
+// directory and keeps the manifest in step. Segment data is synced before the
+// manifest is replaced, so the manifest never lists bytes that are not on disk.
+type SegmentWriter struct {
+	dir          string
+	partitioning Partitioning
+	manifest     Manifest
+}
+
+// OpenSegmentWriter loads the manifest under dir and cuts the newest segment
+// back to its recorded length, discarding a batch interrupted by a crash.
+func OpenSegmentWriter(dir string, partitioning Partitioning) (*SegmentWriter, error) {
+	if err := partitioning.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create output dir: %w", err)
+	}
+	manifest, err := LoadManifest(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, err
+	}
+
+	w := &SegmentWriter{dir: dir, partitioning: partitioning, manifest: manifest}
+	if n := len(manifest.Segments); n > 0 {
+		last := manifest.Segments[n-1]
+		if err := truncateFile(w.segmentPath(last), last.Bytes); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Manifest returns a copy of the current manifest.
+func (w *SegmentWriter) Manifest() Manifest {
+	return Manifest{Segments: append([]Segment(nil), w.manifest.Segments...)}
+}
+
+// Append writes entries in order, opening a new segment whenever the chain,
+// partition or rotation limit changes, then saves the manifest.
+func (w *SegmentWriter) Append(entries []SegmentEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var (
+		file    *os.File
+		writer  *bufio.Writer
+		current *Segment
+	)
+	closeCurrent := func() error {
+		if file == nil {
+			return nil
+		}
+		defer file.Close()
+		if err := writer.Flush(); err != nil {
+			return fmt.Errorf("flush segment: %w", err)
+		}
+		if err := file.Sync(); err != nil {
+			return fmt.Errorf("sync segment: %w", err)
+		}
+		file = nil
+		return nil
+	}
+
+	for _, entry := range entries {
+		partition := w.partitioning.key(entry.BlockNumber, entry.Timestamp)
+		if current == nil || !w.fits(current, entry.ChainID, partition) {
+			if err := closeCurrent(); err != nil {
+				return err
+			}
+			var err error
+			current, file, err = w.openSegment(entry.ChainID, partition)
+			if err != nil {
+				return err
+			}
+			writer = bufio.NewWriter(file)
+		}
+
+		if _, err := writer.Write(entry.Line); err != nil {
+			file.Close()
+			return fmt.Errorf("write segment: %w", err)
+		}
+		if err := writer.WriteByte('\n'); err != nil {
+			file.Close()
+			return fmt.Errorf("write newline: %w", err)
+		}
+		current.add(entry, int64(len(entry.Line))+1)
+	}
+	if err := closeCurrent(); err != nil {
+		return err
+	}
+	return w.manifest.Save(filepath.Join(w.dir, ManifestName))
+}
+
+// Truncate cuts the output back to offset bytes counted across segments in
+// write order, deleting later segments and rescanning the one that is cut.
+func (w *SegmentWriter) Truncate(offset int64) error {
+	total := w.manifest.Size()
+	if total < offset {
+		return fmt.Errorf("segmented output %s has %d bytes, checkpoint expects %d", w.dir, total, offset)
+	}
+	if total == offset {
+		return nil
+	}
+
+	var (
+		kept []Segment
+		seen int64
+	)
+	for _, segment := range w.manifest.Segments {
+		path := w.segmentPath(segment)
+		switch {
+		case seen+segment.Bytes <= offset:
+			kept = append(kept, segment)
+		case seen >= offset:
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("remove segment: %w", err)
+			}
+		default:
+			if err := truncateFile(path, offset-seen); err != nil {
+				return err
+			}
+			rescanned, err := rescanSegment(path, segment)
+			if err != nil {
+				return err
+			}
+			kept = append(kept, rescanned)
+		}
+		seen += segment.Bytes
+	}
+
+	w.manifest.Segments = kept
+	return w.manifest.Save(filepath.Join(w.dir, ManifestName))
+}
+
+func (w *SegmentWriter) fits(segment *Segment, chainID uint64, partition string) bool {
+	if segment.ChainID != chainID || segment.Partition != partition {
+		return false
+	}
+	return w.partitioning.MaxRecords <= 0 || segment.Records < w.partitioning.MaxRecords
+}
+
+// openSegment continues the newest segment if it matches, otherwise it starts
+// the next one.
+func (w *SegmentWriter) openSegment(chainID uint64, partition string) (*Segment, *os.File, error) {
+	if n := len(w.manifest.Segments); n > 0 {
+		last := &w.manifest.Segments[n-1]
+		if w.fits(last, chainID, partition) {
+			file, err := os.OpenFile(w.segmentPath(*last), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, nil, fmt.Errorf("open segment: %w", err)
+			}
+			return last, file, nil
+		}
+	}
+
+	seq := 1
+	if n := len(w.manifest.Segments); n > 0 {
+		seq = w.manifest.Segments[n-1].Seq + 1
+	}
+	segment := Segment{
+		Seq:       seq,
+		Path:      filepath.ToSlash(filepath.Join(fmt.Sprint(chainID), partition, fmt.Sprintf("%06d.jsonl", seq))),
+		ChainID:   chainID,
+		Partition: partition,
+	}
+	path := w.segmentPath(segment)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, fmt.Errorf("create segment dir: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create segment: %w", err)
+	}
+	w.manifest.Segments = append(w.manifest.Segments, segment)
+	return &w.manifest.Segments[len(w.manifest.Segments)-1], file, nil
+}
+
+func (w *SegmentWriter) segmentPath(segment Segment) string {
+	return filepath.Join(w.dir, filepath.FromSlash(segment.Path))
+}
+
+// truncateFile cuts path to size if it is longer.
+func truncateFile(path string, size int64) error {
+	stat, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if size == 0 {
+			return nil
+		}
+		return fmt.Errorf("segment %s is missing, manifest expects %d bytes", path, size)
+	}
+	if err != nil {
+		return fmt.Errorf("stat segment: %w", err)
+	}
+	if stat.Size() < size {
+		return fmt.Errorf("segment %s has %d bytes, expected %d", path, stat.Size(), size)
+	}
+	if stat.Size() == size {
+		return nil
+	}
+	if err := os.Truncate(path, size); err != nil {
+		return fmt.Errorf("truncate segment: %w", err)
+	}
+	return nil
+}
+
+// rescanSegment recomputes the manifest entry of a segment from its contents.
+func rescanSegment(path string, segment Segment) (Segment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Segment{}, fmt.Errorf("read segment: %w", err)
+	}
+
+	out := Segment{Seq: segment.Seq, Path: segment.Path, ChainID: segment.ChainID, Partition: segment.Partition}
+	for _, line := range bytes.SplitAfter(data, []byte{'\n'}) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			out.Bytes += int64(len(line))
+			continue
+		}
+		var fields struct {
+			BlockNumber uint64 `json:"block_number"`
+			Timestamp   uint64 `json:"timestamp"`
+		}
+		if err := json.Unmarshal(line, &fields); err != nil {
+			return Segment{}, fmt.Errorf("parse segment %s: %w", path, err)
+		}
+		out.add(SegmentEntry{BlockNumber: fields.BlockNumber, Timestamp: fields.Timestamp}, int64(len(line)))
+	}
+	return out, nil
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"liquidityScope/internal/model"
+)
+
+// SegmentedStorage writes log records to partitioned JSONL segments under a
+// directory, e.g. logs/56/2024-01-01/000123.jsonl, listed in a manifest.
+type SegmentedStorage struct {
+	dir    string
+	mu     sync.Mutex
+	writer *SegmentWriter
+}
+
+func NewSegmentedStorage(dir string, partitioning Partitioning) (*SegmentedStorage, error) {
+	writer, err := OpenSegmentWriter(dir, partitioning)
+	if err != nil {
+		return nil, err
+	}
+	return &SegmentedStorage{dir: dir, writer: writer}, nil
+}
+
+// PutLogBatch appends a batch of log records to their segments.
+func (s *SegmentedStorage) PutLogBatch(logs []model.LogRecord) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	entries := make([]SegmentEntry, 0, len(logs))
+	for _, record := range logs {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("marshal log record: %w", err)
+		}
+		entries = append(entries, SegmentEntry{
+			ChainID:     record.ChainID,
+			BlockNumber: record.BlockNumber,
+			Timestamp:   record.Timestamp,
+			Line:        line,
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writer.Append(entries)
+}
+
+// Position returns the total size of all segments in the manifest.
+func (s *SegmentedStorage) Position() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writer.manifest.Size(), nil
+}
+
+// Truncate cuts the output back to offset bytes across segments.
+func (s *SegmentedStorage) Truncate(offset int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writer.Truncate(offset)
+}
+
+// Tail returns the records of the last blocks blocks, in write order. Only
+// segments that overlap that window are read.
+func (s *SegmentedStorage) Tail(blocks uint64) ([]model.LogRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments := s.writer.manifest.Segments
+	if len(segments) == 0 || blocks == 0 {
+		return nil, nil
+	}
+	var newest uint64
+	for _, segment := range segments {
+		newest = max(newest, segment.ToBlock)
+	}
+
+	var records []model.LogRecord
+	for _, segment := range segments {
+		if segment.ToBlock+blocks <= newest {
+			continue
+		}
+		file, err := os.Open(filepath.Join(s.dir, filepath.FromSlash(segment.Path)))
+		if err != nil {
+			return nil, fmt.Errorf("open segment: %w", err)
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var record model.LogRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("parse segment %s: %w", segment.Path, err)
+			}
+			if record.BlockNumber+blocks > newest {
+				records = append(records, record)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("scan segment %s: %w", segment.Path, err)
+		}
+	}
+	return records, nil
+}
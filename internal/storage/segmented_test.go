@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"liquidityScope/internal/model"
+)
+
+const testDay = 1704067200 // 2024-01-01T00:00:00Z
+
+func segmentTestRecords() []model.LogRecord {
+	var records []model.LogRecord
+	for block := uint64(100); block < 106; block++ {
+		ts := uint64(testDay) - 10 + (block-100)*5 // blocks 102+ fall on 2024-01-01
+		records = append(records, model.LogRecord{ChainID: 56, BlockNumber: block, Timestamp: ts})
+	}
+	return records
+}
+
+func TestSegmentedStoragePartitionsByDay(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSegmentedStorage(dir, Partitioning{Mode: PartitionDay, MaxRecords: 3})
+	if err != nil {
+		t.Fatalf("open storage: %v", err)
+	}
+	if err := store.PutLogBatch(segmentTestRecords()); err != nil {
+		t.Fatalf("put batch: %v", err)
+	}
+
+	manifest, err := LoadManifest(filepath.Join(dir, ManifestName))
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	want := []struct {
+		path     string
+		from, to uint64
+		records  int
+	}{
+		{"56/2023-12-31/000001.jsonl", 100, 101, 2},
+		{"56/2024-01-01/000002.jsonl", 102, 104, 3},
+		{"56/2024-01-01/000003.jsonl", 105, 105, 1},
+	}
+	if len(manifest.Segments) != len(want) {
+		t.Fatalf("expected %d segments, got %+v", len(want), manifest.Segments)
+	}
+	for i, segment := range manifest.Segments {
+		if segment.Path != want[i].path || segment.FromBlock != want[i].from || segment.ToBlock != want[i].to || segment.Records != want[i].records {
+			t.Fatalf("segment %d: got %+v, want %+v", i, segment, want[i])
+		}
+	}
+
+	tail, err := store.Tail(2)
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if len(tail) != 2 || tail[0].BlockNumber != 104 || tail[1].BlockNumber != 105 {
+		t.Fatalf("unexpected tail: %+v", tail)
+	}
+}
+
+func TestSegmentedStorageTruncate(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSegmentedStorage(dir, Partitioning{Mode: PartitionBlocks, BlockSpan: 1000})
+	if err != nil {
+		t.Fatalf("open storage: %v", err)
+	}
+	records := segmentTestRecords()
+	if err := store.PutLogBatch(records[:4]); err != nil {
+		t.Fatalf("put batch: %v", err)
+	}
+	committed, _ := store.Position()
+	if err := store.PutLogBatch(records[4:]); err != nil {
+		t.Fatalf("put batch: %v", err)
+	}
+
+	reopened, err := NewSegmentedStorage(dir, Partitioning{Mode: PartitionBlocks, BlockSpan: 1000})
+	if err != nil {
+		t.Fatalf("reopen storage: %v", err)
+	}
+	if err := reopened.Truncate(committed); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	manifest := reopened.writer.Manifest()
+	if len(manifest.Segments) != 1 {
+		t.Fatalf("expected one segment, got %+v", manifest.Segments)
+	}
+	segment := manifest.Segments[0]
+	if segment.Partition != "0-999" || segment.Records != 4 || segment.ToBlock != 103 || segment.Bytes != committed {
+		t.Fatalf("unexpected segment after truncate: %+v", segment)
+	}
+}
+
+func TestOpenInputSelectsSegments(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSegmentedStorage(dir, Partitioning{Mode: PartitionBlocks, BlockSpan: 2})
+	if err != nil {
+		t.Fatalf("open storage: %v", err)
+	}
+	if err := store.PutLogBatch(segmentTestRecords()); err != nil {
+		t.Fatalf("put batch: %v", err)
+	}
+
+	input, err := OpenInput(dir, SegmentFilter{FromBlock: 102, ToBlock: 103})
+	if err != nil {
+		t.Fatalf("open input: %v", err)
+	}
+	defer input.Close()
+	if len(input.Files) != 1 || input.Skipped != 2 {
+		t.Fatalf("expected 1 file and 2 skipped segments, got %v / %d", input.Files, input.Skipped)
+	}
+
+	var blocks []uint64
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		var record model.LogRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		blocks = append(blocks, record.BlockNumber)
+	}
+	if len(blocks) != 2 || blocks[0] != 102 || blocks[1] != 103 {
+		t.Fatalf("unexpected blocks: %v", blocks)
+	}
+
+	byManifest, err := OpenInput(filepath.Join(dir, ManifestName), SegmentFilter{AfterTimestamp: testDay + 10})
+	if err != nil {
+		t.Fatalf("open manifest: %v", err)
+	}
+	if len(byManifest.Files) != 1 {
+		t.Fatalf("expected only the last segment, got %v", byManifest.Files)
+	}
+}
@@ -6,3 +6,28 @@ import "liquidityScope/internal/model"
 type Storage interface {
 	PutLogBatch(logs []model.LogRecord) error
 }
+
+// Positioner is implemented by append-only sinks whose durable length can be
+// recorded in a checkpoint and restored after a crash.
+type Positioner interface {
+	// Position returns the length of the durably written output.
+	Position() (int64, error)
+	// Truncate discards everything written after offset.
+	Truncate(offset int64) error
+}
+
+// Tailer is implemented by sinks that can read back their most recent records.
+type Tailer interface {
+	// Tail returns, in write order, the records of the last blocks blocks
+	// counted back from the newest block in the output.
+	Tail(blocks uint64) ([]model.LogRecord, error)
+}
+
+// Checkpointer is implemented by sinks that store the checkpoint themselves,
+// atomically with the batch that advances it.
+type Checkpointer interface {
+	// PutLogBatchAt writes logs and records lastProcessed as the checkpoint.
+	PutLogBatchAt(logs []model.LogRecord, lastProcessed uint64) error
+	// LoadCheckpoint returns the stored checkpoint, if any.
+	LoadCheckpoint() (uint64, bool, error)
+}
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS raw_logs (
+  chain_id BIGINT NOT NULL,
+  block_number BIGINT NOT NULL,
+  block_hash TEXT NOT NULL,
+  tx_hash TEXT NOT NULL,
+  tx_index BIGINT NOT NULL,
+  log_index BIGINT NOT NULL,
+  address TEXT NOT NULL,
+  topics TEXT[] NOT NULL,
+  data TEXT NOT NULL,
+  removed BOOLEAN NOT NULL DEFAULT false,
+  block_timestamp BIGINT NOT NULL,
+  ingested_at TEXT NOT NULL,
+  PRIMARY KEY (chain_id, block_hash, log_index)
+);
+
+CREATE INDEX IF NOT EXISTS raw_logs_block_idx ON raw_logs (chain_id, block_number, log_index);
+
+CREATE TABLE IF NOT EXISTS indexer_checkpoints (
+  name TEXT PRIMARY KEY,
+  last_processed_block BIGINT NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,3 @@
+ALTER TABLE pool_window_metrics
+  ADD COLUMN IF NOT EXISTS protocol_fee0 NUMERIC NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS protocol_fee1 NUMERIC NOT NULL DEFAULT 0;
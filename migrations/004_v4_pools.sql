@@ -0,0 +1,6 @@
+ALTER TABLE pools
+  ADD COLUMN IF NOT EXISTS protocol TEXT NOT NULL DEFAULT 'v3',
+  ADD COLUMN IF NOT EXISTS hooks TEXT;
+
+COMMENT ON COLUMN pools.pool_address IS 'pool contract address, or the poolId of a V4 pool';
+COMMENT ON COLUMN pool_window_metrics.pool_address IS 'pool contract address, or the poolId of a V4 pool';
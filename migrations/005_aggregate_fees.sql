@@ -0,0 +1,4 @@
+ALTER TABLE indexer_state
+  ADD COLUMN IF NOT EXISTS fees JSONB;
+
+COMMENT ON COLUMN indexer_state.fees IS 'fees and fee protocols in force at last_processed_ts per pool';